@@ -0,0 +1,446 @@
+package svg
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchRow is a row of a batch data source.
+type BatchRow struct {
+	// Number is the position of the row in the source, starting at 1.
+	Number int
+	// Values are the values of the row by column name.
+	Values map[string]interface{}
+}
+
+// RowError is the error of a single row of a batch.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (err *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", err.Row, err.Err)
+}
+
+func (err *RowError) Unwrap() error {
+	return err.Err
+}
+
+// BatchSource provides the rows of a batch. Next returns io.EOF after the
+// last row. A *RowError skips the row, other errors stop the batch.
+type BatchSource interface {
+	Next() (*BatchRow, error)
+}
+
+// BatchOutput stores the rendered files. Write is called from several
+// goroutines.
+type BatchOutput interface {
+	Write(name string, content []byte) error
+}
+
+// BatchResult summarizes a batch.
+type BatchResult struct {
+	Rendered int
+	// Errors are the errors of the rows which were not rendered, by row.
+	Errors []*RowError
+}
+
+// Batch renders a template once per row of a data source.
+//
+// Each row is first applied to the template as its data, replacing the
+// placeholders, then each column named after the id of an element sets
+// it: images are set from the file of the path in the column as
+// SetImage does, other elements get the value as content as SetContent
+// does.
+type Batch struct {
+	Template *Template
+
+	// Workers is the number of rows rendered at the same time, the
+	// number of CPUs when zero.
+	Workers int
+
+	// NameColumn names the column holding the name of the files, made
+	// unique with the row number. Files are named after the row number
+	// when empty.
+	NameColumn string
+
+	// ImageDir is the directory of relative image paths.
+	ImageDir string
+
+	// LinkImages links images instead of embedding them.
+	LinkImages bool
+
+	// LinkBase is the directory links to images are relative to, which
+	// is where the files are written. Links are relative to the working
+	// directory when empty.
+	LinkBase string
+}
+
+type batchJob struct {
+	row  *BatchRow
+	name string
+}
+
+// Run renders every row of the source into the output. Rows which fail
+// are reported in the result and do not stop the batch.
+func (b *Batch) Run(source BatchSource, output BatchOutput) (*BatchResult, error) {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan batchJob)
+	errs := make(chan *RowError)
+	result := &BatchResult{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				content, err := b.render(job.row)
+				if err == nil {
+					err = output.Write(job.name, content)
+				}
+				if err != nil {
+					errs <- &RowError{Row: job.row.Number, Err: err}
+				} else {
+					errs <- nil
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for err := range errs {
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+			} else {
+				result.Rendered++
+			}
+		}
+		close(done)
+	}()
+
+	fatal := b.feed(source, jobs, errs)
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	<-done
+
+	sort.Slice(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+	return result, fatal
+}
+
+// feed sends the rows of the source to the workers.
+func (b *Batch) feed(source BatchSource, jobs chan<- batchJob, errs chan<- *RowError) error {
+	names := make(map[string]bool)
+	for {
+		row, err := source.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			errs <- rowErr
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		name := b.fileName(row)
+		base := strings.TrimSuffix(name, ".svg")
+		for i := 0; names[name]; i++ {
+			// the row number may itself be the name of another row
+			if i == 0 {
+				name = fmt.Sprintf("%s-%d.svg", base, row.Number)
+			} else {
+				name = fmt.Sprintf("%s-%d-%d.svg", base, row.Number, i)
+			}
+		}
+		names[name] = true
+
+		jobs <- batchJob{row: row, name: name}
+	}
+}
+
+// fileName returns the name of the file of a row.
+func (b *Batch) fileName(row *BatchRow) string {
+	name := ""
+	if b.NameColumn != "" {
+		name = formatTemplateValue(row.Values[b.NameColumn])
+		name = strings.Map(func(r rune) rune {
+			switch r {
+			case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+				return '_'
+			}
+			return r
+		}, strings.TrimSpace(name))
+		name = strings.TrimLeft(name, ".")
+	}
+	if name == "" {
+		name = fmt.Sprintf("%04d", row.Number)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".svg") {
+		name += ".svg"
+	}
+	return name
+}
+
+// render renders the template for a row.
+func (b *Batch) render(row *BatchRow) ([]byte, error) {
+	root, err := b.Template.Execute(row.Values)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(row.Values))
+	for column := range row.Values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		el := root.FindID(column)
+		if el == nil {
+			continue
+		}
+
+		value := formatTemplateValue(row.Values[column])
+		if el.LocalName() != "image" {
+			if err := SetContent(root, column, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if value == "" {
+			continue
+		}
+		if b.ImageDir != "" && !filepath.IsAbs(value) {
+			value = filepath.Join(b.ImageDir, value)
+		}
+		if b.LinkImages {
+			err = LinkImage(root, column, value, b.LinkBase)
+		} else {
+			err = SetImage(root, column, value, true)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+	}
+
+	var content bytes.Buffer
+	if err := Render(root, &content); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// CSVSource reads rows from CSV, the first record naming the columns.
+type CSVSource struct {
+	reader  *csv.Reader
+	columns []string
+	row     int
+}
+
+// NewCSVSource creates a source reading CSV.
+func NewCSVSource(r io.Reader) *CSVSource {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return &CSVSource{reader: reader}
+}
+
+// Next returns the next row.
+func (s *CSVSource) Next() (*BatchRow, error) {
+	if s.columns == nil {
+		header, err := s.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		s.columns = make([]string, len(header))
+		for i, column := range header {
+			s.columns[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		}
+	}
+
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	s.row++
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &RowError{Row: s.row, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(record) > len(s.columns) {
+		return nil, &RowError{Row: s.row, Err: fmt.Errorf("%d fields for %d columns", len(record), len(s.columns))}
+	}
+
+	values := make(map[string]interface{}, len(s.columns))
+	for i, column := range s.columns {
+		if i < len(record) {
+			values[column] = record[i]
+		} else {
+			values[column] = ""
+		}
+	}
+	return &BatchRow{Number: s.row, Values: values}, nil
+}
+
+// JSONLinesSource reads rows from JSON objects, one per line. Blank lines
+// are ignored.
+type JSONLinesSource struct {
+	reader *bufio.Reader
+	row    int
+}
+
+// NewJSONLinesSource creates a source reading JSON lines.
+func NewJSONLinesSource(r io.Reader) *JSONLinesSource {
+	return &JSONLinesSource{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next row.
+func (s *JSONLinesSource) Next() (*BatchRow, error) {
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == io.EOF {
+				return nil, err
+			}
+			continue
+		}
+		s.row++
+
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var values map[string]interface{}
+		if err := decoder.Decode(&values); err != nil {
+			return nil, &RowError{Row: s.row, Err: err}
+		}
+		return &BatchRow{Number: s.row, Values: values}, nil
+	}
+}
+
+// JSONSource reads rows from a JSON array of objects.
+type JSONSource struct {
+	decoder *json.Decoder
+	started bool
+	row     int
+}
+
+// NewJSONSource creates a source reading a JSON array.
+func NewJSONSource(r io.Reader) *JSONSource {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &JSONSource{decoder: decoder}
+}
+
+// Next returns the next row.
+func (s *JSONSource) Next() (*BatchRow, error) {
+	if !s.started {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if token != json.Delim('[') {
+			return nil, fmt.Errorf("expected a JSON array, found %v", token)
+		}
+		s.started = true
+	}
+
+	if !s.decoder.More() {
+		if _, err := s.decoder.Token(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	s.row++
+
+	// values which are not objects are skipped, malformed JSON ends
+	// the array
+	var values map[string]interface{}
+	err := s.decoder.Decode(&values)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil, &RowError{Row: s.row, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BatchRow{Number: s.row, Values: values}, nil
+}
+
+// DirOutput writes the files of a batch to a directory.
+type DirOutput struct {
+	Dir string
+}
+
+// NewDirOutput creates the directory if needed.
+func NewDirOutput(dir string) (*DirOutput, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirOutput{Dir: dir}, nil
+}
+
+// Write writes a file.
+func (o *DirOutput) Write(name string, content []byte) error {
+	return os.WriteFile(filepath.Join(o.Dir, name), content, 0644)
+}
+
+// ZipOutput writes the files of a batch to a zip archive, which is
+// complete once closed.
+type ZipOutput struct {
+	mu sync.Mutex
+	w  *zip.Writer
+}
+
+// NewZipOutput creates an archive writing to w.
+func NewZipOutput(w io.Writer) *ZipOutput {
+	return &ZipOutput{w: zip.NewWriter(w)}
+}
+
+// Write adds a file to the archive.
+func (o *ZipOutput) Write(name string, content []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	f, err := o.w.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+// Close writes the end of the archive. It does not close the underlying
+// writer.
+func (o *ZipOutput) Close() error {
+	return o.w.Close()
+}
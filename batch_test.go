@@ -0,0 +1,233 @@
+package svg
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func batchTemplate() *Template {
+	root, _ := parse(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`+
+		`<text id="name">Name</text><text id="role">{{role}}</text><image id="photo"/></svg>`, false)
+	return NewTemplate(root)
+}
+
+// memoryOutput keeps the files of a batch in memory.
+type memoryOutput struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func newMemoryOutput() *memoryOutput {
+	return &memoryOutput{files: make(map[string]string)}
+}
+
+func (o *memoryOutput) Write(name string, content []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.files[name] = string(content)
+	return nil
+}
+
+func TestBatchCSV(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ada.png"), []byte("\x89PNG\x0D\x0A\x1A\x0Adata"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := "\ufeffid,name,role,photo\n" +
+		"ada,Ada,Engineer,ada.png\n" +
+		"bob,Bob,\"Designer\n" +
+		"\"\n" +
+		"eve,Eve,Tester,missing.png\n" +
+		"ada,Ada 2,Manager,\n" +
+		",Nobody\n"
+
+	batch := &Batch{Template: batchTemplate(), Workers: 3, NameColumn: "id", ImageDir: dir}
+	output := newMemoryOutput()
+	result, err := batch.Run(NewCSVSource(strings.NewReader(data)), output)
+	if err != nil {
+		t.Fatalf("Run: unexpected error %v\n", err)
+	}
+
+	if result.Rendered != 4 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("Run: unexpected result %+v\n", result)
+	}
+	if !errors.Is(result.Errors[0], os.ErrNotExist) {
+		t.Errorf("Run: expected %v, actual %v\n", os.ErrNotExist, result.Errors[0])
+	}
+
+	var names []string
+	for name := range output.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{"0005.svg", "ada-4.svg", "ada.svg", "bob.svg"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("Run: expected %v, actual %v\n", expected, names)
+	}
+
+	ada := output.files["ada.svg"]
+	for _, part := range []string{">Ada<", ">Engineer<", "data:image/png;base64,iVBORw0KGgpkYXRh"} {
+		if !strings.Contains(ada, part) {
+			t.Errorf("Run: expected %v in %v\n", part, ada)
+		}
+	}
+	if bob := output.files["bob.svg"]; !strings.Contains(bob, "Designer\n") {
+		t.Errorf("Run: expected multi-line value in %v\n", bob)
+	}
+}
+
+func TestBatchJSONLines(t *testing.T) {
+	data := `{"name": "Ada", "role": 1.5}
+
+{"name": "Bob",
+{"name": "Eve", "role": "Tester"}
+`
+
+	var archive bytes.Buffer
+	output := NewZipOutput(&archive)
+	result, err := (&Batch{Template: batchTemplate()}).Run(NewJSONLinesSource(strings.NewReader(data)), output)
+	if err != nil {
+		t.Fatalf("Run: unexpected error %v\n", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close: unexpected error %v\n", err)
+	}
+
+	if result.Rendered != 2 || len(result.Errors) != 1 || result.Errors[0].Row != 2 {
+		t.Fatalf("Run: unexpected result %+v\n", result)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("zip: unexpected error %v\n", err)
+	}
+
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "0001.svg 0003.svg" {
+		t.Errorf("Run: expected %v, actual %v\n", "0001.svg 0003.svg", names)
+	}
+}
+
+func TestBatchJSON(t *testing.T) {
+	tests := []struct {
+		data     string
+		rendered int
+		errors   []int
+		fatal    bool
+	}{
+		{`[{"name": "Ada"}, 3, {"name": "Eve", "role": 1.5}]`, 2, []int{2}, false},
+		{` [ ] `, 0, nil, false},
+		{`{"name": "Ada"}`, 0, nil, true},
+		{`[{"name": "Ada"}, {"name": }]`, 1, nil, true},
+	}
+
+	for _, test := range tests {
+		output := newMemoryOutput()
+		result, err := (&Batch{Template: batchTemplate(), Workers: 1}).Run(NewJSONSource(strings.NewReader(test.data)), output)
+		if (err != nil) != test.fatal {
+			t.Errorf("Run %v: unexpected error %v\n", test.data, err)
+		}
+
+		var errs []int
+		for _, rowErr := range result.Errors {
+			errs = append(errs, rowErr.Row)
+		}
+		if result.Rendered != test.rendered || fmt.Sprint(errs) != fmt.Sprint(test.errors) {
+			t.Errorf("Run %v: expected %v rendered, errors %v, actual %v, %v\n", test.data, test.rendered, test.errors, result.Rendered, errs)
+		}
+	}
+
+	output := newMemoryOutput()
+	(&Batch{Template: batchTemplate()}).Run(NewJSONSource(strings.NewReader(`[{"role": 1.5}]`)), output)
+	if file := output.files["0001.svg"]; !strings.Contains(file, ">1.5<") {
+		t.Errorf("Run: expected %v in %v\n", ">1.5<", file)
+	}
+}
+
+func TestDirOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	output, err := NewDirOutput(dir)
+	if err != nil {
+		t.Fatalf("NewDirOutput: unexpected error %v\n", err)
+	}
+
+	result, err := (&Batch{Template: batchTemplate()}).Run(NewCSVSource(strings.NewReader("name\nAda\n")), output)
+	if err != nil || result.Rendered != 1 {
+		t.Fatalf("Run: unexpected result %+v, %v\n", result, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "0001.svg"))
+	if err != nil || !strings.Contains(string(content), ">Ada<") {
+		t.Errorf("Run: unexpected file %s, %v\n", content, err)
+	}
+}
+
+func TestBatchLinkImages(t *testing.T) {
+	dir := t.TempDir()
+	images := filepath.Join(dir, "images")
+	if err := os.Mkdir(images, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(images, "ada.png"), []byte("\x89PNG\x0D\x0A\x1A\x0Adata"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out")
+	output, err := NewDirOutput(out)
+	if err != nil {
+		t.Fatalf("NewDirOutput: unexpected error %v\n", err)
+	}
+
+	batch := &Batch{Template: batchTemplate(), ImageDir: images, LinkImages: true, LinkBase: out}
+	result, err := batch.Run(NewCSVSource(strings.NewReader("photo\nada.png\n")), output)
+	if err != nil || result.Rendered != 1 {
+		t.Fatalf("Run: unexpected result %+v, %v\n", result, err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(out, "0001.svg"))
+	if err != nil {
+		t.Fatalf("Run: unexpected error %v\n", err)
+	}
+	root, _ := parse(string(content), false)
+	href := useHref(root.FindID("photo"))
+	if expected := "../images/ada.png"; href != expected {
+		t.Errorf("Run: expected %v, actual %v\n", expected, href)
+	}
+	if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(href))); err != nil {
+		t.Errorf("Run: expected link resolved from the output, actual %v\n", err)
+	}
+}
+
+func TestBatchNames(t *testing.T) {
+	data := "id\nada\nada-3\nada\nada\n"
+
+	batch := &Batch{Template: batchTemplate(), Workers: 1, NameColumn: "id"}
+	output := newMemoryOutput()
+	result, err := batch.Run(NewCSVSource(strings.NewReader(data)), output)
+	if err != nil || result.Rendered != 4 {
+		t.Fatalf("Run: unexpected result %+v, %v\n", result, err)
+	}
+
+	var names []string
+	for name := range output.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{"ada-3-1.svg", "ada-3.svg", "ada-4.svg", "ada.svg"}
+	if strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("Run: expected %v, actual %v\n", expected, names)
+	}
+}
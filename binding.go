@@ -0,0 +1,287 @@
+package svg
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// binding kinds
+const (
+	bindText  = "text"
+	bindImage = "image"
+	bindAttr  = "attr"
+)
+
+// binding is a struct field bound to an element by its svg tag.
+type binding struct {
+	field     string
+	value     reflect.Value
+	id        string
+	kind      string
+	attribute string
+}
+
+// Fill sets the elements of the tree from the fields of v, a struct or a
+// pointer to a struct. Fields are bound to elements by id with tags:
+//
+//	type Badge struct {
+//		Title string `svg:"#title,text"`
+//		Logo  []byte `svg:"#logo,image"`
+//		Color string `svg:"#bg,attr=fill"`
+//	}
+//
+// text sets the content of the element, as SetContent does, and is the
+// default. image embeds the bytes of a []byte field, or the base64 or
+// data URI of a string field, as Set64Image does. attr=name sets an
+// attribute. Strings, booleans and numbers are supported. Fields of
+// untagged nested structs are bound as well, fields tagged "-" are
+// ignored.
+func Fill(root *Element, v interface{}) error {
+	bindings, err := bindingsOf(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings {
+		if err := b.fill(root); err != nil {
+			return fmt.Errorf("field %s: %w", b.field, err)
+		}
+	}
+	return nil
+}
+
+// Extract reads the values bound by the tags of Fill from the elements
+// of the tree into v, which must be a pointer to a struct. Images are
+// decoded from their data URI into []byte fields, string fields get the
+// link as is.
+func Extract(root *Element, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("extract into %T: %w", v, ErrUnsupportedBinding)
+	}
+
+	bindings, err := bindingsOf(value)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bindings {
+		if err := b.extract(root); err != nil {
+			return fmt.Errorf("field %s: %w", b.field, err)
+		}
+	}
+	return nil
+}
+
+// Fill sets the elements of the document from the fields of v.
+func (d *Document) Fill(v interface{}) error {
+	return Fill(d.Root, v)
+}
+
+// Extract reads the elements of the document into v.
+func (d *Document) Extract(v interface{}) error {
+	return Extract(d.Root, v)
+}
+
+// bindingsOf returns the bound fields of a struct, in field order.
+func bindingsOf(v reflect.Value) ([]binding, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bind %s: %w", v.Type(), ErrUnsupportedBinding)
+	}
+
+	var bindings []binding
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup("svg")
+		if tag == "-" {
+			continue
+		}
+
+		if !tagged {
+			nested := v.Field(i)
+			if nested.Kind() == reflect.Struct ||
+				(nested.Kind() == reflect.Ptr && nested.Type().Elem().Kind() == reflect.Struct) {
+				inner, err := bindingsOf(nested)
+				if err != nil {
+					return nil, err
+				}
+				for j := range inner {
+					inner[j].field = field.Name + "." + inner[j].field
+				}
+				bindings = append(bindings, inner...)
+			}
+			continue
+		}
+
+		b, err := parseBinding(tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		b.field = field.Name
+		b.value = v.Field(i)
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
+
+// parseBinding parses a tag such as "#bg,attr=fill".
+func parseBinding(tag string) (binding, error) {
+	id, kind := tag, bindText
+	if i := strings.Index(tag, ","); i >= 0 {
+		id, kind = tag[:i], strings.TrimSpace(tag[i+1:])
+	}
+
+	b := binding{id: strings.TrimPrefix(strings.TrimSpace(id), "#"), kind: kind}
+	if strings.HasPrefix(kind, bindAttr+"=") {
+		b.kind, b.attribute = bindAttr, strings.TrimSpace(kind[len(bindAttr)+1:])
+	}
+
+	switch {
+	case b.id == "":
+		return b, fmt.Errorf("tag %q has no id: %w", tag, ErrUnsupportedBinding)
+	case b.kind == bindAttr && b.attribute == "":
+		return b, fmt.Errorf("tag %q has no attribute: %w", tag, ErrUnsupportedBinding)
+	case b.kind != bindText && b.kind != bindImage && b.kind != bindAttr:
+		return b, fmt.Errorf("tag %q: unknown kind %q: %w", tag, kind, ErrUnsupportedBinding)
+	}
+	return b, nil
+}
+
+func (b binding) fill(root *Element) error {
+	if b.kind == bindImage {
+		switch {
+		case b.value.Kind() == reflect.String:
+			return Set64Image(root, b.id, b.value.String())
+		case b.value.Kind() == reflect.Slice && b.value.Type().Elem().Kind() == reflect.Uint8:
+			content := b.value.Bytes()
+			return Set64Image(root, b.id, fmt.Sprintf("data:%s;base64,%s",
+				detectImageType(content), base64.StdEncoding.EncodeToString(content)))
+		}
+		return fmt.Errorf("image from %s: %w", b.value.Type(), ErrUnsupportedBinding)
+	}
+
+	text, err := formatValue(b.value)
+	if err != nil {
+		return err
+	}
+	if b.kind == bindText {
+		return SetContent(root, b.id, text)
+	}
+
+	el := findID(root, b.id)
+	if el == nil {
+		return ErrElementNotFound
+	}
+	el.SetAttribute(b.attribute, text)
+	return nil
+}
+
+func (b binding) extract(root *Element) error {
+	el := findID(root, b.id)
+	if el == nil {
+		return ErrElementNotFound
+	}
+
+	switch b.kind {
+	case bindText:
+		if tspans := el.FindAll("tspan"); len(tspans) > 0 {
+			return parseValue(b.value, tspans[0].Content)
+		}
+		return parseValue(b.value, el.Content)
+
+	case bindAttr:
+		return parseValue(b.value, el.Attributes[b.attribute])
+	}
+
+	href := useHref(el)
+	switch {
+	case b.value.Kind() == reflect.String:
+		b.value.SetString(href)
+		return nil
+	case b.value.Kind() == reflect.Slice && b.value.Type().Elem().Kind() == reflect.Uint8:
+		_, content, err := parseDataURI(href)
+		if err != nil {
+			return err
+		}
+		b.value.SetBytes(content)
+		return nil
+	}
+	return fmt.Errorf("image into %s: %w", b.value.Type(), ErrUnsupportedBinding)
+}
+
+// formatValue formats strings, booleans and numbers.
+func formatValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("value of %s: %w", v.Type(), ErrUnsupportedBinding)
+}
+
+// parseValue sets strings, booleans and numbers. Empty text sets the zero
+// value.
+func parseValue(v reflect.Value, text string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(text)
+		return nil
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("value of %s: %w", v.Type(), ErrUnsupportedBinding)
+	}
+	return nil
+}
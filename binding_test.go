@@ -0,0 +1,152 @@
+package svg
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type badgeLabel struct {
+	Text  string  `svg:"#label"`
+	Size  float64 `svg:"#label,attr=font-size"`
+	Shown bool    `svg:"#label,attr=data-shown"`
+}
+
+type badge struct {
+	Title  string `svg:"#title,text"`
+	Logo   []byte `svg:"#logo,image"`
+	Color  string `svg:"#bg,attr=fill"`
+	Count  int    `svg:"#count"`
+	Label  badgeLabel
+	Ignore string `svg:"-"`
+	hidden string
+}
+
+func badgeTemplate() *Element {
+	element, _ := parse(`
+		<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+			<rect id="bg" fill="#000"/>
+			<text id="title"><tspan>Title</tspan></text>
+			<text id="count">0</text>
+			<text id="label">Label</text>
+			<image id="logo"/>
+		</svg>
+	`, false)
+	return element
+}
+
+func TestFill(t *testing.T) {
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0Adata")
+	root := badgeTemplate()
+	err := Fill(root, badge{
+		Title:  "Build",
+		Logo:   png,
+		Color:  "#4c1",
+		Count:  42,
+		Label:  badgeLabel{Text: "passing", Size: 11.5, Shown: true},
+		hidden: "not bound",
+	})
+	if err != nil {
+		t.Fatalf("Fill: unexpected error %v\n", err)
+	}
+
+	var testCases = []struct {
+		id        string
+		attribute string
+		expected  string
+	}{
+		{"bg", "fill", "#4c1"},
+		{"label", "font-size", "11.5"},
+		{"label", "data-shown", "true"},
+		{"logo", "xlink:href", "data:image/png;base64,iVBORw0KGgpkYXRh"},
+	}
+
+	for _, test := range testCases {
+		if actual := root.FindID(test.id).Attributes[test.attribute]; actual != test.expected {
+			t.Errorf("Fill %s %s: expected %v, actual %v\n", test.id, test.attribute, test.expected, actual)
+		}
+	}
+
+	if actual := root.FindID("title").Children[0].Content; actual != "Build" {
+		t.Errorf("Fill title: expected %v, actual %v\n", "Build", actual)
+	}
+	if actual := root.FindID("count").Content; actual != "42" {
+		t.Errorf("Fill count: expected %v, actual %v\n", "42", actual)
+	}
+
+	// and back
+	var extracted badge
+	if err := Extract(root, &extracted); err != nil {
+		t.Fatalf("Extract: unexpected error %v\n", err)
+	}
+	if extracted.Title != "Build" || !bytes.Equal(extracted.Logo, png) || extracted.Color != "#4c1" ||
+		extracted.Count != 42 || extracted.Label != (badgeLabel{"passing", 11.5, true}) {
+		t.Errorf("Extract: unexpected %+v\n", extracted)
+	}
+}
+
+func TestFillDocument(t *testing.T) {
+	doc := NewDocument(badgeTemplate())
+	if err := doc.Fill(&badgeLabel{Text: "doc"}); err != nil {
+		t.Fatalf("Fill: unexpected error %v\n", err)
+	}
+
+	var label badgeLabel
+	if err := doc.Extract(&label); err != nil {
+		t.Fatalf("Extract: unexpected error %v\n", err)
+	}
+	if label.Text != "doc" || label.Size != 0 || label.Shown {
+		t.Errorf("Extract: unexpected %+v\n", label)
+	}
+}
+
+func TestBindingErrors(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		bind     func() error
+		expected error
+	}{
+		{"missing element", func() error {
+			return Fill(badgeTemplate(), struct {
+				A string `svg:"#missing"`
+			}{})
+		}, ErrElementNotFound},
+		{"no id", func() error {
+			return Fill(badgeTemplate(), struct {
+				A string `svg:",text"`
+			}{})
+		}, ErrUnsupportedBinding},
+		{"unknown kind", func() error {
+			return Fill(badgeTemplate(), struct {
+				A string `svg:"#bg,color"`
+			}{})
+		}, ErrUnsupportedBinding},
+		{"unsupported type", func() error {
+			return Fill(badgeTemplate(), struct {
+				A []string `svg:"#bg"`
+			}{})
+		}, ErrUnsupportedBinding},
+		{"not a struct", func() error {
+			return Fill(badgeTemplate(), "text")
+		}, ErrUnsupportedBinding},
+		{"extract not a pointer", func() error {
+			return Extract(badgeTemplate(), badge{})
+		}, ErrUnsupportedBinding},
+	}
+
+	for _, test := range testCases {
+		if err := test.bind(); !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, actual %v\n", test.name, test.expected, err)
+		}
+	}
+
+	// images which are not embedded can not be read into bytes
+	var logo struct {
+		Logo []byte `svg:"#logo,image"`
+	}
+	root := badgeTemplate()
+	root.FindID("logo").Attributes["xlink:href"] = "logo.png"
+	if err := Extract(root, &logo); err == nil {
+		t.Errorf("Extract: expected error for linked image\n")
+	}
+}
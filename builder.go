@@ -0,0 +1,445 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Point is a pair of coordinates.
+type Point struct {
+	X, Y float64
+}
+
+// Builder constructs an element with typed attributes. Methods return
+// the builder so that calls can be chained:
+//
+//	doc := svg.SVG(100, 100).Add(
+//		svg.Rect(0, 0, 100, 100).Fill("white"),
+//		svg.Circle(50, 50, 40).Fill("red").Stroke("black").StrokeWidth(2),
+//	).Element()
+type Builder struct {
+	e *Element
+}
+
+// namespaces in scope of built elements, copied to each of them
+var builderNamespaces = map[string]string{
+	"":      SVGNamespace,
+	"xlink": XLinkNamespace,
+}
+
+// NewBuilder starts an element of any name.
+func NewBuilder(name string) *Builder {
+	return &Builder{e: &Element{
+		Name:       name,
+		Space:      SVGNamespace,
+		Namespaces: copyStrings(builderNamespaces),
+		Attributes: make(map[string]string),
+		Children:   []*Element{},
+	}}
+}
+
+// SVG starts a root svg element of the given size, with a matching
+// viewBox.
+func SVG(width, height float64) *Builder {
+	return NewBuilder("svg").
+		Attr("xmlns", SVGNamespace).
+		Attr("xmlns:xlink", XLinkNamespace).
+		Size(width, height).
+		ViewBox(0, 0, width, height)
+}
+
+// G starts a group.
+func G() *Builder {
+	return NewBuilder("g")
+}
+
+// Defs starts a definitions element.
+func Defs() *Builder {
+	return NewBuilder("defs")
+}
+
+// Rect starts a rectangle.
+func Rect(x, y, width, height float64) *Builder {
+	return NewBuilder("rect").Number("x", x).Number("y", y).Size(width, height)
+}
+
+// Circle starts a circle.
+func Circle(cx, cy, r float64) *Builder {
+	return NewBuilder("circle").Number("cx", cx).Number("cy", cy).Number("r", r)
+}
+
+// Ellipse starts an ellipse.
+func Ellipse(cx, cy, rx, ry float64) *Builder {
+	return NewBuilder("ellipse").Number("cx", cx).Number("cy", cy).Number("rx", rx).Number("ry", ry)
+}
+
+// Line starts a line.
+func Line(x1, y1, x2, y2 float64) *Builder {
+	return NewBuilder("line").Number("x1", x1).Number("y1", y1).Number("x2", x2).Number("y2", y2)
+}
+
+// Polyline starts an open shape through the points.
+func Polyline(points ...Point) *Builder {
+	return NewBuilder("polyline").Attr("points", formatPoints(points))
+}
+
+// Polygon starts a closed shape through the points.
+func Polygon(points ...Point) *Builder {
+	return NewBuilder("polygon").Attr("points", formatPoints(points))
+}
+
+// Path starts a path. PathData may be used to build d.
+func Path(d string) *Builder {
+	return NewBuilder("path").Attr("d", d)
+}
+
+// Text starts a text element at x, y.
+func Text(x, y float64, content string) *Builder {
+	return NewBuilder("text").Number("x", x).Number("y", y).Content(content)
+}
+
+// TSpan starts a text span.
+func TSpan(content string) *Builder {
+	return NewBuilder("tspan").Content(content)
+}
+
+// Image starts an image of the given place and source.
+func Image(x, y, width, height float64, href string) *Builder {
+	return NewBuilder("image").Number("x", x).Number("y", y).Size(width, height).Attr("xlink:href", href)
+}
+
+// Use starts a use of the element with the id.
+func Use(id string) *Builder {
+	return NewBuilder("use").Attr("xlink:href", "#"+id)
+}
+
+// LinearGradient starts a linear gradient along the vector from x1, y1
+// to x2, y2, in fractions of the bounding box of the painted element.
+func LinearGradient(id string, x1, y1, x2, y2 float64) *Builder {
+	return NewBuilder("linearGradient").ID(id).
+		Number("x1", x1).Number("y1", y1).Number("x2", x2).Number("y2", y2)
+}
+
+// RadialGradient starts a radial gradient of center cx, cy and radius r,
+// in fractions of the bounding box of the painted element.
+func RadialGradient(id string, cx, cy, r float64) *Builder {
+	return NewBuilder("radialGradient").ID(id).Number("cx", cx).Number("cy", cy).Number("r", r)
+}
+
+// ClipPath starts a clipping path.
+func ClipPath(id string) *Builder {
+	return NewBuilder("clipPath").ID(id)
+}
+
+// Mask starts a mask.
+func Mask(id string) *Builder {
+	return NewBuilder("mask").ID(id)
+}
+
+// Pattern starts a pattern tile in user space.
+func Pattern(id string, x, y, width, height float64) *Builder {
+	return NewBuilder("pattern").ID(id).Attr("patternUnits", "userSpaceOnUse").
+		Number("x", x).Number("y", y).Size(width, height)
+}
+
+// Element returns the built element.
+func (b *Builder) Element() *Element {
+	return b.e
+}
+
+// Attr sets an attribute.
+func (b *Builder) Attr(name, value string) *Builder {
+	b.e.Attributes[name] = value
+	return b
+}
+
+// Number sets a numeric attribute.
+func (b *Builder) Number(name string, value float64) *Builder {
+	return b.Attr(name, formatNumber(value))
+}
+
+// ID sets the id.
+func (b *Builder) ID(id string) *Builder {
+	return b.Attr("id", id)
+}
+
+// Class adds classes.
+func (b *Builder) Class(classes ...string) *Builder {
+	all := strings.Fields(b.e.Attributes["class"])
+	return b.Attr("class", strings.Join(append(all, classes...), " "))
+}
+
+// Size sets the width and height.
+func (b *Builder) Size(width, height float64) *Builder {
+	return b.Number("width", width).Number("height", height)
+}
+
+// ViewBox sets the viewBox.
+func (b *Builder) ViewBox(x, y, width, height float64) *Builder {
+	return b.Attr("viewBox", formatNumbers(x, y, width, height))
+}
+
+// Fill sets the fill paint.
+func (b *Builder) Fill(paint string) *Builder {
+	return b.Attr("fill", paint)
+}
+
+// FillURL fills with the gradient or pattern of the id.
+func (b *Builder) FillURL(id string) *Builder {
+	return b.Attr("fill", "url(#"+id+")")
+}
+
+// FillOpacity sets the fill opacity.
+func (b *Builder) FillOpacity(opacity float64) *Builder {
+	return b.Number("fill-opacity", opacity)
+}
+
+// Stroke sets the stroke paint.
+func (b *Builder) Stroke(paint string) *Builder {
+	return b.Attr("stroke", paint)
+}
+
+// StrokeURL strokes with the gradient or pattern of the id.
+func (b *Builder) StrokeURL(id string) *Builder {
+	return b.Attr("stroke", "url(#"+id+")")
+}
+
+// StrokeWidth sets the stroke width.
+func (b *Builder) StrokeWidth(width float64) *Builder {
+	return b.Number("stroke-width", width)
+}
+
+// StrokeOpacity sets the stroke opacity.
+func (b *Builder) StrokeOpacity(opacity float64) *Builder {
+	return b.Number("stroke-opacity", opacity)
+}
+
+// StrokeDashArray sets the dash pattern of the stroke.
+func (b *Builder) StrokeDashArray(dashes ...float64) *Builder {
+	return b.Attr("stroke-dasharray", formatNumbers(dashes...))
+}
+
+// Opacity sets the opacity of the element.
+func (b *Builder) Opacity(opacity float64) *Builder {
+	return b.Number("opacity", opacity)
+}
+
+// ClipPathURL clips the element with the clipping path of the id.
+func (b *Builder) ClipPathURL(id string) *Builder {
+	return b.Attr("clip-path", "url(#"+id+")")
+}
+
+// MaskURL masks the element with the mask of the id.
+func (b *Builder) MaskURL(id string) *Builder {
+	return b.Attr("mask", "url(#"+id+")")
+}
+
+// FontFamily sets the font family.
+func (b *Builder) FontFamily(family string) *Builder {
+	return b.Attr("font-family", family)
+}
+
+// FontSize sets the font size.
+func (b *Builder) FontSize(size float64) *Builder {
+	return b.Number("font-size", size)
+}
+
+// FontWeight sets the font weight, such as bold or 700.
+func (b *Builder) FontWeight(weight string) *Builder {
+	return b.Attr("font-weight", weight)
+}
+
+// TextAnchor sets the text alignment: start, middle or end.
+func (b *Builder) TextAnchor(anchor string) *Builder {
+	return b.Attr("text-anchor", anchor)
+}
+
+// Style sets a property of the style attribute.
+func (b *Builder) Style(property, value string) *Builder {
+	return b.Attr("style", setStyle(b.e.Attributes["style"], property, value))
+}
+
+// setStyle returns the style with the property set, last.
+func setStyle(style, property, value string) string {
+	var styles []string
+	for _, declaration := range strings.Split(style, ";") {
+		if name := strings.TrimSpace(strings.SplitN(declaration, ":", 2)[0]); name != "" && name != property {
+			styles = append(styles, strings.TrimSpace(declaration))
+		}
+	}
+	return strings.Join(append(styles, property+":"+value), ";")
+}
+
+// Translate appends a translation to the transform.
+func (b *Builder) Translate(x, y float64) *Builder {
+	return b.transform("translate", x, y)
+}
+
+// Scale appends a scaling to the transform.
+func (b *Builder) Scale(sx, sy float64) *Builder {
+	return b.transform("scale", sx, sy)
+}
+
+// Rotate appends a rotation in degrees around cx, cy to the transform.
+func (b *Builder) Rotate(angle, cx, cy float64) *Builder {
+	if cx == 0 && cy == 0 {
+		return b.transform("rotate", angle)
+	}
+	return b.transform("rotate", angle, cx, cy)
+}
+
+// SkewX appends a skew along the x axis to the transform.
+func (b *Builder) SkewX(angle float64) *Builder {
+	return b.transform("skewX", angle)
+}
+
+// SkewY appends a skew along the y axis to the transform.
+func (b *Builder) SkewY(angle float64) *Builder {
+	return b.transform("skewY", angle)
+}
+
+// Matrix appends the transformation matrix(a b c d e f) to the
+// transform.
+func (b *Builder) Matrix(sx, ky, kx, sy, tx, ty float64) *Builder {
+	return b.transform("matrix", sx, ky, kx, sy, tx, ty)
+}
+
+func (b *Builder) transform(name string, args ...float64) *Builder {
+	t := name + "(" + formatNumbers(args...) + ")"
+	if current := b.e.Attributes["transform"]; current != "" {
+		t = current + " " + t
+	}
+	return b.Attr("transform", t)
+}
+
+// Content sets the text of the element.
+func (b *Builder) Content(content string) *Builder {
+	b.e.Content = content
+	return b
+}
+
+// Stop adds a gradient stop at offset, between 0 and 1.
+func (b *Builder) Stop(offset float64, color string) *Builder {
+	return b.Add(NewBuilder("stop").Number("offset", offset).Attr("stop-color", color))
+}
+
+// StopOpacity adds a gradient stop with an opacity.
+func (b *Builder) StopOpacity(offset float64, color string, opacity float64) *Builder {
+	return b.Add(NewBuilder("stop").Number("offset", offset).Attr("stop-color", color).Number("stop-opacity", opacity))
+}
+
+// Add appends the built children.
+func (b *Builder) Add(children ...*Builder) *Builder {
+	for _, child := range children {
+		b.e.AppendChild(child.e)
+	}
+	return b
+}
+
+// Append appends existing elements as children.
+func (b *Builder) Append(children ...*Element) *Builder {
+	for _, child := range children {
+		b.e.AppendChild(child)
+	}
+	return b
+}
+
+// PathData builds the d attribute of a path. Upper case methods take
+// absolute coordinates.
+type PathData struct {
+	commands []string
+}
+
+func (p *PathData) add(command string, args ...float64) *PathData {
+	if len(args) == 0 {
+		p.commands = append(p.commands, command)
+	} else {
+		p.commands = append(p.commands, command+formatNumbers(args...))
+	}
+	return p
+}
+
+// MoveTo starts a new subpath at x, y.
+func (p *PathData) MoveTo(x, y float64) *PathData {
+	return p.add("M", x, y)
+}
+
+// LineTo draws a line to x, y.
+func (p *PathData) LineTo(x, y float64) *PathData {
+	return p.add("L", x, y)
+}
+
+// HorizontalTo draws a horizontal line to x.
+func (p *PathData) HorizontalTo(x float64) *PathData {
+	return p.add("H", x)
+}
+
+// VerticalTo draws a vertical line to y.
+func (p *PathData) VerticalTo(y float64) *PathData {
+	return p.add("V", y)
+}
+
+// QuadTo draws a quadratic Bézier curve to x, y.
+func (p *PathData) QuadTo(x1, y1, x, y float64) *PathData {
+	return p.add("Q", x1, y1, x, y)
+}
+
+// CubicTo draws a cubic Bézier curve to x, y.
+func (p *PathData) CubicTo(x1, y1, x2, y2, x, y float64) *PathData {
+	return p.add("C", x1, y1, x2, y2, x, y)
+}
+
+// ArcTo draws an elliptical arc to x, y.
+func (p *PathData) ArcTo(rx, ry, rotation float64, largeArc, sweep bool, x, y float64) *PathData {
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return p.add("A", rx, ry, rotation, flag(largeArc), flag(sweep), x, y)
+}
+
+// Close closes the current subpath.
+func (p *PathData) Close() *PathData {
+	return p.add("Z")
+}
+
+// String returns the path data.
+func (p *PathData) String() string {
+	return strings.Join(p.commands, " ")
+}
+
+// formatNumber formats a number for an attribute value, rounded to six
+// decimals and without trailing zeros.
+func formatNumber(n float64) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return "0"
+	}
+
+	s := strconv.FormatFloat(n, 'f', 6, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// formatNumbers formats numbers separated by spaces.
+func formatNumbers(numbers ...float64) string {
+	formatted := make([]string, len(numbers))
+	for i, n := range numbers {
+		formatted[i] = formatNumber(n)
+	}
+	return strings.Join(formatted, " ")
+}
+
+func formatPoints(points []Point) string {
+	formatted := make([]string, len(points))
+	for i, p := range points {
+		formatted[i] = fmt.Sprintf("%s,%s", formatNumber(p.X), formatNumber(p.Y))
+	}
+	return strings.Join(formatted, " ")
+}
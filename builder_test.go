@@ -0,0 +1,100 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	path := (&PathData{}).MoveTo(0, 0).LineTo(10, 0.5).QuadTo(1, 2, 3, 4).
+		CubicTo(1, 2, 3, 4, 5, 6).ArcTo(5, 5, 0, false, true, 10, 10).HorizontalTo(-0.25).VerticalTo(1.0 / 3).Close()
+
+	root := SVG(200, 100).Add(
+		Defs().Add(
+			LinearGradient("grad", 0, 0, 1, 0).Stop(0, "#fff").StopOpacity(1, "#000", 0.5),
+			RadialGradient("radial", 0.5, 0.5, 0.5),
+			ClipPath("clip").Add(Circle(50, 50, 40)),
+			Mask("mask").Add(Rect(0, 0, 100, 100).Fill("white")),
+			Pattern("dots", 0, 0, 10, 10).Add(Circle(5, 5, 2)),
+		),
+		G().ID("layer").Class("a").Class("b").Translate(10, 20).Rotate(45, 0, 0).Scale(2, 2).Add(
+			Rect(0, 0, 20, 10).FillURL("grad").Stroke("black").StrokeWidth(1.5).Opacity(0.3),
+			Ellipse(1, 2, 3, 4).ClipPathURL("clip").MaskURL("mask"),
+			Line(0, 0, 0.1+0.2, 1).StrokeDashArray(2, 1),
+			Polyline(Point{0, 0}, Point{1, 2}),
+			Polygon(Point{0, 0}, Point{1, 2}, Point{3, 0}),
+			Path(path.String()).Style("fill", "none").Style("stroke", "red").Style("fill", "blue"),
+			Text(5, 15, "Hello ").FontFamily("Sans").FontSize(12).FontWeight("bold").TextAnchor("middle").Add(TSpan("world")),
+			Image(0, 0, 10, 10, "photo.png"),
+			Use("layer").Number("x", -5),
+		),
+	).Element()
+
+	expected := `<svg height="100" viewBox="0 0 200 100" width="200" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">` +
+		`<defs><linearGradient id="grad" x1="0" x2="1" y1="0" y2="0"><stop offset="0" stop-color="#fff"></stop><stop offset="1" stop-color="#000" stop-opacity="0.5"></stop></linearGradient>` +
+		`<radialGradient cx="0.5" cy="0.5" id="radial" r="0.5"></radialGradient>` +
+		`<clipPath id="clip"><circle cx="50" cy="50" r="40"></circle></clipPath>` +
+		`<mask id="mask"><rect fill="white" height="100" width="100" x="0" y="0"></rect></mask>` +
+		`<pattern height="10" id="dots" patternUnits="userSpaceOnUse" width="10" x="0" y="0"><circle cx="5" cy="5" r="2"></circle></pattern></defs>` +
+		`<g class="a b" id="layer" transform="translate(10 20) rotate(45) scale(2 2)">` +
+		`<rect fill="url(#grad)" height="10" opacity="0.3" stroke="black" stroke-width="1.5" width="20" x="0" y="0"></rect>` +
+		`<ellipse clip-path="url(#clip)" cx="1" cy="2" mask="url(#mask)" rx="3" ry="4"></ellipse>` +
+		`<line stroke-dasharray="2 1" x1="0" x2="0.3" y1="0" y2="1"></line>` +
+		`<polyline points="0,0 1,2"></polyline>` +
+		`<polygon points="0,0 1,2 3,0"></polygon>` +
+		`<path d="M0 0 L10 0.5 Q1 2 3 4 C1 2 3 4 5 6 A5 5 0 0 1 10 10 H-0.25 V0.333333 Z" style="stroke:red;fill:blue"></path>` +
+		`<text font-family="Sans" font-size="12" font-weight="bold" text-anchor="middle" x="5" y="15">Hello <tspan>world</tspan></text>` +
+		`<image height="10" width="10" x="0" xlink:href="photo.png" y="0"></image>` +
+		`<use x="-5" xlink:href="#layer"></use>` +
+		`</g></svg>`
+
+	SetSortAttributes(true)
+	actual, _ := render(root)
+	if actual != expected {
+		t.Errorf("Builder: expected %v, actual %v\n", expected, actual)
+	}
+
+	// built documents parse back to the same tree
+	parsed, err := Parse(strings.NewReader(actual), true)
+	if err != nil {
+		t.Errorf("Builder: unexpected error %v\n", err)
+	} else {
+		equals(t, "Builder", root, parsed)
+	}
+
+	if root.FindID("layer").Children[0].Parent() != root.FindID("layer") {
+		t.Errorf("Builder: expected parent links\n")
+	}
+}
+
+func TestBuilderNamespaces(t *testing.T) {
+	a, b := Rect(0, 0, 1, 1).Element(), Rect(0, 0, 1, 1).Element()
+	a.Namespaces["x"] = "urn:x"
+
+	if _, ok := b.Namespaces["x"]; ok {
+		t.Errorf("Builder: expected namespaces not shared between elements\n")
+	}
+	if _, ok := Circle(0, 0, 1).Element().Namespaces["x"]; ok {
+		t.Errorf("Builder: expected namespaces not shared with new elements\n")
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	var testCases = []struct {
+		n        float64
+		expected string
+	}{
+		{0, "0"},
+		{-0.0000001, "0"},
+		{1.5, "1.5"},
+		{0.1 + 0.2, "0.3"},
+		{-12, "-12"},
+		{1e9, "1000000000"},
+	}
+
+	for _, test := range testCases {
+		if actual := formatNumber(test.n); actual != test.expected {
+			t.Errorf("formatNumber: expected %v, actual %v\n", test.expected, actual)
+		}
+	}
+}
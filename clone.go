@@ -0,0 +1,158 @@
+package svg
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Clone returns a deep copy of the element and its descendants. The copy
+// has no parent. Cloning only reads the element, so a parsed template
+// can be cloned from several goroutines at once.
+func (e *Element) Clone() *Element {
+	c := &cloner{namespaces: make(map[uintptr]map[string]string)}
+	return c.clone(e, nil)
+}
+
+// CloneWithPrefix returns a deep copy of the element in which every id is
+// prefixed. References to those ids from within the copy, such as
+// xlink:href="#id" or fill="url(#id)", are updated as well.
+func (e *Element) CloneWithPrefix(prefix string) *Element {
+	clone := e.Clone()
+	prefixIDs(clone, prefix)
+	return clone
+}
+
+// prefixIDs prefixes the ids of the element and its descendants, and the
+// references to them from within the element.
+func prefixIDs(e *Element, prefix string) {
+	ids := make(map[string]bool)
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if id, ok := el.Attributes["id"]; ok {
+			ids[id] = true
+			el.Attributes["id"] = prefix + id
+		}
+		return WalkContinue
+	})
+
+	rewriteReferences(e, func(id string) (string, bool) {
+		if ids[id] {
+			return prefix + id, true
+		}
+		return id, false
+	})
+}
+
+type cloner struct {
+	// copies of namespace maps, so that sharing is preserved
+	namespaces map[uintptr]map[string]string
+}
+
+func (c *cloner) clone(e *Element, parent *Element) *Element {
+	clone := &Element{
+		Name:       e.Name,
+		Content:    e.Content,
+		Space:      e.Space,
+		Position:   e.Position,
+		Attributes: copyStrings(e.Attributes),
+		Namespaces: c.cloneNamespaces(e.Namespaces),
+		Nodes:      copyNodes(e.Nodes),
+		Prolog:     copyNodes(e.Prolog),
+		Epilog:     copyNodes(e.Epilog),
+		parent:     parent,
+	}
+
+	if e.AttributePositions != nil {
+		clone.AttributePositions = make(map[string]Position, len(e.AttributePositions))
+		for k, v := range e.AttributePositions {
+			clone.AttributePositions[k] = v
+		}
+	}
+
+	if e.Children != nil {
+		clone.Children = make([]*Element, len(e.Children))
+		for i, child := range e.Children {
+			clone.Children[i] = c.clone(child, clone)
+		}
+	}
+
+	return clone
+}
+
+func (c *cloner) cloneNamespaces(namespaces map[string]string) map[string]string {
+	if namespaces == nil {
+		return nil
+	}
+
+	key := reflect.ValueOf(namespaces).Pointer()
+	if clone, ok := c.namespaces[key]; ok {
+		return clone
+	}
+
+	clone := copyStrings(namespaces)
+	c.namespaces[key] = clone
+	return clone
+}
+
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func copyNodes(nodes []*Node) []*Node {
+	if nodes == nil {
+		return nil
+	}
+
+	clone := make([]*Node, len(nodes))
+	for i, node := range nodes {
+		n := *node
+		clone[i] = &n
+	}
+	return clone
+}
+
+var urlReference = regexp.MustCompile(`url\(\s*(['"]?)#([^'")\s]+)(['"]?)\s*\)`)
+
+// isHref reports whether the attribute holds a link, e.g. href or
+// xlink:href.
+func isHref(name string) bool {
+	_, local := splitName(name)
+	return local == "href"
+}
+
+// rewriteReferences replaces ids referenced by the element and its
+// descendants, through href attributes and url() values, with the id
+// returned by rename when it reports a change.
+func rewriteReferences(e *Element, rename func(id string) (string, bool)) {
+	e.Walk(func(el *Element, depth int) WalkAction {
+		for name, value := range el.Attributes {
+			if isHref(name) && strings.HasPrefix(value, "#") {
+				if id, ok := rename(value[1:]); ok {
+					el.Attributes[name] = "#" + id
+				}
+				continue
+			}
+
+			if !strings.Contains(value, "url(") {
+				continue
+			}
+
+			el.Attributes[name] = urlReference.ReplaceAllStringFunc(value, func(match string) string {
+				groups := urlReference.FindStringSubmatch(match)
+				if id, ok := rename(groups[2]); ok {
+					return "url(" + groups[1] + "#" + id + groups[3] + ")"
+				}
+				return match
+			})
+		}
+		return WalkContinue
+	})
+}
@@ -0,0 +1,91 @@
+package svg
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg"><!-- c --><g id="a" fill="red"><text id="t">Hello <tspan>you</tspan></text></g></svg>`
+	root, _ := parse(svg, false)
+
+	clone := root.Clone()
+	equals(t, "Clone", root, clone)
+
+	if clone.Parent() != nil || clone.Children[0].Parent() != clone {
+		t.Errorf("Clone: unexpected parent links\n")
+	}
+
+	clone.Children[0].Attributes["fill"] = "blue"
+	clone.Children[0].Children[0].Content = "Bye "
+	clone.Nodes[0].Data = " changed "
+	clone.AppendChild(element("rect", map[string]string{}))
+
+	expected := `<svg xmlns="http://www.w3.org/2000/svg"><!-- c --><g fill="red" id="a"><text id="t">Hello <tspan>you</tspan></text></g></svg>`
+	SetSortAttributes(true)
+	if actual, _ := render(root); actual != expected {
+		t.Errorf("Clone: original changed, expected %v, actual %v\n", expected, actual)
+	}
+}
+
+func TestCloneWithPrefix(t *testing.T) {
+	svg := `<svg>
+		<defs><linearGradient id="grad"/></defs>
+		<g id="card">
+			<defs><clipPath id="clip"/></defs>
+			<rect id="bg" fill="url(#grad)" clip-path="url('#clip')"/>
+			<use xlink:href="#bg"/>
+			<use href="#outside"/>
+		</g>
+	</svg>`
+	root, _ := parse(svg, false)
+
+	clone := root.FindID("card").CloneWithPrefix("r1-")
+
+	var testCases = []struct {
+		actual   string
+		expected string
+	}{
+		{clone.Attributes["id"], "r1-card"},
+		{clone.Children[0].Children[0].Attributes["id"], "r1-clip"},
+		{clone.Children[1].Attributes["id"], "r1-bg"},
+		{clone.Children[1].Attributes["fill"], "url(#grad)"},
+		{clone.Children[1].Attributes["clip-path"], "url('#r1-clip')"},
+		{clone.Children[2].Attributes["xlink:href"], "#r1-bg"},
+		{clone.Children[3].Attributes["href"], "#outside"},
+		{root.FindID("card").Attributes["id"], "card"},
+	}
+
+	for _, test := range testCases {
+		if test.actual != test.expected {
+			t.Errorf("CloneWithPrefix: expected %v, actual %v\n", test.expected, test.actual)
+		}
+	}
+}
+
+func TestCloneConcurrent(t *testing.T) {
+	root := testElement()
+
+	var wg sync.WaitGroup
+	results := make([]*Element, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			clone := root.CloneWithPrefix(fmt.Sprintf("r%d-", i))
+			clone.FindID(fmt.Sprintf("r%d-second", i)).Attributes["fill"] = "red"
+			results[i] = clone
+		}(i)
+	}
+	wg.Wait()
+
+	if root.FindID("second").Attributes["fill"] != "" {
+		t.Errorf("Clone: original changed\n")
+	}
+	for i, clone := range results {
+		if clone.FindID(fmt.Sprintf("r%d-second", i)).Attributes["fill"] != "red" {
+			t.Errorf("Clone: expected changed clone %d\n", i)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Command svgbatch renders an SVG template once per row of a CSV, JSON
+// array or JSON lines file, into a directory or a zip archive.
+//
+//	svgbatch -template badge.svg -data people.csv -out badges.zip -name id
+//
+// Columns named after element ids set the content of the elements, or
+// the file of images. Placeholders such as {{name}} are replaced by the
+// values of the columns. Rows which fail are reported and do not stop
+// the batch.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/galihrivanto/svg"
+)
+
+func main() {
+	template := flag.String("template", "", "template SVG file")
+	data := flag.String("data", "", "CSV, JSON array (.json) or JSON lines (.jsonl, .ndjson) data file")
+	out := flag.String("out", "", "output directory, or zip archive when ending with .zip")
+	name := flag.String("name", "", "column naming the output files")
+	workers := flag.Int("workers", 0, "rows rendered at the same time, the number of CPUs by default")
+	images := flag.String("images", "", "directory of relative image paths, the data file directory by default")
+	link := flag.Bool("link", false, "link images instead of embedding them")
+	strict := flag.Bool("strict", false, "fail rows with missing placeholder values")
+	flag.Parse()
+
+	if *template == "" || *data == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *images == "" {
+		*images = filepath.Dir(*data)
+	}
+
+	failed, err := run(*template, *data, *out, &svg.Batch{
+		Workers:    *workers,
+		NameColumn: *name,
+		ImageDir:   *images,
+		LinkImages: *link,
+	}, *strict)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "svgbatch:", err)
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// run renders the batch and reports the rows which failed.
+func run(templatePath, dataPath, out string, batch *svg.Batch, strict bool) (bool, error) {
+	f, err := os.Open(templatePath)
+	if err != nil {
+		return false, err
+	}
+	template, err := svg.ParseTemplate(f)
+	f.Close()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", templatePath, err)
+	}
+	template.Strict = strict
+	batch.Template = template
+
+	data, err := os.Open(dataPath)
+	if err != nil {
+		return false, err
+	}
+	defer data.Close()
+
+	var source svg.BatchSource
+	switch strings.ToLower(filepath.Ext(dataPath)) {
+	case ".jsonl", ".ndjson":
+		source = svg.NewJSONLinesSource(data)
+	case ".json":
+		source = svg.NewJSONSource(data)
+	default:
+		source = svg.NewCSVSource(data)
+	}
+
+	var output svg.BatchOutput
+	var closer io.Closer
+	if strings.EqualFold(filepath.Ext(out), ".zip") {
+		archive, err := os.Create(out)
+		if err != nil {
+			return false, err
+		}
+		defer archive.Close()
+
+		zip := svg.NewZipOutput(archive)
+		output, closer = zip, zip
+	} else {
+		dir, err := svg.NewDirOutput(out)
+		if err != nil {
+			return false, err
+		}
+		output = dir
+	}
+
+	// linked images are found from where the files are, the archive
+	// being extracted next to itself
+	if strings.EqualFold(filepath.Ext(out), ".zip") {
+		batch.LinkBase = filepath.Dir(out)
+	} else {
+		batch.LinkBase = out
+	}
+
+	result, err := batch.Run(source, output)
+	if closer != nil {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, rowErr)
+	}
+	fmt.Printf("%d rendered, %d failed\n", result.Rendered, len(result.Errors))
+	return len(result.Errors) > 0, nil
+}
@@ -0,0 +1,235 @@
+package svg
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// Document is a parsed SVG document which keeps its elements indexed by
+// id and class. The indexes follow the tree as it is changed through
+// AppendChild, InsertBefore, InsertAfter, Remove, ReplaceWith,
+// SetAttribute and RemoveAttribute. Attributes changed directly in the
+// Attributes map require a call to Reindex.
+type Document struct {
+	Root *Element
+
+	// BaseDir is the directory the paths of linked images are relative
+	// to, usually the directory of the document file.
+	BaseDir string
+
+	ids     map[string][]*Element
+	classes map[string][]*Element
+}
+
+// NewDocument creates a document from a root element and indexes it.
+func NewDocument(root *Element) *Document {
+	d := &Document{Root: root}
+	root.doc = d
+	d.Reindex()
+	return d
+}
+
+// ParseDocument parses an SVG input like Parse and indexes the result.
+func ParseDocument(source io.Reader, validate bool) (*Document, error) {
+	root, err := Parse(source, validate)
+	if err != nil {
+		return nil, err
+	}
+	return NewDocument(root), nil
+}
+
+// Reindex rebuilds the indexes from the tree.
+func (d *Document) Reindex() {
+	d.ids = make(map[string][]*Element)
+	d.classes = make(map[string][]*Element)
+
+	// the walk is in document order, elements are appended
+	d.Root.Walk(func(el *Element, depth int) WalkAction {
+		d.index(el, el.Attributes["id"], el.Attributes["class"], indexAppend)
+		return WalkContinue
+	})
+}
+
+// ElementByID returns the first element in document order with the id,
+// or nil when there is none.
+func (d *Document) ElementByID(id string) *Element {
+	elements := d.ids[id]
+	if len(elements) == 0 {
+		return nil
+	}
+	return elements[0]
+}
+
+// ElementsByClass returns the elements having the class, in document
+// order.
+func (d *Document) ElementsByClass(class string) []*Element {
+	return append([]*Element(nil), d.classes[class]...)
+}
+
+// DuplicateIDs returns the ids shared by several elements, with these
+// elements in document order.
+func (d *Document) DuplicateIDs() map[string][]*Element {
+	duplicates := make(map[string][]*Element)
+	for id, elements := range d.ids {
+		if len(elements) > 1 {
+			duplicates[id] = append([]*Element(nil), elements...)
+		}
+	}
+	return duplicates
+}
+
+// add indexes the element and its descendants.
+func (d *Document) add(e *Element) {
+	e.Walk(func(el *Element, depth int) WalkAction {
+		d.index(el, el.Attributes["id"], el.Attributes["class"], indexInsert)
+		return WalkContinue
+	})
+}
+
+// remove drops the element and its descendants from the indexes.
+func (d *Document) remove(e *Element) {
+	e.Walk(func(el *Element, depth int) WalkAction {
+		d.index(el, el.Attributes["id"], el.Attributes["class"], indexRemove)
+		return WalkContinue
+	})
+}
+
+// indexOperation is a change of the indexes.
+type indexOperation int
+
+const (
+	// indexAppend adds an element following all those indexed
+	indexAppend indexOperation = iota
+	// indexInsert adds an element where it belongs in document order
+	indexInsert
+	// indexRemove removes an element
+	indexRemove
+)
+
+// index updates the entries of the element under an id and classes.
+// Entries are kept in document order, so that lookups do not change
+// the indexes.
+func (d *Document) index(e *Element, id, class string, op indexOperation) {
+	update := func(index map[string][]*Element, key string) {
+		switch op {
+		case indexAppend:
+			index[key] = append(index[key], e)
+			return
+		case indexInsert:
+			index[key] = insertDocumentOrder(index[key], e)
+			return
+		}
+
+		elements := index[key]
+		for i, el := range elements {
+			if el == e {
+				elements = append(elements[:i], elements[i+1:]...)
+				break
+			}
+		}
+		if len(elements) == 0 {
+			delete(index, key)
+		} else {
+			index[key] = elements
+		}
+	}
+
+	if id != "" {
+		update(d.ids, id)
+	}
+	for _, c := range uniqueFields(class) {
+		update(d.classes, c)
+	}
+}
+
+// uniqueFields splits a class list, dropping repeated names.
+func uniqueFields(s string) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(s, isSpace) {
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// document returns the document the element belongs to, if any.
+func (e *Element) document() *Document {
+	root := e
+	for root.parent != nil {
+		root = root.parent
+	}
+	return root.doc
+}
+
+// SetAttribute sets the value of an attribute, updating the indexes of
+// the document the element belongs to.
+func (e *Element) SetAttribute(name, value string) {
+	if e.Attributes == nil {
+		e.Attributes = make(map[string]string)
+	}
+	e.updateAttribute(name, func() { e.Attributes[name] = value })
+}
+
+// RemoveAttribute removes an attribute, updating the indexes of the
+// document the element belongs to.
+func (e *Element) RemoveAttribute(name string) {
+	e.updateAttribute(name, func() { delete(e.Attributes, name) })
+}
+
+func (e *Element) updateAttribute(name string, update func()) {
+	d := e.document()
+	if d == nil || (name != "id" && name != "class") {
+		update()
+		return
+	}
+
+	d.index(e, e.Attributes["id"], e.Attributes["class"], indexRemove)
+	update()
+	d.index(e, e.Attributes["id"], e.Attributes["class"], indexInsert)
+}
+
+// insertDocumentOrder inserts an element of a tree into elements of the
+// same tree, which are in document order.
+func insertDocumentOrder(elements []*Element, e *Element) []*Element {
+	path := indexPath(e)
+	i := len(elements)
+	if i > 0 && comparePaths(path, indexPath(elements[i-1])) {
+		// elements are mostly added at the end, past the last one
+		i = sort.Search(len(elements), func(j int) bool {
+			return comparePaths(path, indexPath(elements[j]))
+		})
+	}
+
+	elements = append(elements, nil)
+	copy(elements[i+1:], elements[i:])
+	elements[i] = e
+	return elements
+}
+
+// comparePaths reports whether the element at index path a precedes
+// the one at b in document order.
+func comparePaths(a, b []int) bool {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// indexPath returns the child indexes leading from the root to the
+// element.
+func indexPath(e *Element) []int {
+	var path []int
+	for ; e.parent != nil; e = e.parent {
+		path = append(path, e.Index())
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,161 @@
+package svg
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func documentTestElement(t *testing.T) *Document {
+	svg := `<svg>
+		<g id="layer" class="layer">
+			<text id="name" class="field big"><tspan>Name</tspan></text>
+			<rect id="dup" class="field"/>
+		</g>
+		<g id="dup" class="layer field"/>
+		<image id="photo"/>
+	</svg>`
+	doc, err := ParseDocument(strings.NewReader(svg), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDocumentIndex(t *testing.T) {
+	doc := documentTestElement(t)
+	root := doc.Root
+
+	equals(t, "ElementByID", root.FindID("name"), doc.ElementByID("name"))
+	equals(t, "ElementByID", nil, doc.ElementByID("missing"))
+
+	layer := root.Children[0]
+	second := root.Children[1]
+	if doc.ElementByID("dup") != layer.Children[1] {
+		t.Errorf("ElementByID: expected first duplicate in document order\n")
+	}
+
+	classes := doc.ElementsByClass("field")
+	if len(classes) != 3 || classes[0] != layer.Children[0] || classes[1] != layer.Children[1] || classes[2] != second {
+		t.Errorf("ElementsByClass: expected %v, actual %v\n", 3, classes)
+	}
+
+	duplicates := doc.DuplicateIDs()
+	if len(duplicates) != 1 || len(duplicates["dup"]) != 2 {
+		t.Errorf("DuplicateIDs: expected %v, actual %v\n", "dup", duplicates)
+	}
+}
+
+func TestDocumentMutations(t *testing.T) {
+	doc := documentTestElement(t)
+	root := doc.Root
+	layer := doc.ElementByID("layer")
+
+	// moving an element keeps it indexed
+	rect := layer.Children[1]
+	root.AppendChild(rect)
+	if doc.ElementByID("dup") != root.Children[1] {
+		t.Errorf("ElementByID: expected %v, actual %v\n", root.Children[1], doc.ElementByID("dup"))
+	}
+
+	// removed subtrees are dropped
+	layer.Remove()
+	if doc.ElementByID("name") != nil || doc.ElementByID("layer") != nil {
+		t.Errorf("Remove: expected removed elements out of index\n")
+	}
+	if classes := doc.ElementsByClass("big"); len(classes) != 0 {
+		t.Errorf("Remove: expected %v, actual %v\n", 0, classes)
+	}
+
+	// added subtrees are indexed
+	g := element("g", map[string]string{"id": "added"})
+	g.AppendChild(element("circle", map[string]string{"id": "inner", "class": "dot"}))
+	root.InsertBefore(g, root.Children[0])
+	if doc.ElementByID("inner") == nil || len(doc.ElementsByClass("dot")) != 1 {
+		t.Errorf("AppendChild: expected added elements in index\n")
+	}
+
+	// attributes
+	image := doc.ElementByID("photo")
+	image.SetAttribute("id", "avatar")
+	image.SetAttribute("class", "round")
+	if doc.ElementByID("photo") != nil || doc.ElementByID("avatar") != image || len(doc.ElementsByClass("round")) != 1 {
+		t.Errorf("SetAttribute: expected index updated\n")
+	}
+	image.RemoveAttribute("id")
+	if doc.ElementByID("avatar") != nil {
+		t.Errorf("RemoveAttribute: expected index updated\n")
+	}
+
+	// replaced elements
+	replacement := element("rect", map[string]string{"id": "replacement"})
+	g.ReplaceWith(replacement)
+	if doc.ElementByID("added") != nil || doc.ElementByID("inner") != nil || doc.ElementByID("replacement") != replacement {
+		t.Errorf("ReplaceWith: expected index updated\n")
+	}
+
+	// duplicates are resolved once one of them is removed
+	if len(doc.DuplicateIDs()) != 1 {
+		t.Errorf("DuplicateIDs: expected %v, actual %v\n", 1, doc.DuplicateIDs())
+	}
+	doc.ElementByID("dup").Remove()
+	if len(doc.DuplicateIDs()) != 0 || doc.ElementByID("dup") == nil {
+		t.Errorf("DuplicateIDs: expected %v, actual %v\n", 0, doc.DuplicateIDs())
+	}
+}
+
+func TestDocumentOrder(t *testing.T) {
+	doc := documentTestElement(t)
+	root := doc.Root
+
+	// a duplicate added before the others comes first
+	first := element("circle", map[string]string{"id": "dup", "class": "field"})
+	root.InsertBefore(first, root.Children[0])
+	if doc.ElementByID("dup") != first || doc.ElementsByClass("field")[0] != first {
+		t.Errorf("ElementByID: expected the inserted duplicate first\n")
+	}
+
+	// changing the id puts the element in place
+	photo := doc.ElementByID("photo")
+	photo.SetAttribute("id", "dup")
+	if duplicates := doc.DuplicateIDs()["dup"]; len(duplicates) != 4 || duplicates[3] != photo {
+		t.Errorf("DuplicateIDs: expected %v last, actual %v\n", photo, duplicates)
+	}
+
+	// lookups do not change the document
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				doc.ElementByID("dup")
+				doc.ElementsByClass("field")
+				doc.DuplicateIDs()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDocumentEditor(t *testing.T) {
+	doc := documentTestElement(t)
+
+	if err := doc.SetContent("name", "John"); err != nil {
+		t.Errorf("SetContent: expected %v, actual %v\n", nil, err)
+	}
+	if content := doc.ElementByID("name").Children[0].Content; content != "John" {
+		t.Errorf("SetContent: expected %v, actual %v\n", "John", content)
+	}
+
+	if err := doc.Set64Image("photo", "data:image/png;base64,AAAA"); err != nil {
+		t.Errorf("Set64Image: expected %v, actual %v\n", nil, err)
+	}
+	if href := doc.ElementByID("photo").Attributes["xlink:href"]; href != "data:image/png;base64,AAAA" {
+		t.Errorf("Set64Image: expected %v, actual %v\n", "data:image/png;base64,AAAA", href)
+	}
+
+	if err := SetContent(doc.Root, "missing", "x"); err != ErrElementNotFound {
+		t.Errorf("SetContent: expected %v, actual %v\n", ErrElementNotFound, err)
+	}
+}
@@ -5,19 +5,41 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net/http"
+	"mime"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 // define library errors
 var (
-	ErrElementNotFound = errors.New("element not found")
+	ErrElementNotFound    = errors.New("element not found")
+	ErrNotChild           = errors.New("element is not a child")
+	ErrHierarchy          = errors.New("element can not be inserted into itself")
+	ErrDanglingRef        = errors.New("reference to a missing element")
+	ErrReferenceCycle     = errors.New("circular reference")
+	ErrUnsupportedBinding = errors.New("unsupported binding")
+	ErrMissingValue       = errors.New("missing template value")
+	ErrNoMeasurer         = errors.New("no text measurer")
+	ErrNoTextBox          = errors.New("text has no box to flow into")
+	ErrNoFont             = errors.New("no font")
+	ErrImageSize          = errors.New("invalid image size")
+	ErrNoPage             = errors.New("no page")
 )
 
+// findID finds an element by id, through the document index when root
+// is the root of a Document.
+func findID(root *Element, id string) *Element {
+	if root.doc != nil {
+		return root.doc.ElementByID(id)
+	}
+	return root.FindID(id)
+}
+
 // SetContent replace element text by id
 func SetContent(root *Element, id string, text string) error {
-	el := root.FindID(id)
+	el := findID(root, id)
 	if el == nil {
 		return ErrElementNotFound
 	}
@@ -34,27 +56,55 @@ func SetContent(root *Element, id string, text string) error {
 	return nil
 }
 
-// Set64Image replace element embeded image by id
+// Set64Image replace element embeded image by id. content is either
+// base64 encoded, its type being detected from the decoded bytes, or
+// already a data URI.
 func Set64Image(root *Element, id string, content string) error {
-	el := root.FindID(id)
+	return Set64ImageFit(root, id, content, FitNone)
+}
+
+// Set64ImageFit replaces the embedded image of the id like Set64Image
+// and fits it to the image element. PNG, JPEG, GIF and SVG images are
+// measured to size the element.
+func Set64ImageFit(root *Element, id string, content string, fit ImageFit) error {
+	el := findID(root, id)
 	if el == nil {
 		return ErrElementNotFound
 	}
 
-	// check whether content is already valid base64
-	// image format
-	if !strings.HasPrefix(content, "data:") {
-		mime := http.DetectContentType([]byte(content))
-		content = fmt.Sprintf("data:%s;base64,%s", mime, content)
+	var data []byte
+	if strings.HasPrefix(content, "data:") {
+		_, decoded, err := parseDataURI(content)
+		if err != nil {
+			return err
+		}
+		data = decoded
+	} else {
+		// line-wrapped base64 is joined, line breaks are not allowed in URIs
+		content = strings.Join(strings.Fields(content), "")
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return fmt.Errorf("image content: %w", err)
+		}
+		data = decoded
+		content = fmt.Sprintf("data:%s;base64,%s", detectImageType(data), content)
 	}
 
-	el.Attributes["xlink:href"] = content
+	setHref(el, content)
+	if fit != FitNone {
+		fitImage(el, data, fit)
+	}
 
 	return nil
 }
 
-// SetImage replace image by id
+// SetImage replace image by id. The file is embedded as a data URI, or
+// linked when embed is false.
 func SetImage(root *Element, id string, path string, embed bool) error {
+	if !embed {
+		return LinkImage(root, id, path, "")
+	}
+
 	// open image
 	fi, err := os.Open(path)
 	if err != nil {
@@ -71,3 +121,189 @@ func SetImage(root *Element, id string, path string, embed bool) error {
 
 	return Set64Image(root, id, imageContent)
 }
+
+// LinkImage makes the image of the id reference the file of path. The
+// reference is relative to the base directory when there is one.
+func LinkImage(root *Element, id string, path string, base string) error {
+	el := findID(root, id)
+	if el == nil {
+		return ErrElementNotFound
+	}
+
+	setHref(el, imageLink(path, base))
+	return nil
+}
+
+// ExtractImages writes the images embedded as data URIs within root to
+// files of dir and links them instead. Files are named after the id of
+// the image when it has one. Links are relative to the base directory
+// when there is one. The paths of the files are returned.
+func ExtractImages(root *Element, dir string, base string) ([]string, error) {
+	var images []*Element
+	root.Walk(func(el *Element, depth int) WalkAction {
+		if el.LocalName() == "image" && strings.HasPrefix(useHref(el), "data:") {
+			images = append(images, el)
+		}
+		return WalkContinue
+	})
+	if len(images) == 0 {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	names := make(map[string]bool)
+	for i, el := range images {
+		mediaType, content, err := parseDataURI(useHref(el))
+		if err != nil {
+			return paths, fmt.Errorf("image %d: %w", i+1, err)
+		}
+
+		stem := imageFileName(el.Attributes["id"])
+		if stem == "" {
+			stem = fmt.Sprintf("image%d", i+1)
+		}
+		name := stem
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("%s-%d", stem, n)
+		}
+		names[name] = true
+
+		path := filepath.Join(dir, name+imageExtension(mediaType))
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+		setHref(el, imageLink(path, base))
+	}
+	return paths, nil
+}
+
+// setHref sets the reference of an image, in the SVG 2 href attribute
+// when the element uses it.
+func setHref(el *Element, value string) {
+	if _, ok := el.Attributes["href"]; ok {
+		el.Attributes["href"] = value
+		return
+	}
+	el.Attributes["xlink:href"] = value
+}
+
+// imageLink returns the reference to a file, relative to base when
+// possible. Path segments are escaped, as the reference is a URL.
+func imageLink(path string, base string) string {
+	if base != "" {
+		absPath, err := filepath.Abs(path)
+		if err == nil {
+			absBase, err := filepath.Abs(base)
+			if err == nil {
+				if rel, err := filepath.Rel(absBase, absPath); err == nil {
+					path = rel
+				}
+			}
+		}
+	}
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// imageFileName turns an id into a file name.
+func imageFileName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// imageExtensions are the file extensions of the usual image types.
+var imageExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/bmp":     ".bmp",
+	"image/svg+xml": ".svg",
+}
+
+func imageExtension(mimeType string) string {
+	if ext, ok := imageExtensions[mimeType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// parseDataURI returns the media type and the content of a data URI.
+func parseDataURI(uri string) (string, []byte, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return "", nil, fmt.Errorf("%.32q is not a data URI", uri)
+	}
+
+	i := strings.Index(uri, ",")
+	if i < 0 {
+		return "", nil, fmt.Errorf("%.32q is not a data URI", uri)
+	}
+
+	params := strings.Split(uri[len("data:"):i], ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+
+	data := uri[i+1:]
+	if strings.EqualFold(strings.TrimSpace(params[len(params)-1]), "base64") {
+		content, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+		return mediaType, content, err
+	}
+
+	content, err := url.PathUnescape(data)
+	return mediaType, []byte(content), err
+}
+
+// LinkImage makes the image of the id reference the file of path,
+// relative to the directory of the document.
+func (d *Document) LinkImage(id string, path string) error {
+	return LinkImage(d.Root, id, path, d.BaseDir)
+}
+
+// ExtractImages writes the embedded images of the document to files of
+// dir and links them instead.
+func (d *Document) ExtractImages(dir string) ([]string, error) {
+	return ExtractImages(d.Root, dir, d.BaseDir)
+}
+
+// SetContent replace element text by id
+func (d *Document) SetContent(id string, text string) error {
+	return SetContent(d.Root, id, text)
+}
+
+// Set64Image replace element embeded image by id
+func (d *Document) Set64Image(id string, content string) error {
+	return Set64Image(d.Root, id, content)
+}
+
+// Set64ImageFit replaces the embedded image of the id and fits it to the
+// image element.
+func (d *Document) Set64ImageFit(id string, content string, fit ImageFit) error {
+	return Set64ImageFit(d.Root, id, content, fit)
+}
+
+// SetImage replace image by id. Linked images are relative to the
+// directory of the document.
+func (d *Document) SetImage(id string, path string, embed bool) error {
+	if !embed {
+		return d.LinkImage(id, path)
+	}
+	return SetImage(d.Root, id, path, embed)
+}
@@ -0,0 +1,128 @@
+package svg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func imagesElement() *Element {
+	element, _ := parse(`
+		<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+			<image id="logo" xlink:href="data:image/png;base64,iVBORw0KGgpkYXRh"/>
+			<image href="data:image/svg+xml,%3Csvg%2F%3E"/>
+			<image id="photo" xlink:href="photo.jpg"/>
+			<g><image id="logo" xlink:href="data:image/gif;base64,R0lGODlh"/></g>
+		</svg>
+	`, false)
+	return element
+}
+
+func TestSetImageLink(t *testing.T) {
+	root := imagesElement()
+	if err := SetImage(root, "photo", "images/new.jpg", false); err != nil {
+		t.Fatalf("SetImage: unexpected error %v\n", err)
+	}
+	if actual := root.FindID("photo").Attributes["xlink:href"]; actual != "images/new.jpg" {
+		t.Errorf("SetImage: expected %v, actual %v\n", "images/new.jpg", actual)
+	}
+
+	doc := NewDocument(root)
+	doc.BaseDir = "/srv/templates"
+	var testCases = []struct {
+		path     string
+		expected string
+	}{
+		{"/srv/templates/images/a.png", "images/a.png"},
+		{"/srv/assets/b.png", "../assets/b.png"},
+		{"/srv/templates/my photo #1?.png", "my%20photo%20%231%3F.png"},
+		{"/srv/templates/100%/c.png", "100%25/c.png"},
+	}
+
+	for _, test := range testCases {
+		if err := doc.SetImage("photo", test.path, false); err != nil {
+			t.Fatalf("SetImage: unexpected error %v\n", err)
+		}
+		if actual := root.FindID("photo").Attributes["xlink:href"]; actual != test.expected {
+			t.Errorf("SetImage %s: expected %v, actual %v\n", test.path, test.expected, actual)
+		}
+	}
+
+	if err := LinkImage(root, "missing", "a.png", ""); err != ErrElementNotFound {
+		t.Errorf("LinkImage: expected %v, actual %v\n", ErrElementNotFound, err)
+	}
+}
+
+func TestExtractImages(t *testing.T) {
+	dir := t.TempDir()
+	doc := NewDocument(imagesElement())
+	doc.BaseDir = dir
+
+	paths, err := doc.ExtractImages(filepath.Join(dir, "images"))
+	if err != nil {
+		t.Fatalf("ExtractImages: unexpected error %v\n", err)
+	}
+
+	var testCases = []struct {
+		path    string
+		href    string
+		content string
+	}{
+		{"logo.png", "images/logo.png", "\x89PNG\x0D\x0A\x1A\x0Adata"},
+		{"image2.svg", "images/image2.svg", "<svg/>"},
+		{"logo-2.gif", "images/logo-2.gif", "GIF89a"},
+	}
+
+	images := doc.Root.FindAll("image")
+	hrefs := []string{
+		images[0].Attributes["xlink:href"],
+		images[1].Attributes["href"],
+		images[3].Attributes["xlink:href"],
+	}
+	if len(paths) != len(testCases) {
+		t.Fatalf("ExtractImages: expected %v, actual %v\n", len(testCases), paths)
+	}
+	for i, test := range testCases {
+		expected := filepath.Join(dir, "images", test.path)
+		if paths[i] != expected {
+			t.Errorf("ExtractImages: expected %v, actual %v\n", expected, paths[i])
+		}
+		if hrefs[i] != test.href {
+			t.Errorf("ExtractImages: expected %v, actual %v\n", test.href, hrefs[i])
+		}
+		if content, _ := os.ReadFile(expected); string(content) != test.content {
+			t.Errorf("ExtractImages: expected %q, actual %q\n", test.content, content)
+		}
+	}
+
+	if actual := images[2].Attributes["xlink:href"]; actual != "photo.jpg" {
+		t.Errorf("ExtractImages: expected %v, actual %v\n", "photo.jpg", actual)
+	}
+}
+
+func TestParseDataURI(t *testing.T) {
+	var testCases = []struct {
+		uri       string
+		mediaType string
+		content   string
+		fails     bool
+	}{
+		{"data:image/png;base64,aGk=", "image/png", "hi", false},
+		{"data:,a%20b", "text/plain", "a b", false},
+		{"data:Image/SVG+XML;charset=utf-8,%3Csvg%2F%3E", "image/svg+xml", "<svg/>", false},
+		{"data:image/png;base64,aG k=\n", "image/png", "hi", false},
+		{"photo.png", "", "", true},
+		{"data:image/png;base64", "", "", true},
+	}
+
+	for _, test := range testCases {
+		mediaType, content, err := parseDataURI(test.uri)
+		if (err != nil) != test.fails {
+			t.Errorf("parseDataURI %s: unexpected error %v\n", test.uri, err)
+			continue
+		}
+		if mediaType != test.mediaType || string(content) != test.content {
+			t.Errorf("parseDataURI %s: expected %v %q, actual %v %q\n", test.uri, test.mediaType, test.content, mediaType, content)
+		}
+	}
+}
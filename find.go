@@ -1,26 +1,28 @@
 package svg
 
-// FindID finds the first child with the specified ID.
+// FindID finds the first element with the specified ID, the element
+// itself included.
 func (e *Element) FindID(id string) *Element {
-	for _, child := range e.Children {
-		if childID, ok := child.Attributes["id"]; ok && childID == id {
-			return child
+	var found *Element
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if elementID, ok := el.Attributes["id"]; ok && elementID == id {
+			found = el
+			return WalkStop
 		}
-		if element := child.FindID(id); element != nil {
-			return element
-		}
-	}
-	return nil
+		return WalkContinue
+	})
+	return found
 }
 
-// FindAll finds all children with the given name.
+// FindAll finds all elements with the given name, the element itself
+// included.
 func (e *Element) FindAll(name string) []*Element {
 	var elements []*Element
-	for _, child := range e.Children {
-		if child.Name == name {
-			elements = append(elements, child)
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if el.Name == name {
+			elements = append(elements, el)
 		}
-		elements = append(elements, child.FindAll(name)...)
-	}
+		return WalkContinue
+	})
 	return elements
 }
@@ -48,6 +48,7 @@ func TestFindAll(t *testing.T) {
 	}, svgElement.FindAll("rect"))
 
 	equalSlices(t, "Find", []*Element{}, svgElement.FindAll("circle"))
+	equalSlices(t, "Find", []*Element{svgElement}, svgElement.FindAll("svg"))
 }
 
 func TestFindID(t *testing.T) {
@@ -68,4 +69,7 @@ func TestFindID(t *testing.T) {
 	)
 
 	equals(t, "Find", nil, svgElement.FindID("missing"))
+
+	svgElement.Attributes["id"] = "root"
+	equals(t, "Find", svgElement, svgElement.FindID("root"))
 }
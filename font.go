@@ -0,0 +1,397 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a TrueType or OpenType font, used to measure text.
+type Font struct {
+	font *sfnt.Font
+	// ppem measuring in font units
+	ppem fixed.Int26_6
+	// data is the font file of the font alone, nil when it can not be
+	// embedded.
+	data []byte
+
+	family string
+	weight int
+	italic bool
+}
+
+// LoadFont loads a TTF or OTF font file.
+func LoadFont(path string) (*Font, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFont(data)
+}
+
+// ParseFont parses TTF or OTF font data.
+func ParseFont(data []byte) (*Font, error) {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return newFont(f, data), nil
+}
+
+// parseFonts parses a font or a collection of fonts, such as TTC data.
+func parseFonts(data []byte) ([]*Font, error) {
+	collection, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fonts := make([]*Font, collection.NumFonts())
+	for i := range fonts {
+		f, err := collection.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		standalone := data
+		if bytes.HasPrefix(data, []byte("ttcf")) {
+			standalone = collectionFont(data, i)
+		}
+		fonts[i] = newFont(f, standalone)
+	}
+	return fonts, nil
+}
+
+// collectionFont extracts a font of a TTC or OTC collection as a font
+// file of its own, nil when the collection is malformed.
+func collectionFont(data []byte, i int) []byte {
+	u16 := func(offset int) int { return int(binary.BigEndian.Uint16(data[offset:])) }
+	u32 := func(offset int) int { return int(binary.BigEndian.Uint32(data[offset:])) }
+
+	if len(data) < 12 || i >= u32(8) || len(data) < 16+4*i {
+		return nil
+	}
+	start := u32(12 + 4*i)
+	if start < 0 || start+12 > len(data) {
+		return nil
+	}
+	tables := u16(start + 4)
+	if start+12+16*tables > len(data) {
+		return nil
+	}
+
+	// the offset table and the table records, then the tables aligned
+	// on 4 bytes
+	header := 12 + 16*tables
+	font := make([]byte, header, header+len(data)/4)
+	copy(font, data[start:start+header])
+	for j := 0; j < tables; j++ {
+		record := start + 12 + 16*j
+		offset, length := u32(record+8), u32(record+12)
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil
+		}
+		binary.BigEndian.PutUint32(font[12+16*j+8:], uint32(len(font)))
+		font = append(font, data[offset:offset+length]...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+	return font
+}
+
+func newFont(f *sfnt.Font, data []byte) *Font {
+	var buf sfnt.Buffer
+	name := func(ids ...sfnt.NameID) string {
+		for _, id := range ids {
+			if value, err := f.Name(&buf, id); err == nil && value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	family := name(sfnt.NameIDTypographicFamily, sfnt.NameIDFamily)
+	weight, italic := fontStyle(family + " " + name(sfnt.NameIDTypographicSubfamily, sfnt.NameIDSubfamily))
+	return &Font{
+		font:   f,
+		ppem:   fixed.Int26_6(f.UnitsPerEm()) << 6,
+		data:   data,
+		family: family,
+		weight: weight,
+		italic: italic,
+	}
+}
+
+// fontWeights are the weights of the words of font names.
+var fontWeights = map[string]int{
+	"thin":       100,
+	"hairline":   100,
+	"extralight": 200,
+	"ultralight": 200,
+	"light":      300,
+	"medium":     500,
+	"semibold":   600,
+	"demibold":   600,
+	"bold":       700,
+	"extrabold":  800,
+	"ultrabold":  800,
+	"black":      900,
+	"heavy":      900,
+}
+
+// fontStyle returns the weight and the slant a font name describes, such
+// as "Open Sans SemiBold Italic".
+func fontStyle(name string) (int, bool) {
+	weight, italic := 400, false
+	words := strings.Fields(strings.ToLower(strings.NewReplacer("-", " ", "_", " ").Replace(name)))
+	for i, word := range words {
+		// "Extra Bold" as well as "ExtraBold"
+		if i+1 < len(words) {
+			if w, ok := fontWeights[word+words[i+1]]; ok {
+				weight = w
+				continue
+			}
+		}
+		if w, ok := fontWeights[word]; ok && weight == 400 {
+			weight = w
+		}
+		if word == "italic" || word == "oblique" {
+			italic = true
+		}
+	}
+	return weight, italic
+}
+
+// Family returns the family name of the font.
+func (f *Font) Family() string {
+	return f.family
+}
+
+// Weight returns the weight of the font, 400 for a regular one.
+func (f *Font) Weight() int {
+	return f.weight
+}
+
+// Italic reports whether the font is italic or oblique.
+func (f *Font) Italic() bool {
+	return f.italic
+}
+
+// MeasureText returns the advance width of the text at the size of the
+// face, with kerning. The other properties of the face are ignored.
+func (f *Font) MeasureText(text string, face FontFace) float64 {
+	var buf sfnt.Buffer
+	var width fixed.Int26_6
+	previous := sfnt.GlyphIndex(0)
+	for i, r := range text {
+		index, err := f.font.GlyphIndex(&buf, r)
+		if err != nil {
+			index = 0
+		}
+		if i > 0 {
+			if kern, err := f.font.Kern(&buf, previous, index, f.ppem, font.HintingNone); err == nil {
+				width += kern
+			}
+		}
+		if advance, err := f.font.GlyphAdvance(&buf, index, f.ppem, font.HintingNone); err == nil {
+			width += advance
+		}
+		previous = index
+	}
+
+	return f.scale(width, face.Size)
+}
+
+// Metrics returns the ascent and the descent of the font at a size, both
+// positive.
+func (f *Font) Metrics(size float64) (float64, float64) {
+	var buf sfnt.Buffer
+	metrics, err := f.font.Metrics(&buf, f.ppem, font.HintingNone)
+	if err != nil {
+		return 0.8 * size, 0.2 * size
+	}
+	return f.scale(metrics.Ascent, size), f.scale(metrics.Descent, size)
+}
+
+// scale converts a length in font units at ppem to a font size.
+func (f *Font) scale(length fixed.Int26_6, size float64) float64 {
+	return float64(length) / 64 * size / float64(f.font.UnitsPerEm())
+}
+
+// FontRegistry holds the fonts text is measured with, matching the
+// family, weight and style of text to them as CSS does. It is safe for
+// concurrent use.
+type FontRegistry struct {
+	mu      sync.RWMutex
+	fonts   []*Font
+	aliases map[string]string
+}
+
+// NewFontRegistry creates an empty registry.
+func NewFontRegistry() *FontRegistry {
+	return &FontRegistry{aliases: make(map[string]string)}
+}
+
+// Register adds fonts.
+func (r *FontRegistry) Register(fonts ...*Font) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fonts = append(r.fonts, fonts...)
+}
+
+// empty reports whether the registry has no fonts.
+func (r *FontRegistry) empty() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.fonts) == 0
+}
+
+// LoadFile registers the fonts of a TTF, OTF, TTC or OTC file.
+func (r *FontRegistry) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fonts, err := parseFonts(data)
+	if err != nil {
+		return &os.PathError{Op: "parse", Path: path, Err: err}
+	}
+	r.Register(fonts...)
+	return nil
+}
+
+// LoadDir registers the font files found in a directory and its
+// subdirectories.
+func (r *FontRegistry) LoadDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ttf", ".otf", ".ttc", ".otc":
+			if !info.IsDir() {
+				return r.LoadFile(path)
+			}
+		}
+		return nil
+	})
+}
+
+// Alias makes a family name, such as the generic sans-serif, stand for
+// a registered family.
+func (r *FontRegistry) Alias(name, family string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases[strings.ToLower(name)] = family
+}
+
+// Lookup returns the font best matching the face. Families which are not
+// registered fall back to the first registered family. It returns nil
+// when the registry is empty.
+func (r *FontRegistry) Lookup(face FontFace) *Font {
+	return r.lookup([]string{face.Family}, face)
+}
+
+// lookup returns the font of the first registered family of a list.
+func (r *FontRegistry) lookup(families []string, face FontFace) *Font {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.fonts) == 0 {
+		return nil
+	}
+
+	for _, family := range families {
+		family = strings.TrimSpace(family)
+		if alias, ok := r.aliases[strings.ToLower(family)]; ok {
+			family = alias
+		}
+		if f := r.match(family, face); f != nil {
+			return f
+		}
+	}
+	return r.match(r.fonts[0].family, face)
+}
+
+// match selects a font of a family by style and then weight.
+func (r *FontRegistry) match(family string, face FontFace) *Font {
+	var candidates []*Font
+	for _, f := range r.fonts {
+		if strings.EqualFold(f.family, family) {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	weight := face.Weight
+	if weight == 0 {
+		weight = 400
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.italic == face.Italic) != (b.italic == face.Italic) {
+			return a.italic == face.Italic
+		}
+		return weightDistance(weight, a.weight) < weightDistance(weight, b.weight)
+	})
+	return candidates[0]
+}
+
+// weightDistance orders the weights of fonts for a desired weight as
+// CSS does: 400 prefers 500 then lighter ones, 500 prefers 400, lighter
+// weights prefer lighter fonts and bolder weights bolder fonts.
+func weightDistance(desired, weight int) int {
+	const far = 1000
+
+	switch {
+	case weight == desired:
+		return 0
+	case desired >= 400 && desired <= 500:
+		if weight > desired && weight <= 500 {
+			return weight - desired
+		}
+		if weight < desired {
+			return far/2 + desired - weight
+		}
+		return far + weight - desired
+	case desired < 400:
+		if weight < desired {
+			return desired - weight
+		}
+		return far + weight - desired
+	default:
+		if weight > desired {
+			return weight - desired
+		}
+		return far + desired - weight
+	}
+}
+
+// MeasureText returns the advance width of the text in the font matching
+// the face. Without fonts, characters are taken half a font size wide.
+func (r *FontRegistry) MeasureText(text string, face FontFace) float64 {
+	if f := r.Lookup(face); f != nil {
+		return f.MeasureText(text, face)
+	}
+	return float64(len([]rune(text))) * face.Size / 2
+}
+
+// Metrics returns the ascent and the descent of the font matching the
+// face.
+func (r *FontRegistry) Metrics(face FontFace) (float64, float64) {
+	if f := r.Lookup(face); f != nil {
+		return f.Metrics(face.Size)
+	}
+	return 0.8 * face.Size, 0.2 * face.Size
+}
@@ -0,0 +1,198 @@
+package svg
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFont(t *testing.T) {
+	font, err := ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatalf("ParseFont: unexpected error %v\n", err)
+	}
+
+	face := FontFace{Size: 20}
+	one := font.MeasureText("i", face)
+	if one <= 0 {
+		t.Fatalf("MeasureText: expected a width, actual %v\n", one)
+	}
+	if actual := font.MeasureText("iiii", face); math.Abs(actual-4*one) > 1e-9 {
+		t.Errorf("MeasureText: expected %v, actual %v\n", 4*one, actual)
+	}
+	if actual := font.MeasureText("i", FontFace{Size: 40}); math.Abs(actual-2*one) > 1e-9 {
+		t.Errorf("MeasureText: expected %v, actual %v\n", 2*one, actual)
+	}
+	if font.MeasureText("W", face) <= one {
+		t.Errorf("MeasureText: expected W wider than i\n")
+	}
+	if actual := font.MeasureText("", face); actual != 0 {
+		t.Errorf("MeasureText: expected 0, actual %v\n", actual)
+	}
+
+	if _, err := ParseFont([]byte("not a font")); err == nil {
+		t.Errorf("ParseFont: expected error\n")
+	}
+	if _, err := LoadFont("missing.ttf"); err == nil {
+		t.Errorf("LoadFont: expected error\n")
+	}
+}
+
+func testRegistry(t *testing.T) *FontRegistry {
+	registry := NewFontRegistry()
+	for _, data := range [][]byte{goregular.TTF, gobold.TTF, goitalic.TTF, gobolditalic.TTF, gomedium.TTF, gomono.TTF} {
+		font, err := ParseFont(data)
+		if err != nil {
+			t.Fatalf("ParseFont: unexpected error %v\n", err)
+		}
+		registry.Register(font)
+	}
+	return registry
+}
+
+func TestFontNames(t *testing.T) {
+	var testCases = []struct {
+		data   []byte
+		family string
+		weight int
+		italic bool
+	}{
+		{goregular.TTF, "Go", 400, false},
+		{gobolditalic.TTF, "Go", 700, true},
+		{gomedium.TTF, "Go Medium", 500, false},
+	}
+
+	for _, test := range testCases {
+		font, _ := ParseFont(test.data)
+		if font.Family() != test.family || font.Weight() != test.weight || font.Italic() != test.italic {
+			t.Errorf("ParseFont: expected %v %v %v, actual %v %v %v\n", test.family, test.weight, test.italic,
+				font.Family(), font.Weight(), font.Italic())
+		}
+	}
+}
+
+func TestFontStyle(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		weight int
+		italic bool
+	}{
+		{"Open Sans Regular", 400, false},
+		{"Open Sans SemiBold Italic", 600, true},
+		{"Source Sans Extra Bold", 800, false},
+		{"Roboto-ThinOblique", 400, false},
+		{"Roboto Thin Oblique", 100, true},
+	}
+
+	for _, test := range testCases {
+		if weight, italic := fontStyle(test.name); weight != test.weight || italic != test.italic {
+			t.Errorf("fontStyle %s: expected %v %v, actual %v %v\n", test.name, test.weight, test.italic, weight, italic)
+		}
+	}
+}
+
+func TestFontRegistry(t *testing.T) {
+	registry := testRegistry(t)
+	registry.Alias("monospace", "Go Mono")
+
+	var testCases = []struct {
+		face   FontFace
+		family string
+		weight int
+		italic bool
+	}{
+		{FontFace{Family: "Go"}, "Go", 400, false},
+		{FontFace{Family: "go", Weight: 700, Italic: true}, "Go", 700, true},
+		{FontFace{Family: "Go", Weight: 900}, "Go", 700, false},
+		{FontFace{Family: "Go", Weight: 500}, "Go", 400, false},
+		{FontFace{Family: "Go", Weight: 300, Italic: true}, "Go", 400, true},
+		{FontFace{Family: "monospace", Weight: 700}, "Go Mono", 400, false},
+		{FontFace{Family: "Missing"}, "Go", 400, false},
+	}
+
+	for _, test := range testCases {
+		font := registry.Lookup(test.face)
+		if font.Family() != test.family || font.Weight() != test.weight || font.Italic() != test.italic {
+			t.Errorf("Lookup %+v: expected %v %v %v, actual %v %v %v\n", test.face, test.family, test.weight, test.italic,
+				font.Family(), font.Weight(), font.Italic())
+		}
+	}
+
+	face := FontFace{Family: "Go", Size: 10, Weight: 700}
+	if expected, actual := registry.Lookup(face).MeasureText("Hello", face), registry.MeasureText("Hello", face); actual != expected {
+		t.Errorf("MeasureText: expected %v, actual %v\n", expected, actual)
+	}
+
+	ascent, descent := registry.Metrics(face)
+	if ascent <= descent || descent <= 0 || ascent >= 10 {
+		t.Errorf("Metrics: unexpected %v %v\n", ascent, descent)
+	}
+
+	empty := NewFontRegistry()
+	if empty.Lookup(face) != nil {
+		t.Errorf("Lookup: expected no font\n")
+	}
+	if actual := empty.MeasureText("Hello", face); actual != 25 {
+		t.Errorf("MeasureText: expected %v, actual %v\n", 25, actual)
+	}
+}
+
+func TestFontRegistryLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "go"), 0755)
+	os.WriteFile(filepath.Join(dir, "go", "Go-Regular.ttf"), goregular.TTF, 0644)
+	os.WriteFile(filepath.Join(dir, "Go-Bold.TTF"), gobold.TTF, 0644)
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a font"), 0644)
+
+	registry := NewFontRegistry()
+	if err := registry.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir: unexpected error %v\n", err)
+	}
+	if font := registry.Lookup(FontFace{Family: "Go", Weight: 700}); font == nil || font.Weight() != 700 {
+		t.Errorf("LoadDir: expected the bold font, actual %v\n", font)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.otf"), []byte("not a font"), 0644)
+	if err := registry.LoadDir(dir); err == nil {
+		t.Errorf("LoadDir: expected error for a broken font\n")
+	}
+}
+
+func TestCollectionFont(t *testing.T) {
+	registry := testRegistry(t)
+	data := registry.fonts[0].data
+
+	// a collection of the font, twice
+	header := []byte("ttcf\x00\x02\x00\x00\x00\x00\x00\x02\x00\x00\x00\x14\x00\x00\x00\x14")
+	collection := append(append([]byte{}, header...), data...)
+	// the tables are now 20 bytes further
+	tables := int(collection[20+4])<<8 | int(collection[20+5])
+	for i := 0; i < tables; i++ {
+		record := 20 + 12 + 16*i + 8
+		offset := int(collection[record])<<24 | int(collection[record+1])<<16 | int(collection[record+2])<<8 | int(collection[record+3])
+		offset += 20
+		collection[record], collection[record+1], collection[record+2], collection[record+3] = byte(offset>>24), byte(offset>>16), byte(offset>>8), byte(offset)
+	}
+
+	fonts, err := parseFonts(collection)
+	if err != nil || len(fonts) != 2 {
+		t.Fatalf("parseFonts: unexpected %v %v\n", fonts, err)
+	}
+	if _, err := ParseFont(fonts[1].data); err != nil {
+		t.Errorf("collectionFont: expected a font, actual error %v\n", err)
+	}
+	if fonts[1].family != "Go" {
+		t.Errorf("collectionFont: expected Go, actual %v\n", fonts[1].family)
+	}
+	if collectionFont(collection[:30], 0) != nil || collectionFont(collection, 2) != nil {
+		t.Errorf("collectionFont: expected nil for malformed collections\n")
+	}
+}
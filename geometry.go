@@ -0,0 +1,574 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/galihrivanto/svg/utils"
+)
+
+// matrix is an affine transform a, b, c, d, e, f, mapping x, y to
+// a*x + c*y + e, b*x + d*y + f as the SVG matrix() does.
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+func translation(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+func scaling(x, y float64) matrix {
+	return matrix{x, 0, 0, y, 0, 0}
+}
+
+// rotation rotates by an angle in degrees.
+func rotation(angle float64) matrix {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return matrix{cos, sin, -sin, cos, 0, 0}
+}
+
+// multiply returns the transform applying n, then m.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// invert returns the inverse transform, false when there is none.
+func (m matrix) invert() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 || math.IsNaN(det) {
+		return identity, false
+	}
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// scale returns the mean scale of the transform, the factor lengths
+// grow by.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// parseTransform parses a transform attribute.
+func parseTransform(value string) (matrix, error) {
+	m := identity
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		match := transformRegex.FindStringSubmatch(rest)
+		if match == nil {
+			return identity, fmt.Errorf("transform %q is malformed", value)
+		}
+
+		var args []float64
+		for _, arg := range splitList(match[2]) {
+			n, err := parseNumber(arg)
+			if err != nil {
+				return identity, fmt.Errorf("transform %q: %s", value, err)
+			}
+			args = append(args, n)
+		}
+
+		var t matrix
+		switch {
+		case match[1] == "matrix" && len(args) == 6:
+			t = matrix{args[0], args[1], args[2], args[3], args[4], args[5]}
+		case match[1] == "translate" && len(args) == 1:
+			t = translation(args[0], 0)
+		case match[1] == "translate" && len(args) == 2:
+			t = translation(args[0], args[1])
+		case match[1] == "scale" && len(args) == 1:
+			t = scaling(args[0], args[0])
+		case match[1] == "scale" && len(args) == 2:
+			t = scaling(args[0], args[1])
+		case match[1] == "rotate" && len(args) == 1:
+			t = rotation(args[0])
+		case match[1] == "rotate" && len(args) == 3:
+			t = translation(args[1], args[2]).multiply(rotation(args[0])).multiply(translation(-args[1], -args[2]))
+		case match[1] == "skewX" && len(args) == 1:
+			t = matrix{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case match[1] == "skewY" && len(args) == 1:
+			t = matrix{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return identity, fmt.Errorf("transform %q: wrong number of arguments for %s", value, match[1])
+		}
+
+		m = m.multiply(t)
+		rest = strings.TrimSpace(rest[len(match[0]):])
+	}
+	return m, nil
+}
+
+// viewBoxTransform maps a viewBox to a viewport of a size at the origin
+// as preserveAspectRatio tells.
+func viewBoxTransform(box [4]float64, preserveAspectRatio string, width, height float64) matrix {
+	if box[2] <= 0 || box[3] <= 0 {
+		return identity
+	}
+
+	sx, sy := width/box[2], height/box[3]
+	items := strings.Fields(preserveAspectRatio)
+	if len(items) > 0 && items[0] == "defer" {
+		items = items[1:]
+	}
+	align := "xMidYMid"
+	if len(items) > 0 {
+		align = items[0]
+	}
+	if align == "none" {
+		return scaling(sx, sy).multiply(translation(-box[0], -box[1]))
+	}
+
+	scale := math.Min(sx, sy)
+	if len(items) > 1 && items[1] == "slice" {
+		scale = math.Max(sx, sy)
+	}
+	x, y := 0.0, 0.0
+	switch {
+	case strings.HasPrefix(align, "xMid"):
+		x = (width - box[2]*scale) / 2
+	case strings.HasPrefix(align, "xMax"):
+		x = width - box[2]*scale
+	}
+	switch {
+	case strings.HasSuffix(align, "YMid"):
+		y = (height - box[3]*scale) / 2
+	case strings.HasSuffix(align, "YMax"):
+		y = height - box[3]*scale
+	}
+	return translation(x, y).multiply(scaling(scale, scale)).multiply(translation(-box[0], -box[1]))
+}
+
+// parseViewBox parses a viewBox attribute, false when it is missing or
+// invalid.
+func parseViewBox(value string) ([4]float64, bool) {
+	var box [4]float64
+	items := splitList(value)
+	if len(items) != 4 {
+		return box, false
+	}
+	for i, item := range items {
+		n, err := parseNumber(item)
+		if err != nil {
+			return box, false
+		}
+		box[i] = n
+	}
+	return box, box[2] > 0 && box[3] > 0
+}
+
+// userLength parses a length in user units or in an absolute unit, a
+// percentage being of reference.
+func userLength(value string, reference float64) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		n, err := parseNumber(strings.TrimSuffix(value, "%"))
+		return n / 100 * reference, err == nil
+	}
+	return absoluteLength(value)
+}
+
+type point struct {
+	x, y float64
+}
+
+type segmentOp int
+
+const (
+	segmentMove segmentOp = iota
+	segmentLine
+	segmentCubic
+	segmentClose
+)
+
+// pathSegment is a move, a line or a cubic Bézier curve to the last of
+// its points, or the closing of the subpath.
+type pathSegment struct {
+	op     segmentOp
+	points [3]point
+}
+
+// shapePath is the outline of a shape in absolute moves, lines and cubic
+// curves.
+type shapePath []pathSegment
+
+func (p *shapePath) moveTo(x, y float64) {
+	*p = append(*p, pathSegment{op: segmentMove, points: [3]point{{x, y}}})
+}
+
+func (p *shapePath) lineTo(x, y float64) {
+	*p = append(*p, pathSegment{op: segmentLine, points: [3]point{{x, y}}})
+}
+
+func (p *shapePath) cubicTo(x1, y1, x2, y2, x, y float64) {
+	*p = append(*p, pathSegment{op: segmentCubic, points: [3]point{{x1, y1}, {x2, y2}, {x, y}}})
+}
+
+// quadTo adds a quadratic curve from x0, y0 as a cubic one.
+func (p *shapePath) quadTo(x0, y0, x1, y1, x, y float64) {
+	p.cubicTo(x0+2*(x1-x0)/3, y0+2*(y1-y0)/3, x+2*(x1-x)/3, y+2*(y1-y)/3, x, y)
+}
+
+func (p *shapePath) close() {
+	*p = append(*p, pathSegment{op: segmentClose})
+}
+
+// arcTo adds an elliptical arc from x0, y0 as cubic curves, following
+// the SVG implementation notes.
+func (p *shapePath) arcTo(x0, y0, rx, ry, xAxisRotation float64, largeArc, sweep bool, x, y float64) {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || (x0 == x && y0 == y) {
+		p.lineTo(x, y)
+		return
+	}
+
+	sin, cos := math.Sincos(xAxisRotation * math.Pi / 180)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// scale up radii too small to reach the end point
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	factor := math.Sqrt(math.Max(0, num/den))
+	if largeArc == sweep {
+		factor = -factor
+	}
+	cx1, cy1 := factor*rx*y1/ry, -factor*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (x0+x)/2
+	cy := sin*cx1 + cos*cy1 + (y0+y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	start := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// curves of at most a quarter turn
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	ellipse := func(theta float64) (float64, float64, float64, float64) {
+		s, c := math.Sincos(theta)
+		px, py := rx*c, ry*s
+		tx, ty := -rx*s, ry*c
+		return cos*px - sin*py + cx, sin*px + cos*py + cy, cos*tx - sin*ty, sin*tx + cos*ty
+	}
+	for i := 0; i < n; i++ {
+		ax, ay, atx, aty := ellipse(start + float64(i)*step)
+		bx, by, btx, bty := ellipse(start + float64(i+1)*step)
+		if i == n-1 {
+			bx, by = x, y
+		}
+		p.cubicTo(ax+k*atx, ay+k*aty, bx-k*btx, by-k*bty, bx, by)
+	}
+}
+
+// transform returns the path transformed by m.
+func (p shapePath) transform(m matrix) shapePath {
+	transformed := make(shapePath, len(p))
+	for i, segment := range p {
+		transformed[i].op = segment.op
+		for j, pt := range segment.points {
+			x, y := m.apply(pt.x, pt.y)
+			transformed[i].points[j] = point{x, y}
+		}
+	}
+	return transformed
+}
+
+// polyline is a flattened subpath.
+type polyline struct {
+	points []point
+	closed bool
+}
+
+// flatten approximates the curves of the path by lines no further than
+// tolerance from them.
+func (p shapePath) flatten(tolerance float64) []polyline {
+	var lines []polyline
+	var current *polyline
+	var last point
+	start := func() {
+		if current == nil {
+			lines = append(lines, polyline{points: []point{last}})
+			current = &lines[len(lines)-1]
+		}
+	}
+
+	for _, segment := range p {
+		switch segment.op {
+		case segmentMove:
+			current = nil
+			last = segment.points[0]
+			start()
+		case segmentLine:
+			start()
+			last = segment.points[0]
+			current.points = append(current.points, last)
+		case segmentCubic:
+			start()
+			p1, p2, p3 := segment.points[0], segment.points[1], segment.points[2]
+			dd := math.Max(math.Hypot(last.x-2*p1.x+p2.x, last.y-2*p1.y+p2.y), math.Hypot(p1.x-2*p2.x+p3.x, p1.y-2*p2.y+p3.y))
+			n := int(math.Ceil(math.Sqrt(0.75 * dd / tolerance)))
+			if n < 1 {
+				n = 1
+			} else if n > 256 {
+				n = 256
+			}
+			for i := 1; i <= n; i++ {
+				t := float64(i) / float64(n)
+				u := 1 - t
+				a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+				current.points = append(current.points, point{
+					a*last.x + b*p1.x + c*p2.x + d*p3.x,
+					a*last.y + b*p1.y + c*p2.y + d*p3.y,
+				})
+			}
+			last = p3
+		case segmentClose:
+			if current != nil {
+				current.closed = true
+				last = current.points[0]
+				current = nil
+			}
+		}
+	}
+	return lines
+}
+
+// bounds returns the box of the points of the path, which holds its
+// curves.
+func (p shapePath) bounds() (x, y, width, height float64) {
+	first := true
+	var left, top, right, bottom float64
+	for _, segment := range p {
+		n := 0
+		switch segment.op {
+		case segmentMove, segmentLine:
+			n = 1
+		case segmentCubic:
+			n = 3
+		}
+		for _, pt := range segment.points[:n] {
+			if first {
+				left, top, right, bottom = pt.x, pt.y, pt.x, pt.y
+				first = false
+				continue
+			}
+			left, top = minFloat(left, pt.x), minFloat(top, pt.y)
+			right, bottom = maxFloat(right, pt.x), maxFloat(bottom, pt.y)
+		}
+	}
+	return left, top, right - left, bottom - top
+}
+
+// parsePath converts path data into a shape path.
+func parsePath(d string) (shapePath, error) {
+	parsed, err := utils.PathParser(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var p shapePath
+	var x, y, startX, startY float64
+	// reflected control point of the previous curve
+	var controlX, controlY float64
+	previous := ""
+	for _, subpath := range parsed.Subpaths {
+		for _, command := range subpath.Commands {
+			params := command.Params
+			relative := !command.IsAbsolute()
+			abs := func(i int) (float64, float64) {
+				if relative {
+					return x + params[i], y + params[i+1]
+				}
+				return params[i], params[i+1]
+			}
+
+			symbol := strings.ToUpper(command.Symbol)
+			switch symbol {
+			case "M":
+				x, y = abs(0)
+				startX, startY = x, y
+				p.moveTo(x, y)
+			case "L":
+				x, y = abs(0)
+				p.lineTo(x, y)
+			case "H":
+				if relative {
+					x += params[0]
+				} else {
+					x = params[0]
+				}
+				p.lineTo(x, y)
+			case "V":
+				if relative {
+					y += params[0]
+				} else {
+					y = params[0]
+				}
+				p.lineTo(x, y)
+			case "C", "S":
+				x1, y1 := 2*x-controlX, 2*y-controlY
+				if previous != "C" && previous != "S" {
+					x1, y1 = x, y
+				}
+				i := 0
+				if symbol == "C" {
+					x1, y1 = abs(0)
+					i = 2
+				}
+				x2, y2 := abs(i)
+				ex, ey := abs(i + 2)
+				p.cubicTo(x1, y1, x2, y2, ex, ey)
+				controlX, controlY = x2, y2
+				x, y = ex, ey
+			case "Q", "T":
+				x1, y1 := 2*x-controlX, 2*y-controlY
+				if previous != "Q" && previous != "T" {
+					x1, y1 = x, y
+				}
+				i := 0
+				if symbol == "Q" {
+					x1, y1 = abs(0)
+					i = 2
+				}
+				ex, ey := abs(i)
+				p.quadTo(x, y, x1, y1, ex, ey)
+				controlX, controlY = x1, y1
+				x, y = ex, ey
+			case "A":
+				ex, ey := abs(5)
+				p.arcTo(x, y, params[0], params[1], params[2], params[3] != 0, params[4] != 0, ex, ey)
+				x, y = ex, ey
+			case "Z":
+				p.close()
+				x, y = startX, startY
+			}
+			previous = symbol
+		}
+	}
+	return p, nil
+}
+
+// elementPath returns the outline of a path or a basic shape in user
+// units, percentages being of the viewport. It returns false for other
+// elements and for shapes which are not rendered.
+func elementPath(e *Element, viewport [2]float64) (shapePath, bool) {
+	diagonal := math.Sqrt((viewport[0]*viewport[0] + viewport[1]*viewport[1]) / 2)
+	length := func(name string, reference float64) float64 {
+		n, _ := userLength(e.Attributes[name], reference)
+		return n
+	}
+
+	var p shapePath
+	switch e.LocalName() {
+	case "path":
+		parsed, err := parsePath(e.Attributes["d"])
+		if err != nil {
+			return nil, false
+		}
+		p = parsed
+
+	case "rect":
+		x, y := length("x", viewport[0]), length("y", viewport[1])
+		width, height := length("width", viewport[0]), length("height", viewport[1])
+		if width <= 0 || height <= 0 {
+			return nil, false
+		}
+		rx, okX := userLength(e.Attributes["rx"], viewport[0])
+		ry, okY := userLength(e.Attributes["ry"], viewport[1])
+		switch {
+		case !okX && okY:
+			rx = ry
+		case okX && !okY:
+			ry = rx
+		}
+		rx, ry = math.Min(math.Max(rx, 0), width/2), math.Min(math.Max(ry, 0), height/2)
+		if rx == 0 || ry == 0 {
+			p.moveTo(x, y)
+			p.lineTo(x+width, y)
+			p.lineTo(x+width, y+height)
+			p.lineTo(x, y+height)
+			p.close()
+			break
+		}
+		p.moveTo(x+rx, y)
+		p.lineTo(x+width-rx, y)
+		p.arcTo(x+width-rx, y, rx, ry, 0, false, true, x+width, y+ry)
+		p.lineTo(x+width, y+height-ry)
+		p.arcTo(x+width, y+height-ry, rx, ry, 0, false, true, x+width-rx, y+height)
+		p.lineTo(x+rx, y+height)
+		p.arcTo(x+rx, y+height, rx, ry, 0, false, true, x, y+height-ry)
+		p.lineTo(x, y+ry)
+		p.arcTo(x, y+ry, rx, ry, 0, false, true, x+rx, y)
+		p.close()
+
+	case "circle", "ellipse":
+		cx, cy := length("cx", viewport[0]), length("cy", viewport[1])
+		rx, ry := length("rx", viewport[0]), length("ry", viewport[1])
+		if e.LocalName() == "circle" {
+			rx = length("r", diagonal)
+			ry = rx
+		}
+		if rx <= 0 || ry <= 0 {
+			return nil, false
+		}
+		p.moveTo(cx+rx, cy)
+		p.arcTo(cx+rx, cy, rx, ry, 0, false, true, cx-rx, cy)
+		p.arcTo(cx-rx, cy, rx, ry, 0, false, true, cx+rx, cy)
+		p.close()
+
+	case "line":
+		p.moveTo(length("x1", viewport[0]), length("y1", viewport[1]))
+		p.lineTo(length("x2", viewport[0]), length("y2", viewport[1]))
+
+	case "polyline", "polygon":
+		items := splitList(e.Attributes["points"])
+		for i := 0; i+1 < len(items); i += 2 {
+			x, errX := parseNumber(items[i])
+			y, errY := parseNumber(items[i+1])
+			if errX != nil || errY != nil {
+				break
+			}
+			if i == 0 {
+				p.moveTo(x, y)
+			} else {
+				p.lineTo(x, y)
+			}
+		}
+		if len(p) > 0 && e.LocalName() == "polygon" {
+			p.close()
+		}
+
+	default:
+		return nil, false
+	}
+	return p, len(p) > 0
+}
@@ -0,0 +1,208 @@
+package svg
+
+import (
+	"math"
+	"testing"
+)
+
+func equalMatrices(a, b matrix) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseTransform(t *testing.T) {
+	var testCases = []struct {
+		value    string
+		expected matrix
+	}{
+		{"", identity},
+		{"translate(10)", matrix{1, 0, 0, 1, 10, 0}},
+		{"translate(10, 20)", matrix{1, 0, 0, 1, 10, 20}},
+		{"scale(2)", matrix{2, 0, 0, 2, 0, 0}},
+		{"scale(2 3)", matrix{2, 0, 0, 3, 0, 0}},
+		{"rotate(90)", matrix{0, 1, -1, 0, 0, 0}},
+		{"rotate(90 10 10)", matrix{0, 1, -1, 0, 20, 0}},
+		{"skewX(45)", matrix{1, 0, 1, 1, 0, 0}},
+		{"skewY(45)", matrix{1, 1, 0, 1, 0, 0}},
+		{"matrix(1 2 3 4 5 6)", matrix{1, 2, 3, 4, 5, 6}},
+		{"translate(10 0) scale(2)", matrix{2, 0, 0, 2, 10, 0}},
+		{"scale(2),translate(10 0)", matrix{2, 0, 0, 2, 20, 0}},
+	}
+
+	for _, test := range testCases {
+		actual, err := parseTransform(test.value)
+		if err != nil || !equalMatrices(actual, test.expected) {
+			t.Errorf("parseTransform %q: expected %v, actual %v %v\n", test.value, test.expected, actual, err)
+		}
+	}
+
+	for _, value := range []string{"translate(1 2 3)", "move(1)", "scale(a)"} {
+		if _, err := parseTransform(value); err == nil {
+			t.Errorf("parseTransform %q: expected error\n", value)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	m := translation(10, 20).multiply(rotation(30)).multiply(scaling(2, 3))
+	inverse, ok := m.invert()
+	if !ok || !equalMatrices(m.multiply(inverse), identity) {
+		t.Errorf("invert: expected the inverse of %v, actual %v\n", m, inverse)
+	}
+	if _, ok := scaling(0, 1).invert(); ok {
+		t.Errorf("invert: expected no inverse\n")
+	}
+	if actual := scaling(2, 8).scale(); actual != 4 {
+		t.Errorf("scale: expected 4, actual %v\n", actual)
+	}
+	if x, y := m.apply(0, 0); x != 10 || y != 20 {
+		t.Errorf("apply: expected 10 20, actual %v %v\n", x, y)
+	}
+}
+
+func TestViewBoxTransform(t *testing.T) {
+	box := [4]float64{10, 10, 100, 50}
+	var testCases = []struct {
+		preserveAspectRatio string
+		width, height       float64
+		expected            matrix
+	}{
+		{"", 100, 80, matrix{1, 0, 0, 1, -10, 5}},
+		{"xMinYMin", 100, 80, matrix{1, 0, 0, 1, -10, -10}},
+		{"xMaxYMax meet", 100, 80, matrix{1, 0, 0, 1, -10, 20}},
+		{"xMidYMid slice", 200, 50, matrix{2, 0, 0, 2, -20, -45}},
+		{"none", 200, 50, matrix{2, 0, 0, 1, -20, -10}},
+	}
+
+	for _, test := range testCases {
+		actual := viewBoxTransform(box, test.preserveAspectRatio, test.width, test.height)
+		if !equalMatrices(actual, test.expected) {
+			t.Errorf("viewBoxTransform %q: expected %v, actual %v\n", test.preserveAspectRatio, test.expected, actual)
+		}
+	}
+
+	if _, ok := parseViewBox("0 0 0 10"); ok {
+		t.Errorf("parseViewBox: expected an invalid box\n")
+	}
+	if box, ok := parseViewBox("0,0 20 10"); !ok || box != [4]float64{0, 0, 20, 10} {
+		t.Errorf("parseViewBox: expected [0 0 20 10], actual %v\n", box)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	var testCases = []struct {
+		d      string
+		bounds [4]float64
+		end    point
+	}{
+		{"M10 10 L20 10 L20 20 Z", [4]float64{10, 10, 10, 10}, point{10, 10}},
+		{"m10 10 h10 v10 h-10 z", [4]float64{10, 10, 10, 10}, point{10, 10}},
+		{"M0 0 H5 V5", [4]float64{0, 0, 5, 5}, point{5, 5}},
+		{"M0 0 C0 10 10 10 10 0 S20 -10 20 0", [4]float64{0, -10, 20, 20}, point{20, 0}},
+		{"M0 0 Q5 10 10 0 T20 0", [4]float64{0, -6.666667, 20, 13.333333}, point{20, 0}},
+		{"M0 0 A5 5 0 0 1 10 0", [4]float64{0, -5, 10, 5}, point{10, 0}},
+		{"M0 0 a5 5 0 1 0 10 0", [4]float64{0, 0, 10, 5}, point{10, 0}},
+	}
+
+	for _, test := range testCases {
+		p, err := parsePath(test.d)
+		if err != nil {
+			t.Errorf("parsePath %q: unexpected error %v\n", test.d, err)
+			continue
+		}
+
+		x, y, width, height := p.bounds()
+		lines := p.flatten(0.01)
+		last := lines[len(lines)-1]
+		end := last.points[len(last.points)-1]
+		if last.closed {
+			end = last.points[0]
+		}
+		for i, value := range []float64{x, y, width, height} {
+			if math.Abs(value-test.bounds[i]) > 1e-3 {
+				t.Errorf("parsePath %q: expected bounds %v, actual %v\n", test.d, test.bounds, []float64{x, y, width, height})
+				break
+			}
+		}
+		if math.Abs(end.x-test.end.x) > 1e-9 || math.Abs(end.y-test.end.y) > 1e-9 {
+			t.Errorf("parsePath %q: expected end %v, actual %v\n", test.d, test.end, end)
+		}
+	}
+
+	if _, err := parsePath("M0 0 L10"); err == nil {
+		t.Errorf("parsePath: expected error\n")
+	}
+}
+
+func TestArc(t *testing.T) {
+	var p shapePath
+	p.moveTo(10, 0)
+	p.arcTo(10, 0, 10, 10, 0, false, true, -10, 0)
+	p.arcTo(-10, 0, 10, 10, 0, false, true, 10, 0)
+
+	for _, line := range p.flatten(0.001) {
+		for _, pt := range line.points {
+			if r := math.Hypot(pt.x, pt.y); math.Abs(r-10) > 0.01 {
+				t.Errorf("arcTo: expected points on the circle, actual %v at %v\n", pt, r)
+				return
+			}
+		}
+	}
+}
+
+func TestElementPath(t *testing.T) {
+	viewport := [2]float64{200, 100}
+	var testCases = []struct {
+		element *Element
+		bounds  [4]float64
+		ok      bool
+	}{
+		{element("rect", map[string]string{"x": "10", "y": "20", "width": "30", "height": "40"}), [4]float64{10, 20, 30, 40}, true},
+		{element("rect", map[string]string{"width": "50%", "height": "50%", "rx": "5"}), [4]float64{0, 0, 100, 50}, true},
+		{element("rect", map[string]string{"width": "10"}), [4]float64{}, false},
+		{element("circle", map[string]string{"cx": "10", "cy": "10", "r": "5"}), [4]float64{5, 5, 10, 10}, true},
+		{element("circle", map[string]string{"r": "0"}), [4]float64{}, false},
+		{element("ellipse", map[string]string{"rx": "10", "ry": "5"}), [4]float64{-10, -5, 20, 10}, true},
+		{element("line", map[string]string{"x1": "1in", "x2": "10", "y2": "10"}), [4]float64{10, 0, 86, 10}, true},
+		{element("polygon", map[string]string{"points": "0,0 10,0 10,10"}), [4]float64{0, 0, 10, 10}, true},
+		{element("polyline", map[string]string{"points": "0 0 10 5 20"}), [4]float64{0, 0, 10, 5}, true},
+		{element("path", map[string]string{"d": "M0 0 L10 x"}), [4]float64{}, false},
+		{element("g", nil), [4]float64{}, false},
+	}
+
+	for _, test := range testCases {
+		p, ok := elementPath(test.element, viewport)
+		if ok != test.ok {
+			t.Errorf("elementPath %v: expected %v, actual %v\n", test.element.Attributes, test.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// the bounds of the flattened curves, the control points of arcs
+		// being outside of the shape
+		first := true
+		var bounds [4]float64
+		for _, line := range p.flatten(0.001) {
+			for _, pt := range line.points {
+				if first {
+					bounds = [4]float64{pt.x, pt.y, pt.x, pt.y}
+					first = false
+				}
+				bounds = [4]float64{minFloat(bounds[0], pt.x), minFloat(bounds[1], pt.y), maxFloat(bounds[2], pt.x), maxFloat(bounds[3], pt.y)}
+			}
+		}
+		bounds[2], bounds[3] = bounds[2]-bounds[0], bounds[3]-bounds[1]
+		for i := range bounds {
+			if math.Abs(bounds[i]-test.bounds[i]) > 0.01 {
+				t.Errorf("elementPath %v: expected bounds %v, actual %v\n", test.element.Attributes, test.bounds, bounds)
+				break
+			}
+		}
+	}
+}
@@ -1,5 +1,10 @@
 module github.com/galihrivanto/svg
 
-go 1.15
+go 1.18
 
-require golang.org/x/net v0.0.0-20210119194325-5f4716e94777
+require (
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.0.0-20210119194325-5f4716e94777
+)
+
+require golang.org/x/text v0.16.0 // indirect
@@ -0,0 +1,132 @@
+package svg
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"  // measure GIF images
+	_ "image/jpeg" // measure JPEG images
+	_ "image/png"  // measure PNG images
+	"net/http"
+	"strings"
+)
+
+// ImageFit is the way an image is fitted to the box of its element.
+type ImageFit int
+
+// fit modes
+const (
+	// FitNone leaves the element as it is.
+	FitNone ImageFit = iota
+	// FitContain shows the whole image, keeping its aspect ratio. The box
+	// shrinks to the image.
+	FitContain
+	// FitCover fills the box, keeping the aspect ratio of the image and
+	// clipping what overflows.
+	FitCover
+	// FitStretch fills the box, distorting the image.
+	FitStretch
+)
+
+// detectImageType returns the media type of image data, recognizing SVG
+// which http.DetectContentType reports as text.
+func detectImageType(data []byte) string {
+	mediaType := http.DetectContentType(data)
+	if strings.HasPrefix(mediaType, "text/") && isSVGData(data) {
+		return "image/svg+xml"
+	}
+	return mediaType
+}
+
+// isSVGData reports whether data is an SVG document.
+func isSVGData(data []byte) bool {
+	root, err := Parse(bytes.NewReader(data), false)
+	return err == nil && root.LocalName() == "svg"
+}
+
+// imageSize returns the size of image data, false when it is unknown.
+func imageSize(data []byte) (float64, float64, bool) {
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		return float64(config.Width), float64(config.Height), config.Width > 0 && config.Height > 0
+	}
+
+	root, err := Parse(bytes.NewReader(data), false)
+	if err != nil || root.LocalName() != "svg" {
+		return 0, 0, false
+	}
+
+	width, okWidth := imageLength(root.Attributes["width"])
+	height, okHeight := imageLength(root.Attributes["height"])
+	if okWidth && okHeight {
+		return width, height, width > 0 && height > 0
+	}
+
+	box := strings.FieldsFunc(root.Attributes["viewBox"], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(box) != 4 {
+		return 0, 0, false
+	}
+	width, okWidth = imageLength(box[2])
+	height, okHeight = imageLength(box[3])
+	return width, height, okWidth && okHeight && width > 0 && height > 0
+}
+
+// imageLength parses a length in user units, false for other units.
+func imageLength(value string) (float64, bool) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := parseNumber(value)
+	return n, err == nil
+}
+
+// fitImage sets the box and preserveAspectRatio of an image element for
+// the image data.
+func fitImage(el *Element, data []byte, fit ImageFit) {
+	switch fit {
+	case FitContain:
+		el.Attributes["preserveAspectRatio"] = "xMidYMid meet"
+	case FitCover:
+		el.Attributes["preserveAspectRatio"] = "xMidYMid slice"
+	case FitStretch:
+		el.Attributes["preserveAspectRatio"] = "none"
+	}
+
+	imageWidth, imageHeight, ok := imageSize(data)
+	if !ok {
+		return
+	}
+
+	width, okWidth := imageLength(el.Attributes["width"])
+	height, okHeight := imageLength(el.Attributes["height"])
+	_, hasWidth := el.Attributes["width"]
+	_, hasHeight := el.Attributes["height"]
+
+	switch {
+	case !hasWidth && !hasHeight:
+		// intrinsic size
+		width, height = imageWidth, imageHeight
+	case okWidth && !hasHeight:
+		height = width * imageHeight / imageWidth
+	case okHeight && !hasWidth:
+		width = height * imageWidth / imageHeight
+	case okWidth && okHeight && fit == FitContain:
+		// shrink the box to the image, centered
+		x, okX := imageLength(el.Attributes["x"])
+		y, okY := imageLength(el.Attributes["y"])
+		if !(okX || el.Attributes["x"] == "") || !(okY || el.Attributes["y"] == "") {
+			return
+		}
+		fitted, fittedHeight := width, width*imageHeight/imageWidth
+		if fittedHeight > height {
+			fitted, fittedHeight = height*imageWidth/imageHeight, height
+		}
+		el.Attributes["x"] = formatNumber(x + (width-fitted)/2)
+		el.Attributes["y"] = formatNumber(y + (height-fittedHeight)/2)
+		width, height = fitted, fittedHeight
+	default:
+		// the box is kept
+		return
+	}
+
+	el.Attributes["width"] = formatNumber(width)
+	el.Attributes["height"] = formatNumber(height)
+}
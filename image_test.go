@@ -0,0 +1,104 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngImage returns a base64 encoded PNG of the size.
+func pngImage(width, height int) string {
+	var data bytes.Buffer
+	png.Encode(&data, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return base64.StdEncoding.EncodeToString(data.Bytes())
+}
+
+func TestSet64ImageType(t *testing.T) {
+	var testCases = []struct {
+		content  string
+		expected string
+	}{
+		{pngImage(2, 1), "data:image/png;base64,"},
+		{base64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"/>`)), "data:image/svg+xml;base64,"},
+		{base64.StdEncoding.EncodeToString([]byte(`<?xml version="1.0"?><svg/>`)), "data:image/svg+xml;base64,"},
+		{base64.StdEncoding.EncodeToString([]byte("plain text")), "data:text/plain; charset=utf-8;base64,"},
+		{"data:image/gif;base64,R0lGODlh", "data:image/gif;base64,"},
+	}
+
+	for _, test := range testCases {
+		root := element("image", map[string]string{"id": "img"})
+		if err := Set64Image(root, "img", test.content); err != nil {
+			t.Errorf("Set64Image: unexpected error %v\n", err)
+			continue
+		}
+		if actual := root.Attributes["xlink:href"]; !strings.HasPrefix(actual, test.expected) {
+			t.Errorf("Set64Image: expected %v, actual %v\n", test.expected, actual)
+		}
+	}
+
+	// line-wrapped content
+	root := element("image", map[string]string{"id": "img"})
+	content := pngImage(2, 1)
+	if err := Set64Image(root, "img", content[:20]+"\r\n "+content[20:]+"\n"); err != nil {
+		t.Errorf("Set64Image: unexpected error %v\n", err)
+	}
+	if expected, actual := "data:image/png;base64,"+content, root.Attributes["xlink:href"]; actual != expected {
+		t.Errorf("Set64Image: expected %v, actual %v\n", expected, actual)
+	}
+
+	if err := Set64Image(element("image", map[string]string{"id": "img"}), "img", "not base64!"); err == nil {
+		t.Errorf("Set64Image: expected error for invalid content\n")
+	}
+}
+
+func TestSet64ImageFit(t *testing.T) {
+	svgImage := base64.StdEncoding.EncodeToString([]byte(`<svg viewBox="0 0 30 60"/>`))
+
+	var testCases = []struct {
+		attributes map[string]string
+		content    string
+		fit        ImageFit
+		expected   map[string]string
+	}{
+		// intrinsic size
+		{map[string]string{}, pngImage(40, 20), FitContain,
+			map[string]string{"width": "40", "height": "20", "preserveAspectRatio": "xMidYMid meet"}},
+		// missing height from the aspect ratio
+		{map[string]string{"width": "100"}, pngImage(40, 20), FitStretch,
+			map[string]string{"width": "100", "height": "50", "preserveAspectRatio": "none"}},
+		{map[string]string{"height": "30px"}, svgImage, FitCover,
+			map[string]string{"width": "15", "height": "30", "preserveAspectRatio": "xMidYMid slice"}},
+		// box shrunk to the image
+		{map[string]string{"x": "10", "width": "100", "height": "100"}, pngImage(40, 20), FitContain,
+			map[string]string{"x": "10", "y": "25", "width": "100", "height": "50"}},
+		{map[string]string{"width": "100", "height": "100"}, svgImage, FitContain,
+			map[string]string{"x": "25", "y": "0", "width": "50", "height": "100"}},
+		// box kept
+		{map[string]string{"width": "100", "height": "100"}, pngImage(40, 20), FitCover,
+			map[string]string{"width": "100", "height": "100", "preserveAspectRatio": "xMidYMid slice"}},
+		{map[string]string{"width": "50%", "height": "100"}, pngImage(40, 20), FitContain,
+			map[string]string{"width": "50%", "height": "100"}},
+		{map[string]string{"width": "100", "height": "100"}, pngImage(40, 20), FitNone,
+			map[string]string{"width": "100", "height": "100", "preserveAspectRatio": ""}},
+	}
+
+	for i, test := range testCases {
+		attributes := map[string]string{"id": "img"}
+		for k, v := range test.attributes {
+			attributes[k] = v
+		}
+		doc := NewDocument(element("image", attributes))
+		if err := doc.Set64ImageFit("img", test.content, test.fit); err != nil {
+			t.Errorf("Set64ImageFit %d: unexpected error %v\n", i, err)
+			continue
+		}
+		for k, v := range test.expected {
+			if actual := doc.Root.Attributes[k]; actual != v {
+				t.Errorf("Set64ImageFit %d %s: expected %v, actual %v\n", i, k, v, actual)
+			}
+		}
+	}
+}
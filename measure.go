@@ -0,0 +1,276 @@
+package svg
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TextMetrics is the size of the text of an element.
+type TextMetrics struct {
+	// Width is the advance width of the widest line.
+	Width float64
+	// Ascent and Descent are the largest of the fonts used, both
+	// positive.
+	Ascent, Descent float64
+	// Lines are the lines of the text, started by the text element and
+	// by the tspans holding an x or y coordinate.
+	Lines []TextLine
+}
+
+// TextLine is a line of text.
+type TextLine struct {
+	// X and Y are the position of the anchor of the line on its
+	// baseline.
+	X, Y float64
+	// Anchor is the text-anchor of the line: start, middle or end.
+	Anchor string
+	Text   string
+	Width  float64
+}
+
+// Left returns the x of the start of the line, given its anchor.
+func (l TextLine) Left() float64 {
+	switch l.Anchor {
+	case "middle":
+		return l.X - l.Width/2
+	case "end":
+		return l.X - l.Width
+	}
+	return l.X
+}
+
+// MeasureElement measures the text of a text, tspan or flowPara element,
+// with the fonts of the registry. The font of each piece of text is
+// resolved from the font-family, font-weight, font-style and font-size
+// attributes and style properties of its element and its ancestors,
+// letter-spacing is added to the advance of every character. White space
+// is collapsed unless xml:space is preserve.
+func (r *FontRegistry) MeasureElement(e *Element) TextMetrics {
+	var metrics TextMetrics
+	m := &textMeasure{registry: r, metrics: &metrics}
+	m.newLine(e)
+	m.measure(e)
+	m.trimLine()
+
+	for _, line := range metrics.Lines {
+		if line.Width > metrics.Width {
+			metrics.Width = line.Width
+		}
+	}
+	return metrics
+}
+
+// TextBounds returns the box of the text of an element, from the top of
+// its highest line to the bottom of its lowest one, taking text-anchor
+// into account. It returns zeros when the element has no text.
+func (r *FontRegistry) TextBounds(e *Element) (x, y, width, height float64) {
+	metrics := r.MeasureElement(e)
+
+	first := true
+	var left, top, right, bottom float64
+	for _, line := range metrics.Lines {
+		if line.Text == "" {
+			continue
+		}
+		l, t := line.Left(), line.Y-metrics.Ascent
+		rt, b := l+line.Width, line.Y+metrics.Descent
+		if first {
+			left, top, right, bottom = l, t, rt, b
+			first = false
+			continue
+		}
+		left, top = minFloat(left, l), minFloat(top, t)
+		right, bottom = maxFloat(right, rt), maxFloat(bottom, b)
+	}
+	return left, top, right - left, bottom - top
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// textMeasure accumulates the lines of a text.
+type textMeasure struct {
+	registry *FontRegistry
+	metrics  *TextMetrics
+	// trailing white space of the current line, to collapse
+	space bool
+	// white space measured at the end of the current line, trimmed when
+	// the line ends
+	spaceWidth float64
+}
+
+func (m *textMeasure) line() *TextLine {
+	return &m.metrics.Lines[len(m.metrics.Lines)-1]
+}
+
+// newLine starts a line at the position of an element.
+func (m *textMeasure) newLine(e *Element) {
+	x, y := textPosition(e, nil)
+	if len(m.metrics.Lines) > 0 {
+		// missing coordinates continue the previous line
+		previous := m.line()
+		if strings.TrimSpace(e.Attributes["x"]) == "" {
+			x = previous.X + previous.Width
+		}
+		if strings.TrimSpace(e.Attributes["y"]) == "" {
+			y = previous.Y
+		}
+	}
+
+	anchor := computedStyle(e, "text-anchor")
+	if anchor == "" {
+		anchor = "start"
+	}
+	m.metrics.Lines = append(m.metrics.Lines, TextLine{X: x, Y: y, Anchor: anchor})
+	m.space = true
+	m.spaceWidth = 0
+}
+
+// trimLine removes the white space ending the current line.
+func (m *textMeasure) trimLine() {
+	line := m.line()
+	if strings.HasSuffix(line.Text, " ") {
+		line.Text = strings.TrimRight(line.Text, " ")
+		line.Width -= m.spaceWidth
+	}
+	m.spaceWidth = 0
+}
+
+func (m *textMeasure) measure(e *Element) {
+	for _, node := range childNodes(e) {
+		switch node.Type {
+		case XPathTextNode:
+			m.add(e, node.Node.Data)
+
+		case XPathElementNode:
+			child := node.Element
+			switch child.LocalName() {
+			case "tspan", "textPath", "a", "flowSpan", "altGlyph":
+			default:
+				// titles, descriptions and the like are not rendered
+				continue
+			}
+
+			_, hasX := child.Attributes["x"]
+			_, hasY := child.Attributes["y"]
+			if hasX || hasY {
+				m.trimLine()
+				m.newLine(child)
+			}
+			m.measure(child)
+		}
+	}
+}
+
+// add measures a piece of text of an element.
+func (m *textMeasure) add(e *Element, text string) {
+	text = collapseSpace(text, preservesSpace(e), &m.space)
+	if text == "" {
+		return
+	}
+
+	f, face := m.registry.elementFont(e)
+	spacing := letterSpacing(computedStyle(e, "letter-spacing"), face.Size)
+	width := func(s string) float64 {
+		if s == "" {
+			return 0
+		}
+		w := spacing * float64(utf8.RuneCountInString(s))
+		if f != nil {
+			return w + f.MeasureText(s, face)
+		}
+		return w + m.registry.MeasureText(s, face)
+	}
+
+	line := m.line()
+	line.Text += text
+	line.Width += width(text)
+
+	trimmed := strings.TrimRight(text, " ")
+	if trimmed == "" {
+		m.spaceWidth += width(text)
+	} else {
+		m.spaceWidth = width(text[len(trimmed):])
+	}
+
+	ascent, descent := m.registry.Metrics(face)
+	if f != nil {
+		ascent, descent = f.Metrics(face.Size)
+	}
+	m.metrics.Ascent = maxFloat(m.metrics.Ascent, ascent)
+	m.metrics.Descent = maxFloat(m.metrics.Descent, descent)
+}
+
+// elementFont returns the font and the face of the text of an element,
+// the font being the first registered family of its font-family.
+func (r *FontRegistry) elementFont(e *Element) (*Font, FontFace) {
+	face := computedFace(e)
+	families := strings.Split(computedStyle(e, "font-family"), ",")
+	for i := range families {
+		families[i] = strings.Trim(strings.TrimSpace(families[i]), `'"`)
+	}
+	return r.lookup(families, face), face
+}
+
+// preservesSpace reports whether white space is kept in the text of an
+// element.
+func preservesSpace(e *Element) bool {
+	for el := e; el != nil; el = el.Parent() {
+		if space, ok := el.Attributes["xml:space"]; ok {
+			return space == "preserve"
+		}
+	}
+	return false
+}
+
+// collapseSpace turns line breaks and tabs into spaces and, unless
+// preserve is set, collapses consecutive spaces. space tells whether the
+// previous text ended with a space, or starts a line, and is updated.
+func collapseSpace(text string, preserve bool, space *bool) string {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text)
+	if preserve {
+		return text
+	}
+
+	var collapsed strings.Builder
+	for _, r := range text {
+		if r == ' ' {
+			if *space {
+				continue
+			}
+			*space = true
+		} else {
+			*space = false
+		}
+		collapsed.WriteRune(r)
+	}
+	return collapsed.String()
+}
+
+// letterSpacing returns a letter-spacing in user units.
+func letterSpacing(value string, size float64) float64 {
+	switch {
+	case value == "" || value == "normal":
+		return 0
+	case strings.HasSuffix(value, "em"):
+		if n, err := parseNumber(strings.TrimSuffix(value, "em")); err == nil {
+			return n * size
+		}
+	default:
+		if n, ok := absoluteLength(value); ok {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package svg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeasureElement(t *testing.T) {
+	registry := testRegistry(t)
+	regular := FontFace{Family: "Go", Size: 10, Weight: 400}
+	bold := FontFace{Family: "Go", Size: 10, Weight: 700}
+	measure := func(text string, face FontFace) float64 {
+		return registry.Lookup(face).MeasureText(text, face)
+	}
+
+	root, _ := parse(`
+		<svg font-family="Go" font-size="10">
+			<text id="simple" x="5" y="20">  Hello
+				world  </text>
+			<text id="spans" x="0" y="0" text-anchor="middle">Hello <tspan font-weight="bold">bold</tspan></text>
+			<text id="spacing" style="letter-spacing:1px">abc</text>
+			<text id="lines" x="10" y="10" text-anchor="end">
+				<tspan x="10" y="10">one</tspan>
+				<tspan x="10" y="22">three</tspan>
+			</text>
+			<text id="preserve" xml:space="preserve">a  b</text>
+			<text id="empty"><title>not rendered</title></text>
+		</svg>
+	`, false)
+
+	var testCases = []struct {
+		id    string
+		lines []TextLine
+	}{
+		{"simple", []TextLine{{5, 20, "start", "Hello world", measure("Hello world", regular)}}},
+		{"spans", []TextLine{{0, 0, "middle", "Hello bold", measure("Hello ", regular) + measure("bold", bold)}}},
+		{"spacing", []TextLine{{0, 0, "start", "abc", measure("abc", regular) + 3}}},
+		{"lines", []TextLine{
+			{10, 10, "end", "", 0},
+			{10, 10, "end", "one", measure("one", regular)},
+			{10, 22, "end", "three", measure("three", regular)},
+		}},
+		{"preserve", []TextLine{{0, 0, "start", "a  b", measure("a  b", regular)}}},
+		{"empty", []TextLine{{0, 0, "start", "", 0}}},
+	}
+
+	for _, test := range testCases {
+		metrics := registry.MeasureElement(root.FindID(test.id))
+		if len(metrics.Lines) != len(test.lines) {
+			t.Errorf("MeasureElement %s: expected %v, actual %v\n", test.id, test.lines, metrics.Lines)
+			continue
+		}
+		for i, line := range metrics.Lines {
+			expected := test.lines[i]
+			if line.X != expected.X || line.Y != expected.Y || line.Anchor != expected.Anchor || line.Text != expected.Text ||
+				math.Abs(line.Width-expected.Width) > 1e-9 {
+				t.Errorf("MeasureElement %s: expected %+v, actual %+v\n", test.id, expected, line)
+			}
+		}
+	}
+
+	metrics := registry.MeasureElement(root.FindID("lines"))
+	if expected := measure("three", regular); math.Abs(metrics.Width-expected) > 1e-9 {
+		t.Errorf("MeasureElement: expected %v, actual %v\n", expected, metrics.Width)
+	}
+	ascent, descent := registry.Metrics(regular)
+	if metrics.Ascent != ascent || metrics.Descent != descent {
+		t.Errorf("MeasureElement: expected %v %v, actual %v %v\n", ascent, descent, metrics.Ascent, metrics.Descent)
+	}
+}
+
+func TestTextBounds(t *testing.T) {
+	registry := testRegistry(t)
+	face := FontFace{Family: "Go", Size: 10, Weight: 400}
+	ascent, descent := registry.Metrics(face)
+	one, three := registry.MeasureText("one", face), registry.MeasureText("three", face)
+
+	root, _ := parse(`
+		<svg font-family="Go" font-size="10">
+			<text id="lines" text-anchor="middle"><tspan x="50" y="10">one</tspan><tspan x="50" y="22">three</tspan></text>
+			<text id="empty" x="5" y="5"></text>
+		</svg>
+	`, false)
+
+	x, y, width, height := registry.TextBounds(root.FindID("lines"))
+	expected := []float64{50 - three/2, 10 - ascent, three, 12 + ascent + descent}
+	for i, actual := range []float64{x, y, width, height} {
+		if math.Abs(actual-expected[i]) > 1e-9 {
+			t.Errorf("TextBounds: expected %v, actual %v\n", expected, []float64{x, y, width, height})
+			break
+		}
+	}
+	if one >= three {
+		t.Errorf("TextBounds: expected one narrower than three\n")
+	}
+
+	if x, y, width, height := registry.TextBounds(root.FindID("empty")); x != 0 || y != 0 || width != 0 || height != 0 {
+		t.Errorf("TextBounds: expected zeros, actual %v %v %v %v\n", x, y, width, height)
+	}
+}
@@ -0,0 +1,266 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ImportOptions controls how Import adds a document to another one.
+type ImportOptions struct {
+	// Prefix is put in front of every imported id. When empty only the
+	// ids already used in the destination are renamed, with a numbered
+	// suffix.
+	Prefix string
+
+	// X and Y position the imported content, Scale resizes it when not
+	// zero.
+	X, Y  float64
+	Scale float64
+
+	// Nested keeps the imported root as a nested svg element of the given
+	// Width and Height, so that its viewBox still applies. Otherwise its
+	// children are put in a group.
+	Nested        bool
+	Width, Height string
+}
+
+// Import adds a copy of source, usually the root of another document, as
+// the last child of the element. The source is not changed.
+//
+// Ids of the copy are renamed to be unique in the destination and the
+// references to them are updated. Definitions found in <defs> which are
+// identical to definitions of the destination, apart from their id, are
+// dropped in favour of the existing ones, the others are moved to the
+// first <defs> of the destination. The element wrapping the imported
+// content is returned.
+func (e *Element) Import(source *Element, options ImportOptions) *Element {
+	root := e
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+
+	clone := source.Clone()
+	// the declaration and other document level nodes of the source are
+	// not valid within the destination
+	clone.Prolog, clone.Epilog = nil, nil
+	taken := collectIDs(root)
+	renames := make(map[string]string)
+
+	// reuse identical definitions
+	definitions := definitionsOf(root)
+	for _, definition := range definitionsOf(clone) {
+		id := definition.Attributes["id"]
+		if id == "" {
+			continue
+		}
+		for _, existing := range definitions {
+			if sameDefinition(definition, existing) {
+				renames[id] = existing.Attributes["id"]
+				definition.Remove()
+				break
+			}
+		}
+	}
+
+	// rename the other ids
+	clone.Walk(func(el *Element, depth int) WalkAction {
+		id, ok := el.Attributes["id"]
+		if !ok {
+			return WalkContinue
+		}
+
+		renamed := options.Prefix + id
+		for n := 1; taken[renamed] != nil; n++ {
+			renamed = options.Prefix + id + "-" + strconv.Itoa(n)
+		}
+		taken[renamed] = el
+		if _, found := renames[id]; !found {
+			renames[id] = renamed
+		}
+		el.Attributes["id"] = renamed
+		return WalkContinue
+	})
+
+	rewriteReferences(clone, func(id string) (string, bool) {
+		renamed, ok := renames[id]
+		return renamed, ok && renamed != id
+	})
+
+	mergeDefinitions(root, clone)
+
+	wrapper := importWrapper(clone, options)
+	e.AppendChild(wrapper)
+	return wrapper
+}
+
+// collectIDs returns the elements of the tree by id, the first one in
+// document order winning.
+func collectIDs(root *Element) map[string]*Element {
+	ids := make(map[string]*Element)
+	root.Walk(func(el *Element, depth int) WalkAction {
+		if id, ok := el.Attributes["id"]; ok {
+			if _, found := ids[id]; !found {
+				ids[id] = el
+			}
+		}
+		return WalkContinue
+	})
+	return ids
+}
+
+// definitionsOf returns the children of the defs elements of the tree.
+func definitionsOf(root *Element) []*Element {
+	var definitions []*Element
+	root.Walk(func(el *Element, depth int) WalkAction {
+		if el.LocalName() == "defs" {
+			definitions = append(definitions, el.Children...)
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+	return definitions
+}
+
+// sameDefinition compares two definitions, ignoring their id.
+func sameDefinition(a, b *Element) bool {
+	if a.Name != b.Name || a.Content != b.Content ||
+		len(a.Attributes) != len(b.Attributes) || len(a.Children) != len(b.Children) {
+		return false
+	}
+
+	for k, v := range a.Attributes {
+		if other, ok := b.Attributes[k]; !ok || (k != "id" && v != other) {
+			return false
+		}
+	}
+
+	for i, child := range a.Children {
+		if !child.Compare(b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeDefinitions moves the definitions of imported into the first defs
+// of root, which is created if needed. Emptied defs are removed.
+func mergeDefinitions(root, imported *Element) {
+	var defs []*Element
+	imported.Walk(func(el *Element, depth int) WalkAction {
+		if el.LocalName() == "defs" {
+			defs = append(defs, el)
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+	if len(defs) == 0 {
+		return
+	}
+
+	var target *Element
+	root.Walk(func(el *Element, depth int) WalkAction {
+		if el.LocalName() == "defs" {
+			target = el
+			return WalkStop
+		}
+		return WalkContinue
+	})
+
+	if target == nil {
+		target = &Element{
+			Name:       strings.TrimSuffix(root.Name, root.LocalName()) + "defs",
+			Space:      root.Space,
+			Namespaces: root.Namespaces,
+			Attributes: make(map[string]string),
+		}
+		if len(root.Children) > 0 {
+			root.InsertBefore(target, root.Children[0])
+		} else {
+			root.AppendChild(target)
+		}
+	}
+
+	for _, d := range defs {
+		for len(d.Children) > 0 {
+			target.AppendChild(d.Children[0])
+		}
+		if d.Parent() != nil {
+			d.Remove()
+		}
+	}
+}
+
+// svgGeometry lists the attributes of an svg root which do not apply
+// to its content once put in a group.
+var svgGeometry = map[string]bool{
+	"x":                   true,
+	"y":                   true,
+	"width":               true,
+	"height":              true,
+	"viewBox":             true,
+	"preserveAspectRatio": true,
+	"version":             true,
+	"baseProfile":         true,
+}
+
+// importWrapper puts the imported root in place.
+func importWrapper(imported *Element, options ImportOptions) *Element {
+	// namespaces are declared where needed when rendering
+	for name := range imported.Attributes {
+		if name == "xmlns" || strings.HasPrefix(name, "xmlns:") {
+			delete(imported.Attributes, name)
+		}
+	}
+
+	isSVG := imported.LocalName() == "svg"
+	group := &Element{
+		Name:       strings.TrimSuffix(imported.Name, imported.LocalName()) + "g",
+		Space:      imported.Space,
+		Namespaces: imported.Namespaces,
+		Attributes: make(map[string]string),
+	}
+
+	var transform []string
+	if (options.X != 0 || options.Y != 0) && !(options.Nested && isSVG) {
+		transform = append(transform, fmt.Sprintf("translate(%s %s)", formatNumber(options.X), formatNumber(options.Y)))
+	}
+	if options.Scale != 0 && options.Scale != 1 {
+		transform = append(transform, fmt.Sprintf("scale(%s)", formatNumber(options.Scale)))
+	}
+	if len(transform) > 0 {
+		group.Attributes["transform"] = strings.Join(transform, " ")
+	}
+
+	switch {
+	case options.Nested && isSVG:
+		for name, value := range map[string]string{
+			"x":      formatNumber(options.X),
+			"y":      formatNumber(options.Y),
+			"width":  options.Width,
+			"height": options.Height,
+		} {
+			if value != "" && value != "0" {
+				imported.Attributes[name] = value
+			}
+		}
+		if len(transform) == 0 {
+			return imported
+		}
+		group.AppendChild(imported)
+
+	case isSVG:
+		for name, value := range imported.Attributes {
+			if !svgGeometry[name] {
+				group.Attributes[name] = value
+			}
+		}
+		for len(imported.Children) > 0 {
+			group.AppendChild(imported.Children[0])
+		}
+
+	default:
+		group.AppendChild(imported)
+	}
+	return group
+}
@@ -0,0 +1,80 @@
+package svg
+
+import (
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	base, _ := parse(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><linearGradient id="gold"><stop offset="0"/></linearGradient></defs><rect id="frame" fill="url(#gold)"/></svg>`, false)
+	badge, _ := parse(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10" viewBox="0 0 10 10" fill="red"><defs><linearGradient id="shine"><stop offset="0"/></linearGradient><clipPath id="frame"><circle r="5"/></clipPath></defs><circle id="c" r="5" fill="url(#shine)" clip-path="url(#frame)"/><use xlink:href="#c"/></svg>`, false)
+
+	SetSortAttributes(true)
+	source, _ := render(badge)
+
+	wrapper := base.Import(badge, ImportOptions{X: 5, Y: 10})
+	expected := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><linearGradient id="gold"><stop offset="0"></stop></linearGradient><clipPath id="frame-1"><circle r="5"></circle></clipPath></defs><rect fill="url(#gold)" id="frame"></rect>` +
+		`<g fill="red" transform="translate(5 10)"><circle clip-path="url(#frame-1)" fill="url(#gold)" id="c" r="5"></circle><use xlink:href="#c"></use></g></svg>`
+	if actual, _ := render(base); actual != expected {
+		t.Errorf("Import: expected %v, actual %v\n", expected, actual)
+	}
+	if wrapper != base.Children[2] {
+		t.Errorf("Import: expected wrapper to be returned\n")
+	}
+
+	// importing again renames colliding ids and keeps definitions shared
+	base.Import(badge, ImportOptions{Nested: true, X: 20, Width: "5", Height: "5"})
+	expected = `<svg fill="red" height="5" viewBox="0 0 10 10" width="5" x="20" xmlns="http://www.w3.org/2000/svg"><circle clip-path="url(#frame-1)" fill="url(#gold)" id="c-1" r="5"></circle><use xlink:href="#c-1" xmlns:xlink="http://www.w3.org/1999/xlink"></use></svg>`
+	nested := base.Children[3]
+	if actual, _ := render(nested); actual != expected {
+		t.Errorf("Import: expected %v, actual %v\n", expected, actual)
+	}
+	if defs := base.Children[0]; len(defs.Children) != 2 {
+		t.Errorf("Import: expected %v definitions, actual %v\n", 2, len(defs.Children))
+	}
+
+	// prefixed ids
+	other, _ := parse(`<svg><g id="a"><use href="#a"/></g></svg>`, false)
+	base.Import(other, ImportOptions{Prefix: "p-", Scale: 2})
+	g := base.Children[4]
+	if g.Attributes["transform"] != "scale(2)" || g.Children[0].Attributes["id"] != "p-a" || g.Children[0].Children[0].Attributes["href"] != "#p-a" {
+		t.Errorf("Import: unexpected prefixed import %v\n", g)
+	}
+
+	if actual, _ := render(badge); actual != source {
+		t.Errorf("Import: source changed, expected %v, actual %v\n", source, actual)
+	}
+}
+
+func TestImportCreatesDefs(t *testing.T) {
+	base, _ := parse(`<svg><rect/></svg>`, false)
+	part, _ := parse(`<svg><defs><filter id="f"/></defs><rect filter="url(#f)"/></svg>`, false)
+
+	doc := NewDocument(base)
+	base.Import(part, ImportOptions{})
+
+	if base.Children[0].Name != "defs" || base.Children[0].Children[0].Attributes["id"] != "f" {
+		t.Errorf("Import: expected defs to be created, actual %v\n", base.Children[0])
+	}
+	if doc.ElementByID("f") == nil {
+		t.Errorf("Import: expected document index updated\n")
+	}
+}
+
+func TestImportDocument(t *testing.T) {
+	base, _ := parse(`<svg><rect/></svg>`, false)
+	part, _ := parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- part -->
+<svg><circle/></svg>
+<!-- end -->`, false)
+
+	base.Import(part, ImportOptions{Nested: true})
+
+	expected := `<svg><rect></rect><svg><circle></circle></svg></svg>`
+	actual, err := render(base)
+	if err != nil {
+		t.Errorf("Import: unexpected error %v\n", err)
+	}
+	if actual != expected {
+		t.Errorf("Import: expected %v, actual %v\n", expected, actual)
+	}
+}
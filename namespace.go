@@ -0,0 +1,271 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// well known namespaces
+const (
+	SVGNamespace   = "http://www.w3.org/2000/svg"
+	XLinkNamespace = "http://www.w3.org/1999/xlink"
+	XMLNamespace   = "http://www.w3.org/XML/1998/namespace"
+
+	InkscapeNamespace = "http://www.inkscape.org/namespaces/inkscape"
+	SodipodiNamespace = "http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd"
+)
+
+// splitName splits a qualified name into prefix and local part.
+func splitName(name string) (prefix, local string) {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// lookupNamespace resolves a prefix against namespace bindings in scope.
+func lookupNamespace(namespaces map[string]string, prefix string) (string, bool) {
+	if prefix == "xml" {
+		return XMLNamespace, true
+	}
+	uri, ok := namespaces[prefix]
+	return uri, ok
+}
+
+// canonizedName returns the element name for a raw decoder name and the
+// namespace it belongs to. Elements of the svg namespace are named by
+// their local name, other elements keep the prefix used in the source.
+func canonizedName(name xml.Name, namespaces map[string]string) (string, string) {
+	space, _ := lookupNamespace(namespaces, name.Space)
+	if name.Space == "" || space == SVGNamespace {
+		return name.Local, space
+	}
+
+	return name.Space + ":" + name.Local, space
+}
+
+// rawName returns the name as read by xml.Decoder.RawToken for a name
+// resolved by xml.Decoder.Token, whose space is a namespace URI instead
+// of a prefix. Prefixes can not contain colons unlike URIs, names with
+// a prefix are returned as is. The default namespace does not apply to
+// attributes.
+func rawName(name xml.Name, namespaces map[string]string, attribute bool) xml.Name {
+	if !strings.Contains(name.Space, ":") {
+		return name
+	}
+	if name.Space == XMLNamespace {
+		return xml.Name{Space: "xml", Local: name.Local}
+	}
+	if !attribute && namespaces[""] == name.Space {
+		return xml.Name{Local: name.Local}
+	}
+
+	found := ""
+	ok := false
+	for prefix, uri := range namespaces {
+		// pick the smallest prefix to be deterministic
+		if prefix != "" && uri == name.Space && (!ok || prefix < found) {
+			found, ok = prefix, true
+		}
+	}
+	return xml.Name{Space: found, Local: name.Local}
+}
+
+// declareNamespaces returns the namespace bindings in scope of an
+// element with the given attributes. The parent bindings are returned
+// as is when the attributes do not declare namespaces.
+func declareNamespaces(parent map[string]string, attributes []xml.Attr) map[string]string {
+	namespaces := parent
+	copied := false
+	for _, attr := range attributes {
+		var prefix string
+		switch {
+		case attr.Name.Space == "xmlns":
+			prefix = attr.Name.Local
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			prefix = ""
+		default:
+			continue
+		}
+
+		if !copied {
+			namespaces = make(map[string]string, len(parent)+1)
+			for k, v := range parent {
+				namespaces[k] = v
+			}
+			copied = true
+		}
+		namespaces[prefix] = attr.Value
+	}
+
+	if namespaces == nil {
+		namespaces = make(map[string]string)
+	}
+	return namespaces
+}
+
+// Prefix returns the namespace prefix of the element name.
+func (e *Element) Prefix() string {
+	prefix, _ := splitName(e.Name)
+	return prefix
+}
+
+// LocalName returns the element name without its namespace prefix.
+func (e *Element) LocalName() string {
+	_, local := splitName(e.Name)
+	return local
+}
+
+// LookupNamespace returns the namespace URI bound to prefix in scope of
+// the element. The empty prefix stands for the default namespace.
+func (e *Element) LookupNamespace(prefix string) (string, bool) {
+	return lookupNamespace(e.Namespaces, prefix)
+}
+
+// LookupPrefix returns a prefix bound to the namespace URI in scope of
+// the element. The default namespace is preferred.
+func (e *Element) LookupPrefix(uri string) (string, bool) {
+	if uri == XMLNamespace {
+		return "xml", true
+	}
+
+	if space, ok := e.Namespaces[""]; ok && space == uri {
+		return "", true
+	}
+
+	found := ""
+	ok := false
+	for prefix, space := range e.Namespaces {
+		// pick the smallest prefix to be deterministic
+		if space == uri && (!ok || prefix < found) {
+			found, ok = prefix, true
+		}
+	}
+	return found, ok
+}
+
+// namespaceScope tracks namespace bindings declared in the output
+// while encoding.
+type namespaceScope map[string]string
+
+// startElement returns the start tag of the element with the xmlns
+// attributes it needs, together with the scope of its children.
+func (scope namespaceScope) startElement(e *Element) (xml.StartElement, namespaceScope) {
+	start := e.Serialize()
+	declarations, prefix, inner := scope.declarations(e)
+	start.Attr = append(start.Attr, declarations...)
+	if prefix != "" {
+		start.Name.Local = prefix + ":" + start.Name.Local
+	}
+	return start, inner
+}
+
+// declarations returns the xmlns attributes the element needs on top
+// of the bindings in scope, together with the scope of its children.
+// The prefix is set when the name of the element, which has none, must
+// be written with one as the default namespace is bound to another
+// namespace.
+func (scope namespaceScope) declarations(e *Element) ([]xml.Attr, string, namespaceScope) {
+	inner := scope
+	copied := false
+	bind := func(prefix, uri string) {
+		if !copied {
+			inner = make(namespaceScope, len(scope)+1)
+			for k, v := range scope {
+				inner[k] = v
+			}
+			copied = true
+		}
+		inner[prefix] = uri
+	}
+
+	// bindings written explicitly
+	explicitDefault := false
+	for key, value := range e.Attributes {
+		if key == "xmlns" {
+			bind("", value)
+			explicitDefault = true
+		} else if prefix, local := splitName(key); prefix == "xmlns" {
+			bind(local, value)
+		}
+	}
+
+	var attributes []xml.Attr
+	need := func(prefix, uri string) {
+		if uri == "" || prefix == "xml" {
+			return
+		}
+		if current, ok := inner[prefix]; ok && current == uri {
+			return
+		}
+
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		attributes = append(attributes, xml.Attr{Name: xml.Name{Local: name}, Value: uri})
+		bind(prefix, uri)
+	}
+
+	prefix := e.Prefix()
+	namePrefix := ""
+	if current, bound := inner[""]; prefix == "" && e.Space != "" && bound && current != e.Space {
+		// the default namespace is bound to another one, the element is
+		// written with a prefix such as the one of the source, svg:rect
+		found, ok := "", false
+		for p, uri := range e.Namespaces {
+			if p != "" && uri == e.Space && (!ok || p < found) {
+				found, ok = p, true
+			}
+		}
+		if !ok && explicitDefault {
+			found, ok = freePrefix(inner, e.Namespaces), true
+		}
+		if ok {
+			namePrefix = found
+			need(found, e.Space)
+		} else {
+			need("", e.Space)
+		}
+	} else if prefix == "" {
+		need("", e.Space)
+	} else if uri, ok := e.LookupNamespace(prefix); ok {
+		need(prefix, uri)
+	} else {
+		need(prefix, e.Space)
+	}
+
+	for key := range e.Attributes {
+		prefix, _ := splitName(key)
+		if prefix == "" || prefix == "xmlns" {
+			continue
+		}
+		if uri, ok := e.LookupNamespace(prefix); ok {
+			need(prefix, uri)
+		}
+	}
+
+	sortAttrs(attributes)
+	return attributes, namePrefix, inner
+}
+
+// freePrefix returns a prefix for the svg namespace which is not bound
+// in scope.
+func freePrefix(scopes ...map[string]string) string {
+	for n := 1; ; n++ {
+		prefix := "svg"
+		if n > 1 {
+			prefix = fmt.Sprintf("svg%d", n)
+		}
+		free := true
+		for _, scope := range scopes {
+			if _, ok := scope[prefix]; ok {
+				free = false
+			}
+		}
+		if free {
+			return prefix
+		}
+	}
+}
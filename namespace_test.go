@@ -0,0 +1,191 @@
+package svg
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceScope(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg">
+		<g xmlns:a="urn:a"><a:x/></g>
+		<a:y xmlns:a="urn:b"/>
+		<svg:rect xmlns:svg="http://www.w3.org/2000/svg" width="1"/>
+	</svg>`
+
+	root, err := parse(svg, false)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var testCases = []struct {
+		element *Element
+		name    string
+		space   string
+	}{
+		{root, "svg", SVGNamespace},
+		{root.Children[0], "g", SVGNamespace},
+		{root.Children[0].Children[0], "a:x", "urn:a"},
+		{root.Children[1], "a:y", "urn:b"},
+		{root.Children[2], "rect", SVGNamespace},
+	}
+
+	for _, test := range testCases {
+		if test.element.Name != test.name || test.element.Space != test.space {
+			t.Errorf("Namespace: expected %v %v, actual %v %v\n", test.name, test.space, test.element.Name, test.element.Space)
+		}
+	}
+
+	if _, ok := root.LookupNamespace("a"); ok {
+		t.Errorf("Namespace: prefix a should not be in scope of the root\n")
+	}
+
+	if prefix, _ := root.Children[1].LookupPrefix("urn:b"); prefix != "a" {
+		t.Errorf("Namespace: expected %v, actual %v\n", "a", prefix)
+	}
+}
+
+func TestNamespaceRender(t *testing.T) {
+	var testCases = []struct {
+		svg      string
+		path     []int
+		expected string
+	}{
+		{
+			`<svg xmlns="http://www.w3.org/2000/svg"><g><rect/></g></svg>`,
+			[]int{0},
+			`<g xmlns="http://www.w3.org/2000/svg"><rect></rect></g>`,
+		},
+		{
+			`<svg xmlns:inkscape="urn:i"><g inkscape:label="L"/></svg>`,
+			[]int{0},
+			`<g inkscape:label="L" xmlns:inkscape="urn:i"></g>`,
+		},
+		{
+			`<svg:svg xmlns:svg="http://www.w3.org/2000/svg"><svg:rect/></svg:svg>`,
+			nil,
+			`<svg xmlns:svg="http://www.w3.org/2000/svg" xmlns="http://www.w3.org/2000/svg"><rect></rect></svg>`,
+		},
+		{
+			`<svg xmlns="http://www.w3.org/2000/svg" xml:space="preserve"><a:b xmlns:a="urn:a"><a:c/></a:b></svg>`,
+			nil,
+			`<svg xml:space="preserve" xmlns="http://www.w3.org/2000/svg"><a:b xmlns:a="urn:a"><a:c></a:c></a:b></svg>`,
+		},
+	}
+
+	SetSortAttributes(true)
+	for _, test := range testCases {
+		element, err := parse(test.svg, false)
+		if err != nil {
+			t.Errorf("Namespace: unexpected error %v\n", err)
+			continue
+		}
+
+		for _, i := range test.path {
+			element = element.Children[i]
+		}
+
+		actual, _ := render(element)
+		if actual != test.expected {
+			t.Errorf("Namespace: expected %v, actual %v\n", test.expected, actual)
+		}
+	}
+}
+
+func TestNamespaceDefaultTaken(t *testing.T) {
+	SetSortAttributes(true)
+	defer SetSortAttributes(false)
+
+	root, _ := parse(`<svg:svg xmlns:svg="http://www.w3.org/2000/svg" xmlns="urn:x"><svg:rect/><foo/></svg:svg>`, false)
+	built := &Element{Name: "rect", Space: SVGNamespace, Attributes: map[string]string{"xmlns": "urn:x"}}
+
+	var testCases = []struct {
+		element  *Element
+		expected string
+	}{
+		{root, `<svg:svg xmlns="urn:x" xmlns:svg="http://www.w3.org/2000/svg"><svg:rect></svg:rect><foo></foo></svg:svg>`},
+		{built, `<svg:rect xmlns="urn:x" xmlns:svg="http://www.w3.org/2000/svg"></svg:rect>`},
+	}
+
+	for _, test := range testCases {
+		actual, err := render(test.element)
+		if err != nil || actual != test.expected {
+			t.Errorf("Namespace: expected %v, actual %v %v\n", test.expected, actual, err)
+			continue
+		}
+
+		// the output is well-formed and keeps the namespaces
+		element, err := parse(actual, false)
+		if err != nil {
+			t.Errorf("Namespace: unexpected error %v\n", err)
+			continue
+		}
+		if element.Name != test.element.Name || element.Space != SVGNamespace {
+			t.Errorf("Namespace: expected %v %v, actual %v %v\n", test.element.Name, SVGNamespace, element.Name, element.Space)
+		}
+	}
+	if foo := root.Children[1]; foo.Space != "urn:x" {
+		t.Errorf("Namespace: expected %v, actual %v\n", "urn:x", foo.Space)
+	}
+}
+
+func TestNamespaceMismatch(t *testing.T) {
+	if _, err := parse(`<svg xmlns:a="urn:a" xmlns:b="urn:a"><a:x></b:x></svg>`, false); err == nil {
+		t.Errorf("Namespace: expected error for mismatched end tag\n")
+	}
+
+	if _, err := parse(`<svg><g></svg>`, false); err == nil {
+		t.Errorf("Namespace: expected error for mismatched end tag\n")
+	}
+
+	if _, err := parse(`<svg><g>`, false); err == nil {
+		t.Errorf("Namespace: expected error for unexpected EOF\n")
+	}
+}
+
+func TestNamespaceResolvedToken(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape">
+		<g inkscape:label="L" xml:space="preserve"><inkscape:page/><rect/></g>
+	</svg>`
+
+	// the start of the root read with Token, its content with RawToken
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	var root *Element
+	for root == nil {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatalf("Namespace: unexpected error %v\n", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = NewElement(nil, start)
+		}
+	}
+	if err := root.Decode(root, decoder); err != nil {
+		t.Fatalf("Namespace: unexpected error %v\n", err)
+	}
+
+	if root.Name != "svg" || root.Space != SVGNamespace {
+		t.Errorf("Namespace: expected svg %v, actual %v %v\n", SVGNamespace, root.Name, root.Space)
+	}
+	g := root.Children[0]
+	if g.Attributes["inkscape:label"] != "L" || g.Attributes["xml:space"] != "preserve" {
+		t.Errorf("Namespace: expected prefixed attributes, actual %v\n", g.Attributes)
+	}
+	if g.Children[0].Name != "inkscape:page" || g.Children[0].Space != InkscapeNamespace {
+		t.Errorf("Namespace: expected inkscape:page %v, actual %v %v\n", InkscapeNamespace, g.Children[0].Name, g.Children[0].Space)
+	}
+
+	// an element created apart from its ancestors
+	decoder = xml.NewDecoder(strings.NewReader(`<svg:g xmlns:svg="http://www.w3.org/2000/svg"><svg:rect/></svg:g>`))
+	token, _ := decoder.RawToken()
+	bindings := &Element{Namespaces: map[string]string{"i": InkscapeNamespace}}
+	g = NewElement(nil, token.(xml.StartElement))
+	decoder = xml.NewDecoder(strings.NewReader(`<i:page/></svg:g>`))
+	if err := g.Decode(bindings, decoder); err != nil {
+		t.Fatalf("Namespace: unexpected error %v\n", err)
+	}
+	if g.Name != "g" || g.Children[0].Space != InkscapeNamespace {
+		t.Errorf("Namespace: expected the bindings of root, actual %v %v\n", g.Name, g.Children[0].Space)
+	}
+}
@@ -0,0 +1,105 @@
+package svg
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// NodeType identifies the kind of a Node.
+type NodeType int
+
+// node types
+const (
+	// ElementNode is a placeholder for the next entry of Element.Children.
+	ElementNode NodeType = iota
+	TextNode
+	CDataNode
+	CommentNode
+	ProcInstNode
+	DirectiveNode
+)
+
+// Node is a piece of document content which is not an attribute, such as
+// text, a comment, a processing instruction, a doctype or a CDATA section.
+type Node struct {
+	Type NodeType
+
+	// Target is the target of a processing instruction, e.g. "xml".
+	Target string
+
+	// Data is the content of the node without its markup.
+	Data string
+}
+
+// newNode converts a decoder token into a node. It returns nil for
+// tokens which are not represented as nodes.
+func newNode(token xml.Token) *Node {
+	switch t := token.(type) {
+	case xml.Comment:
+		return &Node{Type: CommentNode, Data: string(t)}
+	case xml.ProcInst:
+		return &Node{Type: ProcInstNode, Target: t.Target, Data: string(t.Inst)}
+	case xml.Directive:
+		return &Node{Type: DirectiveNode, Data: string(t)}
+	}
+	return nil
+}
+
+// Encode writes the node to the encoder. Element nodes are written by
+// their parent and are ignored. The encoder gives no access to its
+// writer, so CDATA sections containing ">" are written as escaped
+// character data; Render and Writer keep them.
+func (n *Node) Encode(encoder *xml.Encoder) error {
+	return n.encode(&serializer{Encoder: encoder})
+}
+
+func (n *Node) encode(s *serializer) error {
+	switch n.Type {
+	case TextNode:
+		return s.EncodeToken(xml.CharData(n.Data))
+	case CDataNode:
+		return s.encodeCData(n.Data)
+	case CommentNode:
+		return s.EncodeToken(xml.Comment(n.Data))
+	case ProcInstNode:
+		return s.EncodeToken(xml.ProcInst{Target: n.Target, Inst: []byte(n.Data)})
+	case DirectiveNode:
+		return s.EncodeToken(xml.Directive(n.Data))
+	}
+	return nil
+}
+
+// isText reports whether the node holds character data.
+func (n *Node) isText() bool {
+	return n.Type == TextNode || n.Type == CDataNode
+}
+
+// isBlank reports whether the node is character data made of white
+// space only.
+func (n *Node) isBlank() bool {
+	return n.isText() && strings.TrimSpace(n.Data) == ""
+}
+
+// encodeCData writes data as a CDATA section. xml.Encoder has no CDATA
+// token, so the section is written to the underlying writer, with "]]>"
+// split across two sections. Without the writer, the section is written
+// as a directive, or as escaped character data when the encoder rejects
+// the directive.
+func (s *serializer) encodeCData(data string) error {
+	if s.w == nil {
+		if !strings.Contains(data, "]]>") {
+			if err := s.EncodeToken(xml.Directive("[CDATA[" + data + "]]")); err == nil {
+				return nil
+			}
+		}
+		return s.EncodeToken(xml.CharData(data))
+	}
+
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	data = strings.ReplaceAll(data, "]]>", "]]]]><![CDATA[>")
+	_, err := io.WriteString(s.w, "<![CDATA["+data+"]]>")
+	return err
+}
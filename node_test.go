@@ -0,0 +1,167 @@
+package svg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNodeRoundTrip(t *testing.T) {
+	var testCases = []struct {
+		svg      string
+		expected string
+	}{
+		{
+			`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- Created with Inkscape -->
+<svg><!-- layer --><g></g><?app data?></svg>
+<!-- end -->`,
+			`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- Created with Inkscape -->
+<svg><!-- layer --><g></g><?app data?></svg>
+<!-- end -->`,
+		},
+		{
+			`<svg><style><![CDATA[rect > .a { fill: red }]]></style></svg>`,
+			`<svg><style><![CDATA[rect > .a { fill: red }]]></style></svg>`,
+		},
+
+		{
+			`<svg><style><![CDATA[.a { font-family: 'Arial' }]]></style></svg>`,
+			`<svg><style><![CDATA[.a { font-family: 'Arial' }]]></style></svg>`,
+		},
+	}
+
+	for _, test := range testCases {
+		element, err := parse(test.svg, false)
+		if err != nil {
+			t.Errorf("Node: unexpected error %v\n", err)
+			continue
+		}
+
+		actual, err := render(element)
+		if actual != test.expected || err != nil {
+			t.Errorf("Node: expected %v, actual %v\n", test.expected, actual)
+		}
+	}
+}
+
+func TestNodeEditCData(t *testing.T) {
+	element, _ := parse(`<svg><style><![CDATA[.a { fill: red }]]></style></svg>`, false)
+
+	style := element.FindAll("style")[0]
+	if style.Content != ".a { fill: red }" {
+		t.Errorf("Node: expected %v, actual %v\n", ".a { fill: red }", style.Content)
+	}
+
+	style.Content = ".a { fill: blue }"
+	expected := `<svg><style><![CDATA[.a { fill: blue }]]></style></svg>`
+	if actual, _ := render(element); actual != expected {
+		t.Errorf("Node: expected %v, actual %v\n", expected, actual)
+	}
+
+	// the end of a section is split across two
+	style.Content = "g > rect { content: ']]>' }"
+	expected = `<svg><style><![CDATA[g > rect { content: ']]]]><![CDATA[>' }]]></style></svg>`
+	actual, _ := render(element)
+	if actual != expected {
+		t.Errorf("Node: expected %v, actual %v\n", expected, actual)
+	}
+	element, _ = parse(actual, false)
+	if content := element.FindAll("style")[0].Content; content != style.Content {
+		t.Errorf("Node: expected %v, actual %v\n", style.Content, content)
+	}
+}
+
+func TestMixedContent(t *testing.T) {
+	var testCases = []struct {
+		svg      string
+		content  string
+		expected string
+	}{
+		{
+			`<svg><text>Hello <tspan>big</tspan> world</text></svg>`,
+			"Hello  world",
+			`<svg><text>Hello <tspan>big</tspan> world</text></svg>`,
+		},
+		{
+			`<svg><text><tspan>a</tspan> <tspan>b</tspan></text></svg>`,
+			"",
+			`<svg><text><tspan>a</tspan> <tspan>b</tspan></text></svg>`,
+		},
+		{
+			"<svg>\n  <text>\n    <textPath>on <tspan>a</tspan> path</textPath>\n  </text>\n</svg>",
+			"",
+			"<svg><text>\n    <textPath>on <tspan>a</tspan> path</textPath>\n  </text></svg>",
+		},
+	}
+
+	for _, test := range testCases {
+		element, err := parse(test.svg, false)
+		if err != nil {
+			t.Errorf("Mixed: unexpected error %v\n", err)
+			continue
+		}
+
+		text := element.FindAll("text")[0]
+		if text.Content != test.content {
+			t.Errorf("Mixed: expected %q, actual %q\n", test.content, text.Content)
+		}
+
+		actual, _ := render(element)
+		if actual != test.expected {
+			t.Errorf("Mixed: expected %v, actual %v\n", test.expected, actual)
+		}
+	}
+}
+
+func TestMixedContentEdit(t *testing.T) {
+	element, _ := parse(`<svg><text>Hello <tspan>big</tspan> world</text></svg>`, false)
+
+	text := element.FindAll("text")[0]
+	text.Content = "Goodbye "
+	text.Children[0].Content = "cruel"
+
+	expected := `<svg><text>Goodbye <tspan>cruel</tspan></text></svg>`
+	if actual, _ := render(element); actual != expected {
+		t.Errorf("Mixed: expected %v, actual %v\n", expected, actual)
+	}
+}
+
+func TestMixedContentIndent(t *testing.T) {
+	var testCases = []struct {
+		svg      string
+		expected string
+	}{
+		{
+			`<svg><g><text>Hello<tspan>big</tspan>world</text><rect/></g></svg>`,
+			"<svg>\n  <g>\n    <text>Hello<tspan>big</tspan>world</text>\n    <rect></rect>\n  </g>\n</svg>",
+		},
+		{
+			`<svg><text><tspan>a</tspan> <tspan>b</tspan></text></svg>`,
+			"<svg>\n  <text><tspan>a</tspan> <tspan>b</tspan></text>\n</svg>",
+		},
+		{
+			`<svg><g xml:space="preserve"><g><rect/></g> </g><desc>a <b/>c</desc></svg>`,
+			"<svg>\n  <g xml:space=\"preserve\"><g><rect></rect></g> </g>\n  <desc>a <b></b>c</desc>\n</svg>",
+		},
+	}
+
+	for _, test := range testCases {
+		element, err := parse(test.svg, false)
+		if err != nil {
+			t.Errorf("Mixed: unexpected error %v\n", err)
+			continue
+		}
+
+		w := &bytes.Buffer{}
+		if err := Render(element, w); err != nil {
+			t.Errorf("Mixed: unexpected error %v\n", err)
+			continue
+		}
+		if actual := w.String(); actual != test.expected {
+			t.Errorf("Mixed: expected %q, actual %q\n", test.expected, actual)
+		}
+	}
+}
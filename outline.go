@@ -0,0 +1,345 @@
+package svg
+
+import (
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// OutlineText replaces the <text> elements within e, e included, by
+// paths drawing the outlines of their glyphs with the fonts of the
+// registry, so that they render the same where the fonts are missing.
+//
+// A text becomes a <g> with its attributes, such as transform, fill and
+// style, but without the x, y, dx, dy and rotate ones, and an aria-label
+// holding its text. Its own characters become a <path>, tspans become a
+// <path> with their attributes, or a <g> when they hold other tspans.
+// The x, y, dx and dy lists of the text and its tspans, text-anchor,
+// font-size and letter-spacing position the glyphs. textPath is laid out
+// as a tspan.
+func (r *FontRegistry) OutlineText(e *Element) error {
+	if r.empty() {
+		return ErrNoFont
+	}
+
+	var texts []*Element
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if el.LocalName() == "text" {
+			texts = append(texts, el)
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+	if e.LocalName() == "text" && e.Parent() == nil {
+		return ErrNotChild
+	}
+
+	for _, text := range texts {
+		o := &textOutline{registry: r}
+		if err := text.ReplaceWith(o.outline(text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textOutline lays out the glyphs of a text.
+type textOutline struct {
+	registry *FontRegistry
+	buf      sfnt.Buffer
+
+	// positions of the text and its tspans being laid out, the innermost
+	// last
+	positions []textPositions
+	// index of the next addressable character
+	index int
+	// pen position
+	x, y float64
+	// trailing white space, to collapse
+	space bool
+	label strings.Builder
+
+	chunk    *textChunk
+	previous *outlineGlyph
+	runs     []*glyphRun
+}
+
+// textPositions are the coordinates of the characters of an element.
+type textPositions struct {
+	// start is the index of the first character of the element.
+	start int
+	lists map[string][]float64
+}
+
+// textChunk is a run of glyphs anchored together, up to the next
+// absolute position.
+type textChunk struct {
+	anchor     string
+	start, end float64
+	glyphs     []*outlineGlyph
+}
+
+type outlineGlyph struct {
+	font  *Font
+	index sfnt.GlyphIndex
+	// char is the character the glyph shows.
+	char rune
+	// x and y are the origin of the glyph on its baseline.
+	x, y, size float64
+}
+
+// glyphRun holds the glyphs drawn by a path.
+type glyphRun struct {
+	path   *Element
+	glyphs []*outlineGlyph
+}
+
+// outline returns the group replacing a text.
+func (o *textOutline) outline(text *Element) *Element {
+	group := outlineElement(text, "g")
+	o.space = true
+	o.push(text)
+	o.content(text, group, nil)
+	o.endChunk()
+
+	for _, run := range o.runs {
+		d := &PathData{}
+		for _, g := range run.glyphs {
+			o.glyphPath(d, g)
+		}
+		if len(d.commands) == 0 {
+			run.path.Remove()
+			continue
+		}
+		run.path.Attributes["d"] = d.String()
+	}
+
+	if label := strings.TrimSpace(o.label.String()); label != "" {
+		group.Attributes["aria-label"] = label
+	}
+	return group
+}
+
+// newOutlineElement returns an element of the namespace of e.
+func newOutlineElement(e *Element, name string) *Element {
+	return &Element{
+		Name:       strings.TrimSuffix(e.Name, e.LocalName()) + name,
+		Space:      e.Space,
+		Namespaces: e.Namespaces,
+		Attributes: make(map[string]string),
+		Children:   []*Element{},
+	}
+}
+
+// outlineElement returns an element of another name with the attributes
+// of e which do not position characters.
+func outlineElement(e *Element, name string) *Element {
+	el := newOutlineElement(e, name)
+	for attr, value := range e.Attributes {
+		switch attr {
+		case "x", "y", "dx", "dy", "rotate", "textLength", "lengthAdjust":
+		default:
+			el.Attributes[attr] = value
+		}
+	}
+	return el
+}
+
+// content outlines the children of e into target. The characters go to
+// run when there is one, otherwise to a path per text node.
+func (o *textOutline) content(e, target *Element, run *glyphRun) {
+	for _, node := range childNodes(e) {
+		switch node.Type {
+		case XPathTextNode:
+			r := run
+			if r == nil {
+				r = o.newRun(newOutlineElement(e, "path"))
+				target.AppendChild(r.path)
+			}
+			o.layout(e, node.Node.Data, r)
+
+		case XPathElementNode:
+			child := node.Element
+			switch child.LocalName() {
+			case "tspan", "textPath", "altGlyph", "a":
+			default:
+				// titles, descriptions and the like are kept
+				target.AppendChild(child.Clone())
+				continue
+			}
+
+			o.push(child)
+			target.AppendChild(o.span(child))
+			o.positions = o.positions[:len(o.positions)-1]
+		}
+	}
+}
+
+// span outlines a tspan, or a link, within a text.
+func (o *textOutline) span(e *Element) *Element {
+	container := false
+	for _, child := range e.Children {
+		switch child.LocalName() {
+		case "tspan", "textPath", "altGlyph", "a":
+			container = true
+		}
+	}
+
+	if !container && e.LocalName() != "a" {
+		run := o.newRun(outlineElement(e, "path"))
+		o.content(e, run.path, run)
+		return run.path
+	}
+
+	name := "g"
+	if e.LocalName() == "a" {
+		name = "a"
+	}
+	group := outlineElement(e, name)
+	o.content(e, group, nil)
+	return group
+}
+
+func (o *textOutline) newRun(path *Element) *glyphRun {
+	run := &glyphRun{path: path}
+	o.runs = append(o.runs, run)
+	return run
+}
+
+// push adds the coordinates of an element whose characters come next.
+func (o *textOutline) push(e *Element) {
+	positions := textPositions{start: o.index, lists: make(map[string][]float64)}
+	for _, name := range []string{"x", "y", "dx", "dy"} {
+		for _, field := range strings.Fields(strings.ReplaceAll(e.Attributes[name], ",", " ")) {
+			n, _ := imageLength(field)
+			positions.lists[name] = append(positions.lists[name], n)
+		}
+	}
+	o.positions = append(o.positions, positions)
+}
+
+// position returns a coordinate of the next character, from the
+// innermost element giving one.
+func (o *textOutline) position(name string) (float64, bool) {
+	for i := len(o.positions) - 1; i >= 0; i-- {
+		list := o.positions[i].lists[name]
+		if n := o.index - o.positions[i].start; n < len(list) {
+			return list[n], true
+		}
+	}
+	return 0, false
+}
+
+// layout places the glyphs of a piece of text of an element.
+func (o *textOutline) layout(e *Element, text string, run *glyphRun) {
+	text = collapseSpace(text, preservesSpace(e), &o.space)
+	if text == "" {
+		return
+	}
+	o.label.WriteString(text)
+
+	f, face := o.registry.elementFont(e)
+	spacing := letterSpacing(computedStyle(e, "letter-spacing"), face.Size)
+	for _, r := range text {
+		x, hasX := o.position("x")
+		y, hasY := o.position("y")
+		dx, _ := o.position("dx")
+		dy, _ := o.position("dy")
+		o.index++
+
+		index, err := f.font.GlyphIndex(&o.buf, r)
+		if err != nil {
+			index = 0
+		}
+
+		if hasX {
+			o.x = x
+		} else if p := o.previous; p != nil && p.font == f && p.size == face.Size {
+			if kern, err := f.font.Kern(&o.buf, p.index, index, f.ppem, font.HintingNone); err == nil {
+				o.x += f.scale(kern, face.Size)
+			}
+		}
+		if hasY {
+			o.y = y
+		}
+		if hasX || hasY || o.chunk == nil {
+			o.endChunk()
+			anchor := computedStyle(e, "text-anchor")
+			o.chunk = &textChunk{anchor: anchor, start: o.x, end: o.x}
+		}
+		o.x += dx
+		o.y += dy
+
+		g := &outlineGlyph{font: f, index: index, char: r, x: o.x, y: o.y, size: face.Size}
+		run.glyphs = append(run.glyphs, g)
+		o.chunk.glyphs = append(o.chunk.glyphs, g)
+		o.previous = g
+
+		if advance, err := f.font.GlyphAdvance(&o.buf, index, f.ppem, font.HintingNone); err == nil {
+			o.x += f.scale(advance, face.Size)
+		}
+		o.x += spacing
+		if r != ' ' {
+			// trailing white space is not anchored
+			o.chunk.end = o.x
+		}
+	}
+}
+
+// endChunk moves the glyphs of the current chunk by its text-anchor.
+func (o *textOutline) endChunk() {
+	if o.chunk == nil {
+		return
+	}
+
+	width := o.chunk.end - o.chunk.start
+	shift := 0.0
+	switch o.chunk.anchor {
+	case "middle":
+		shift = width / 2
+	case "end":
+		shift = width
+	}
+	for _, g := range o.chunk.glyphs {
+		g.x -= shift
+	}
+	o.chunk = nil
+}
+
+// glyphPath adds the outline of a glyph to a path.
+func (o *textOutline) glyphPath(d *PathData, g *outlineGlyph) {
+	segments, err := g.font.font.LoadGlyph(&o.buf, g.index, g.font.ppem, nil)
+	if err != nil {
+		return
+	}
+
+	// glyph coordinates grow downwards, as user units do
+	point := func(p fixed.Point26_6) (float64, float64) {
+		return g.x + g.font.scale(p.X, g.size), g.y + g.font.scale(p.Y, g.size)
+	}
+	for i, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i > 0 {
+				d.Close()
+			}
+			d.MoveTo(point(segment.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			d.LineTo(point(segment.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := point(segment.Args[0])
+			x, y := point(segment.Args[1])
+			d.QuadTo(x1, y1, x, y)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := point(segment.Args[0])
+			x2, y2 := point(segment.Args[1])
+			x, y := point(segment.Args[2])
+			d.CubicTo(x1, y1, x2, y2, x, y)
+		}
+	}
+	if len(segments) > 0 {
+		d.Close()
+	}
+}
@@ -0,0 +1,166 @@
+package svg
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/galihrivanto/svg/utils"
+)
+
+// outlineBounds returns the box of the points of a path.
+func outlineBounds(t *testing.T, e *Element) [4]float64 {
+	path, err := utils.PathParser(e.Attributes["d"])
+	if err != nil {
+		t.Fatalf("PathParser: unexpected error %v\n", err)
+	}
+
+	bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, subpath := range path.Subpaths {
+		for _, command := range subpath.Commands {
+			for i := 0; i+1 < len(command.Params); i += 2 {
+				x, y := command.Params[i], command.Params[i+1]
+				bounds = [4]float64{minFloat(bounds[0], x), minFloat(bounds[1], y), maxFloat(bounds[2], x), maxFloat(bounds[3], y)}
+			}
+		}
+	}
+	return bounds
+}
+
+func TestOutlineText(t *testing.T) {
+	registry := testRegistry(t)
+	root, _ := parse(`
+		<svg font-family="Go" font-size="10">
+			<text id="text" x="5" y="20" transform="rotate(10)" fill="red">Hi <tspan id="bold" font-weight="bold">there</tspan><title>greeting</title></text>
+			<g id="group"><text id="nested"><tspan id="outer" x="3"><tspan id="inner">in</tspan> out</tspan></text></g>
+		</svg>
+	`, false)
+
+	if err := registry.OutlineText(root); err != nil {
+		t.Fatalf("OutlineText: unexpected error %v\n", err)
+	}
+	if texts := root.FindAll("text"); len(texts) != 0 {
+		t.Errorf("OutlineText: expected no text, actual %d\n", len(texts))
+	}
+
+	text := root.FindID("text")
+	expected := map[string]string{"id": "text", "transform": "rotate(10)", "fill": "red", "aria-label": "Hi there"}
+	if text.Name != "g" || !equalAttributes(text.Attributes, expected) {
+		t.Errorf("OutlineText: expected <g %v>, actual <%s %v>\n", expected, text.Name, text.Attributes)
+	}
+
+	var names []string
+	for _, child := range text.Children {
+		names = append(names, child.Name)
+	}
+	if actual := strings.Join(names, " "); actual != "path path title" {
+		t.Errorf("OutlineText: expected %v, actual %v\n", "path path title", actual)
+	}
+	if bold := root.FindID("bold"); bold == nil || bold.Name != "path" || bold.Attributes["font-weight"] != "bold" {
+		t.Errorf("OutlineText: expected the tspan as a path, actual %v\n", bold)
+	}
+
+	for _, path := range root.FindAll("path") {
+		if len(path.Attributes["d"]) == 0 {
+			t.Errorf("OutlineText: expected path data, actual %v\n", path.Attributes)
+			continue
+		}
+		outlineBounds(t, path)
+	}
+
+	// tspans holding tspans become groups
+	if outer := root.FindID("outer"); outer == nil || outer.Name != "g" || len(outer.Children) != 2 {
+		t.Errorf("OutlineText: expected the outer tspan as a group, actual %v\n", outer)
+	} else if _, ok := outer.Attributes["x"]; ok {
+		t.Errorf("OutlineText: expected no x, actual %v\n", outer.Attributes)
+	}
+	if inner := root.FindID("inner"); inner == nil || outlineBounds(t, inner)[0] < 3 {
+		t.Errorf("OutlineText: expected the inner tspan at 3, actual %v\n", inner)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(root, &buf); err != nil {
+		t.Fatalf("Render: unexpected error %v\n", err)
+	}
+	if strings.Contains(buf.String(), "<text") {
+		t.Errorf("OutlineText: expected no text, actual %s\n", buf.String())
+	}
+}
+
+func TestOutlineTextPosition(t *testing.T) {
+	registry := testRegistry(t)
+	face := FontFace{Family: "Go", Size: 10, Weight: 400}
+	width := registry.MeasureText("H", face)
+
+	outline := func(text string) [4]float64 {
+		root, _ := parse(`<svg font-family="Go" font-size="10">`+text+`</svg>`, false)
+		if err := registry.OutlineText(root); err != nil {
+			t.Fatalf("OutlineText: unexpected error %v\n", err)
+		}
+		paths := root.FindAll("path")
+		if len(paths) != 1 {
+			t.Fatalf("OutlineText: expected a path, actual %d\n", len(paths))
+		}
+		return outlineBounds(t, paths[0])
+	}
+
+	origin := outline(`<text>H</text>`)
+	if math.Abs(origin[3]) > 1e-6 || origin[1] >= 0 {
+		t.Errorf("OutlineText: expected H on the baseline, actual %v\n", origin)
+	}
+
+	var testCases = []struct {
+		text   string
+		dx, dy float64
+		scale  float64
+	}{
+		{`<text x="10" y="20">H</text>`, 10, 20, 1},
+		{`<text dx="10" dy="20">H</text>`, 10, 20, 1},
+		{`<text x="10px" y="5"><tspan dx="2" dy="1">H</tspan></text>`, 12, 6, 1},
+		{`<text x="30" text-anchor="middle">H</text>`, 30 - width/2, 0, 1},
+		{`<text x="30" style="text-anchor:end">H </text>`, 30 - width, 0, 1},
+		{`<text x="10" font-size="20">H</text>`, 10, 0, 2},
+		{`<text x="0 40">&#160;H</text>`, 40, 0, 1},
+	}
+
+	for _, test := range testCases {
+		actual := outline(test.text)
+		expected := [4]float64{
+			origin[0]*test.scale + test.dx, origin[1]*test.scale + test.dy,
+			origin[2]*test.scale + test.dx, origin[3]*test.scale + test.dy,
+		}
+		for i := range actual {
+			if math.Abs(actual[i]-expected[i]) > 1e-5 {
+				t.Errorf("OutlineText %s: expected %v, actual %v\n", test.text, expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestOutlineTextErrors(t *testing.T) {
+	root, _ := parse(`<svg><text>a</text></svg>`, false)
+	if err := NewFontRegistry().OutlineText(root); !errors.Is(err, ErrNoFont) {
+		t.Errorf("OutlineText: expected %v, actual %v\n", ErrNoFont, err)
+	}
+
+	text := element("text", nil)
+	text.Content = "a"
+	if err := testRegistry(t).OutlineText(text); !errors.Is(err, ErrNotChild) {
+		t.Errorf("OutlineText: expected %v, actual %v\n", ErrNotChild, err)
+	}
+}
+
+func equalAttributes(actual, expected map[string]string) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for name, value := range expected {
+		if actual[name] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,543 @@
+package svg
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// presentation holds the inherited presentation properties an element
+// is drawn with.
+type presentation struct {
+	// fill and stroke are paint values, resolved when drawing.
+	fill, stroke               string
+	fillOpacity, strokeOpacity float64
+	fillRule                   string
+
+	strokeWidth float64
+	lineCap     string
+	lineJoin    string
+	miterLimit  float64
+	// dashArray is nil for solid strokes.
+	dashArray  []float64
+	dashOffset float64
+
+	color   color.NRGBA
+	visible bool
+}
+
+// newPresentation returns the initial values of the properties.
+func newPresentation() presentation {
+	return presentation{
+		fill:          "black",
+		stroke:        "none",
+		fillOpacity:   1,
+		strokeOpacity: 1,
+		fillRule:      "nonzero",
+		strokeWidth:   1,
+		lineCap:       "butt",
+		lineJoin:      "miter",
+		miterLimit:    4,
+		color:         color.NRGBA{A: 0xff},
+		visible:       true,
+	}
+}
+
+// inherit returns the properties of e, given those of its parent.
+// Percentages are of the viewport.
+func (p presentation) inherit(e *Element, viewport [2]float64) presentation {
+	property := func(name string) (string, bool) {
+		value, ok := styleProperty(e, name)
+		return value, ok && value != "inherit"
+	}
+
+	if value, ok := property("color"); ok {
+		if c, ok := parseColor(value, p.color); ok {
+			p.color = c
+		}
+	}
+	if value, ok := property("fill"); ok {
+		p.fill = value
+	}
+	if value, ok := property("stroke"); ok {
+		p.stroke = value
+	}
+	if value, ok := property("fill-opacity"); ok {
+		p.fillOpacity = parseOpacity(value, p.fillOpacity)
+	}
+	if value, ok := property("stroke-opacity"); ok {
+		p.strokeOpacity = parseOpacity(value, p.strokeOpacity)
+	}
+	if value, ok := property("fill-rule"); ok && (value == "nonzero" || value == "evenodd") {
+		p.fillRule = value
+	}
+
+	diagonal := math.Sqrt((viewport[0]*viewport[0] + viewport[1]*viewport[1]) / 2)
+	if value, ok := property("stroke-width"); ok {
+		if n, ok := userLength(value, diagonal); ok && n >= 0 {
+			p.strokeWidth = n
+		}
+	}
+	if value, ok := property("stroke-linecap"); ok && (value == "butt" || value == "round" || value == "square") {
+		p.lineCap = value
+	}
+	if value, ok := property("stroke-linejoin"); ok {
+		switch value {
+		case "miter", "miter-clip", "round", "bevel", "arcs":
+			p.lineJoin = value
+		}
+	}
+	if value, ok := property("stroke-miterlimit"); ok {
+		if n, err := parseNumber(value); err == nil && n >= 1 {
+			p.miterLimit = n
+		}
+	}
+	if value, ok := property("stroke-dasharray"); ok {
+		p.dashArray = parseDashArray(value, diagonal)
+	}
+	if value, ok := property("stroke-dashoffset"); ok {
+		if n, ok := userLength(value, diagonal); ok {
+			p.dashOffset = n
+		}
+	}
+	if value, ok := property("visibility"); ok {
+		p.visible = value == "visible"
+	}
+	return p
+}
+
+// parseOpacity parses an opacity, a number or a percentage clamped to
+// 0..1.
+func parseOpacity(value string, fallback float64) float64 {
+	value = strings.TrimSpace(value)
+	factor := 1.0
+	if strings.HasSuffix(value, "%") {
+		value, factor = strings.TrimSuffix(value, "%"), 0.01
+	}
+	n, err := parseNumber(value)
+	if err != nil {
+		return fallback
+	}
+	return math.Min(math.Max(n*factor, 0), 1)
+}
+
+// elementOpacity returns the opacity of an element, which is not
+// inherited.
+func elementOpacity(e *Element) float64 {
+	value, ok := styleProperty(e, "opacity")
+	if !ok {
+		return 1
+	}
+	return parseOpacity(value, 1)
+}
+
+// parseDashArray parses a stroke-dasharray, nil when the stroke is
+// solid. Lists of odd length are repeated.
+func parseDashArray(value string, reference float64) []float64 {
+	if strings.TrimSpace(value) == "none" {
+		return nil
+	}
+
+	var dashes []float64
+	total := 0.0
+	for _, item := range splitList(value) {
+		n, ok := userLength(item, reference)
+		if !ok || n < 0 {
+			return nil
+		}
+		dashes = append(dashes, n)
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes, dashes...)
+	}
+	return dashes
+}
+
+// parseColor parses a CSS color: a keyword, #rgb, #rgba, #rrggbb,
+// #rrggbbaa, rgb(), rgba(), hsl() or hsla().
+func parseColor(value string, current color.NRGBA) (color.NRGBA, bool) {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	switch {
+	case lower == "currentcolor":
+		return current, true
+	case lower == "transparent":
+		return color.NRGBA{}, true
+	case hexColorRegex.MatchString(value):
+		digits := value[1:]
+		if len(digits) <= 4 {
+			expanded := make([]byte, 0, 2*len(digits))
+			for i := 0; i < len(digits); i++ {
+				expanded = append(expanded, digits[i], digits[i])
+			}
+			digits = string(expanded)
+		}
+		if len(digits) == 6 {
+			digits += "ff"
+		}
+		var channels [4]uint8
+		for i := range channels {
+			n, _ := strconv.ParseUint(digits[2*i:2*i+2], 16, 8)
+			channels[i] = uint8(n)
+		}
+		return color.NRGBA{channels[0], channels[1], channels[2], channels[3]}, true
+	case colorFuncRegex.MatchString(lower):
+		return parseColorFunc(lower)
+	}
+
+	if c, ok := colornames.Map[lower]; ok {
+		return color.NRGBA{c.R, c.G, c.B, 0xff}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// parseColorFunc parses rgb(), rgba(), hsl() and hsla(), with commas or
+// spaces and a slash before the alpha.
+func parseColorFunc(value string) (color.NRGBA, bool) {
+	open := strings.Index(value, "(")
+	name := value[:open]
+	args := strings.FieldsFunc(value[open+1:len(value)-1], func(r rune) bool {
+		return r == ',' || r == '/' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(args) != 3 && len(args) != 4 {
+		return color.NRGBA{}, false
+	}
+
+	// number parses an argument, percentages being of scale
+	number := func(arg string, scale float64) (float64, bool) {
+		if strings.HasSuffix(arg, "%") {
+			n, err := parseNumber(strings.TrimSuffix(arg, "%"))
+			return n * scale / 100, err == nil
+		}
+		n, err := parseNumber(strings.TrimSuffix(arg, "deg"))
+		return n, err == nil
+	}
+	channel := func(n float64) uint8 {
+		return uint8(math.Round(math.Min(math.Max(n, 0), 255)))
+	}
+
+	alpha := 1.0
+	if len(args) == 4 {
+		a, ok := number(args[3], 1)
+		if !ok {
+			return color.NRGBA{}, false
+		}
+		alpha = math.Min(math.Max(a, 0), 1)
+	}
+
+	var values [3]float64
+	for i := range values {
+		scale := 255.0
+		if strings.HasPrefix(name, "hsl") {
+			scale = 1
+		}
+		n, ok := number(args[i], scale)
+		if !ok {
+			return color.NRGBA{}, false
+		}
+		values[i] = n
+	}
+
+	c := color.NRGBA{A: channel(alpha * 255)}
+	if strings.HasPrefix(name, "hsl") {
+		r, g, b := hslToRGB(values[0], values[1], values[2])
+		c.R, c.G, c.B = channel(r*255), channel(g*255), channel(b*255)
+	} else {
+		c.R, c.G, c.B = channel(values[0]), channel(values[1]), channel(values[2])
+	}
+	return c, true
+}
+
+// hslToRGB converts a hue in degrees, a saturation and a lightness in
+// 0..1 to red, green and blue in 0..1.
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s, l = math.Min(math.Max(s, 0), 1), math.Min(math.Max(l, 0), 1)
+
+	chroma := (1 - math.Abs(2*l-1)) * s
+	x := chroma * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - chroma/2
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g = chroma, x
+	case h < 120:
+		r, g = x, chroma
+	case h < 180:
+		g, b = chroma, x
+	case h < 240:
+		g, b = x, chroma
+	case h < 300:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	return r + m, g + m, b + m
+}
+
+// paint is a resolved fill or stroke: a color or a gradient.
+type paint struct {
+	color    color.NRGBA
+	gradient *gradient
+}
+
+// resolvePaint resolves a fill or stroke value, false when nothing is
+// painted. References to elements other than gradients use their
+// fallback color.
+func resolvePaint(value string, current color.NRGBA, lookup func(id string) *Element, viewport [2]float64) (paint, bool) {
+	value = strings.TrimSpace(value)
+	if value == "none" || value == "" {
+		return paint{}, false
+	}
+
+	if strings.HasPrefix(value, "url(") {
+		if ids := urlReferences(value); len(ids) > 0 && lookup != nil {
+			if target := lookup(ids[0]); target != nil {
+				if g, ok := resolveGradient(target, lookup, viewport); ok {
+					if len(g.stops) == 0 {
+						// a gradient without stops paints nothing
+						return paint{}, false
+					}
+					return paint{gradient: g}, true
+				}
+			}
+		}
+
+		match := funcIRIRegex.FindStringSubmatch(value)
+		if match == nil || match[2] == "" {
+			return paint{}, false
+		}
+		value = match[2]
+		if value == "none" {
+			return paint{}, false
+		}
+	}
+
+	c, ok := parseColor(value, current)
+	return paint{color: c}, ok
+}
+
+// gradient is a linear or radial gradient, with the attributes inherited
+// through href resolved.
+type gradient struct {
+	radial bool
+	// userSpace is set for userSpaceOnUse units, coordinates are
+	// fractions of the bounding box of the painted element otherwise.
+	userSpace bool
+	transform matrix
+	// spread is pad, reflect or repeat.
+	spread string
+
+	// x1, y1, x2, y2 of linear gradients
+	x1, y1, x2, y2 float64
+	// cx, cy, r, fx, fy of radial gradients
+	cx, cy, r, fx, fy float64
+
+	stops []gradientStop
+}
+
+type gradientStop struct {
+	offset float64
+	color  color.NRGBA
+}
+
+// resolveGradient reads a linearGradient or a radialGradient element.
+func resolveGradient(e *Element, lookup func(id string) *Element, viewport [2]float64) (*gradient, bool) {
+	name := e.LocalName()
+	if name != "linearGradient" && name != "radialGradient" {
+		return nil, false
+	}
+
+	// the gradient and the ones it references
+	chain := []*Element{e}
+	for el := e; strings.HasPrefix(useHref(el), "#"); {
+		el = lookup(useHref(el)[1:])
+		if el == nil || (el.LocalName() != "linearGradient" && el.LocalName() != "radialGradient") {
+			break
+		}
+		cycle := false
+		for _, seen := range chain {
+			cycle = cycle || seen == el
+		}
+		if cycle {
+			break
+		}
+		chain = append(chain, el)
+	}
+	attr := func(name string) (string, bool) {
+		for _, el := range chain {
+			if value, ok := el.Attributes[name]; ok {
+				return strings.TrimSpace(value), true
+			}
+		}
+		return "", false
+	}
+
+	g := &gradient{radial: name == "radialGradient", transform: identity, spread: "pad"}
+	if units, _ := attr("gradientUnits"); units == "userSpaceOnUse" {
+		g.userSpace = true
+	}
+	if value, ok := attr("gradientTransform"); ok {
+		if m, err := parseTransform(value); err == nil {
+			g.transform = m
+		}
+	}
+	if value, ok := attr("spreadMethod"); ok && (value == "reflect" || value == "repeat") {
+		g.spread = value
+	}
+
+	diagonal := math.Sqrt((viewport[0]*viewport[0] + viewport[1]*viewport[1]) / 2)
+	coordinate := func(name, fallback string, reference float64) float64 {
+		value, ok := attr(name)
+		if !ok {
+			value = fallback
+		}
+		if !g.userSpace {
+			reference = 1
+		}
+		n, _ := userLength(value, reference)
+		return n
+	}
+	if g.radial {
+		g.cx = coordinate("cx", "50%", viewport[0])
+		g.cy = coordinate("cy", "50%", viewport[1])
+		g.r = coordinate("r", "50%", diagonal)
+		g.fx, g.fy = g.cx, g.cy
+		if _, ok := attr("fx"); ok {
+			g.fx = coordinate("fx", "", viewport[0])
+		}
+		if _, ok := attr("fy"); ok {
+			g.fy = coordinate("fy", "", viewport[1])
+		}
+	} else {
+		g.x1 = coordinate("x1", "0%", viewport[0])
+		g.y1 = coordinate("y1", "0%", viewport[1])
+		g.x2 = coordinate("x2", "100%", viewport[0])
+		g.y2 = coordinate("y2", "0%", viewport[1])
+	}
+
+	// the stops of the first gradient of the chain having some
+	for _, el := range chain {
+		for _, stop := range el.Children {
+			if stop.LocalName() != "stop" {
+				continue
+			}
+			offset := parseOpacity(stop.Attributes["offset"], 0)
+			if n := len(g.stops); n > 0 && offset < g.stops[n-1].offset {
+				offset = g.stops[n-1].offset
+			}
+
+			c := color.NRGBA{A: 0xff}
+			if value, ok := styleProperty(stop, "stop-color"); ok {
+				current, _ := parseColor(computedStyle(stop, "color"), color.NRGBA{A: 0xff})
+				if parsed, ok := parseColor(value, current); ok {
+					c = parsed
+				}
+			}
+			if value, ok := styleProperty(stop, "stop-opacity"); ok {
+				c.A = uint8(math.Round(float64(c.A) * parseOpacity(value, 1)))
+			}
+			g.stops = append(g.stops, gradientStop{offset: offset, color: c})
+		}
+		if len(g.stops) > 0 {
+			break
+		}
+	}
+	return g, true
+}
+
+// space returns the transform from the gradient coordinates to the user
+// space of an element of the bounding box, false when the gradient can
+// not paint it.
+func (g *gradient) space(x, y, width, height float64) (matrix, bool) {
+	if g.userSpace {
+		return g.transform, true
+	}
+	if width <= 0 || height <= 0 {
+		return identity, false
+	}
+	return translation(x, y).multiply(scaling(width, height)).multiply(g.transform), true
+}
+
+// offset returns the gradient offset of a point in gradient coordinates,
+// before spreading.
+func (g *gradient) offset(x, y float64) float64 {
+	if !g.radial {
+		dx, dy := g.x2-g.x1, g.y2-g.y1
+		length := dx*dx + dy*dy
+		if length == 0 {
+			return 1
+		}
+		return ((x-g.x1)*dx + (y-g.y1)*dy) / length
+	}
+
+	if g.r <= 0 {
+		return 1
+	}
+	// the focus is kept within the circle
+	fx, fy := g.fx, g.fy
+	if d := math.Hypot(fx-g.cx, fy-g.cy); d > g.r*0.999 {
+		fx = g.cx + (fx-g.cx)*g.r*0.999/d
+		fy = g.cy + (fy-g.cy)*g.r*0.999/d
+	}
+
+	// t for which the point is on the circle of center f + t(c - f)
+	// and radius t r
+	dx, dy := x-fx, y-fy
+	cdx, cdy := g.cx-fx, g.cy-fy
+	a := cdx*cdx + cdy*cdy - g.r*g.r
+	b := dx*cdx + dy*cdy
+	c := dx*dx + dy*dy
+	return (b - math.Sqrt(math.Max(0, b*b-a*c))) / a
+}
+
+// colorAt returns the color at an offset.
+func (g *gradient) colorAt(t float64) color.NRGBA {
+	switch g.spread {
+	case "repeat":
+		t -= math.Floor(t)
+	case "reflect":
+		t = math.Mod(math.Abs(t), 2)
+		if t > 1 {
+			t = 2 - t
+		}
+	}
+
+	stops := g.stops
+	if len(stops) == 0 {
+		return color.NRGBA{}
+	}
+	if t <= stops[0].offset {
+		return stops[0].color
+	}
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].offset {
+			from, to := stops[i-1], stops[i]
+			span := to.offset - from.offset
+			if span <= 0 {
+				return to.color
+			}
+			f := (t - from.offset) / span
+			mix := func(a, b uint8) uint8 {
+				return uint8(math.Round(float64(a) + f*(float64(b)-float64(a))))
+			}
+			return color.NRGBA{
+				mix(from.color.R, to.color.R),
+				mix(from.color.G, to.color.G),
+				mix(from.color.B, to.color.B),
+				mix(from.color.A, to.color.A),
+			}
+		}
+	}
+	return stops[len(stops)-1].color
+}
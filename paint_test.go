@@ -0,0 +1,175 @@
+package svg
+
+import (
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	current := color.NRGBA{1, 2, 3, 255}
+	var testCases = []struct {
+		value    string
+		expected color.NRGBA
+		ok       bool
+	}{
+		{"red", color.NRGBA{255, 0, 0, 255}, true},
+		{"CornflowerBlue", color.NRGBA{100, 149, 237, 255}, true},
+		{"#f00", color.NRGBA{255, 0, 0, 255}, true},
+		{"#f008", color.NRGBA{255, 0, 0, 0x88}, true},
+		{"#102030", color.NRGBA{0x10, 0x20, 0x30, 255}, true},
+		{"#10203040", color.NRGBA{0x10, 0x20, 0x30, 0x40}, true},
+		{"rgb(255, 128, 0)", color.NRGBA{255, 128, 0, 255}, true},
+		{"rgb(100%, 50%, 0%)", color.NRGBA{255, 128, 0, 255}, true},
+		{"rgba(0, 0, 255, 0.5)", color.NRGBA{0, 0, 255, 128}, true},
+		{"rgb(0 0 255 / 50%)", color.NRGBA{0, 0, 255, 128}, true},
+		{"hsl(120, 100%, 50%)", color.NRGBA{0, 255, 0, 255}, true},
+		{"hsla(240deg, 100%, 50%, 0.5)", color.NRGBA{0, 0, 255, 128}, true},
+		{"currentColor", current, true},
+		{"transparent", color.NRGBA{}, true},
+		{"#12", color.NRGBA{}, false},
+		{"rgb(1, 2)", color.NRGBA{}, false},
+		{"nocolor", color.NRGBA{}, false},
+	}
+
+	for _, test := range testCases {
+		actual, ok := parseColor(test.value, current)
+		if ok != test.ok || actual != test.expected {
+			t.Errorf("parseColor %q: expected %v %v, actual %v %v\n", test.value, test.expected, test.ok, actual, ok)
+		}
+	}
+}
+
+func TestPresentation(t *testing.T) {
+	root, _ := parse(`
+		<svg>
+			<g id="group" fill="red" stroke="blue" style="stroke-width:4;stroke-linejoin:round;color:green" opacity="0.5">
+				<rect id="rect" fill="inherit" fill-opacity="50%" stroke-dasharray="1 2 3" stroke-dashoffset="1" stroke-miterlimit="0.5"/>
+				<rect id="hidden" visibility="hidden" stroke-width="-1" stroke-dasharray="none"/>
+			</g>
+		</svg>
+	`, false)
+
+	viewport := [2]float64{100, 100}
+	group := newPresentation().inherit(root.FindID("group"), viewport)
+	rect := group.inherit(root.FindID("rect"), viewport)
+
+	if rect.fill != "red" || rect.stroke != "blue" || rect.strokeWidth != 4 || rect.lineJoin != "round" {
+		t.Errorf("inherit: expected inherited properties, actual %+v\n", rect)
+	}
+	if rect.color != (color.NRGBA{0, 128, 0, 255}) {
+		t.Errorf("inherit: expected green, actual %v\n", rect.color)
+	}
+	if rect.fillOpacity != 0.5 || rect.strokeOpacity != 1 {
+		t.Errorf("inherit: expected opacities 0.5 1, actual %v %v\n", rect.fillOpacity, rect.strokeOpacity)
+	}
+	if expected := []float64{1, 2, 3, 1, 2, 3}; !reflect.DeepEqual(rect.dashArray, expected) || rect.dashOffset != 1 {
+		t.Errorf("inherit: expected dashes %v, actual %v %v\n", expected, rect.dashArray, rect.dashOffset)
+	}
+	if rect.miterLimit != 4 {
+		t.Errorf("inherit: expected the miter limit kept, actual %v\n", rect.miterLimit)
+	}
+
+	hidden := group.inherit(root.FindID("hidden"), viewport)
+	if hidden.visible || hidden.strokeWidth != 4 || hidden.dashArray != nil {
+		t.Errorf("inherit: expected hidden, actual %+v\n", hidden)
+	}
+
+	if actual := elementOpacity(root.FindID("group")); actual != 0.5 {
+		t.Errorf("elementOpacity: expected 0.5, actual %v\n", actual)
+	}
+	if actual := elementOpacity(root.FindID("rect")); actual != 1 {
+		t.Errorf("elementOpacity: expected 1, actual %v\n", actual)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	root, _ := parse(`
+		<svg>
+			<defs>
+				<linearGradient id="base" x2="0" y2="1">
+					<stop offset="0" stop-color="red"/>
+					<stop offset="50%" style="stop-color:blue;stop-opacity:0.5"/>
+					<stop offset="0.25" stop-color="lime"/>
+				</linearGradient>
+				<linearGradient id="derived" href="#base" spreadMethod="reflect" gradientUnits="userSpaceOnUse" x1="10" x2="30" y2="0"/>
+				<radialGradient id="radial" r="10" cx="10" cy="10" gradientUnits="userSpaceOnUse" gradientTransform="scale(2)">
+					<stop offset="0" stop-color="white"/>
+					<stop offset="1" stop-color="black"/>
+				</radialGradient>
+				<linearGradient id="loop" href="#loop"/>
+				<pattern id="pattern"/>
+			</defs>
+		</svg>
+	`, false)
+	lookup := referenceLookup(root)
+	viewport := [2]float64{100, 100}
+
+	base, _ := resolveGradient(root.FindID("base"), lookup, viewport)
+	if base.radial || base.userSpace || base.x1 != 0 || base.x2 != 0 || base.y2 != 1 || len(base.stops) != 3 {
+		t.Errorf("resolveGradient: unexpected %+v\n", base)
+	}
+	if base.stops[2].offset != 0.5 || base.stops[1].color != (color.NRGBA{0, 0, 255, 128}) {
+		t.Errorf("resolveGradient: unexpected stops %+v\n", base.stops)
+	}
+	if actual := base.offset(0.7, 0.25); actual != 0.25 {
+		t.Errorf("offset: expected 0.25, actual %v\n", actual)
+	}
+	if actual := base.colorAt(0.25); actual != (color.NRGBA{128, 0, 128, 192}) {
+		t.Errorf("colorAt: expected a mix of red and blue, actual %v\n", actual)
+	}
+
+	derived, _ := resolveGradient(root.FindID("derived"), lookup, viewport)
+	if !derived.userSpace || derived.x1 != 10 || derived.x2 != 30 || derived.y2 != 0 || derived.spread != "reflect" || len(derived.stops) != 3 {
+		t.Errorf("resolveGradient: unexpected %+v\n", derived)
+	}
+	// reflected past the end, back at the start
+	if actual := derived.colorAt(derived.offset(50, 0)); actual != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("colorAt: expected red, actual %v\n", actual)
+	}
+	if m, ok := derived.space(0, 0, 0, 0); !ok || m != identity {
+		t.Errorf("space: expected identity, actual %v %v\n", m, ok)
+	}
+	if _, ok := base.space(0, 0, 10, 0); ok {
+		t.Errorf("space: expected no space for an empty box\n")
+	}
+
+	radial, _ := resolveGradient(root.FindID("radial"), lookup, viewport)
+	if !radial.radial || !equalMatrices(radial.transform, scaling(2, 2)) {
+		t.Errorf("resolveGradient: unexpected %+v\n", radial)
+	}
+	for _, test := range []struct{ x, y, offset float64 }{{10, 10, 0}, {15, 10, 0.5}, {10, 20, 1}, {10, 30, 2}} {
+		if actual := radial.offset(test.x, test.y); math.Abs(actual-test.offset) > 1e-9 {
+			t.Errorf("offset %v %v: expected %v, actual %v\n", test.x, test.y, test.offset, actual)
+		}
+	}
+
+	if loop, ok := resolveGradient(root.FindID("loop"), lookup, viewport); !ok || len(loop.stops) != 0 {
+		t.Errorf("resolveGradient: expected a gradient without stops, actual %+v\n", loop)
+	}
+	if _, ok := resolveGradient(root.FindID("pattern"), lookup, viewport); ok {
+		t.Errorf("resolveGradient: expected no gradient\n")
+	}
+
+	var testCases = []struct {
+		value    string
+		color    color.NRGBA
+		gradient bool
+		ok       bool
+	}{
+		{"none", color.NRGBA{}, false, false},
+		{"#00f", color.NRGBA{0, 0, 255, 255}, false, true},
+		{"url(#base)", color.NRGBA{}, true, true},
+		{"url(#loop) red", color.NRGBA{}, false, false},
+		{"url(#pattern) red", color.NRGBA{255, 0, 0, 255}, false, true},
+		{"url(#missing)", color.NRGBA{}, false, false},
+		{"url(#missing) none", color.NRGBA{}, false, false},
+	}
+	for _, test := range testCases {
+		p, ok := resolvePaint(test.value, color.NRGBA{}, lookup, viewport)
+		if ok != test.ok || p.color != test.color || (p.gradient != nil) != test.gradient {
+			t.Errorf("resolvePaint %q: expected %v %v %v, actual %v %v %v\n", test.value, test.color, test.gradient, test.ok, p.color, p.gradient != nil, ok)
+		}
+	}
+}
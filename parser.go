@@ -3,8 +3,8 @@ package svg
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
 
 	"golang.org/x/net/html/charset"
@@ -12,11 +12,16 @@ import (
 
 // ValidationError contains errors which have occured when parsing svg input.
 type ValidationError struct {
-	msg string
+	Violations []Violation
 }
 
 func (err ValidationError) Error() string {
-	return err.msg
+	messages := make([]string, len(err.Violations))
+	for i, v := range err.Violations {
+		messages[i] = v.String()
+	}
+
+	return "invalid svg: " + strings.Join(messages, "; ")
 }
 
 // Element is a representation of an SVG element.
@@ -24,63 +29,177 @@ type Element struct {
 	Name       string
 	Attributes map[string]string
 	Children   []*Element
-	Content    string
 
-	// namespace dict extracted form root element attrributes
-	// which prefixed with xmlns:
+	// Content is the text of the element without its children. Text
+	// interleaved with children is concatenated; assigning Content
+	// replaces all of it.
+	Content string
+
+	// Space is the namespace URI of the element. It is empty for
+	// documents which do not declare namespaces.
+	Space string
+
+	// Namespaces maps prefixes to the namespace URIs bound in scope of
+	// the element, the empty prefix being the default namespace. The map
+	// is shared with ancestors when the element declares no namespace
+	// of its own and should be treated as read-only.
 	Namespaces map[string]string
+
+	// Nodes keeps text, comments, processing instructions, directives
+	// and CDATA sections in document order. Nodes of type ElementNode
+	// mark the position of the next entry of Children.
+	Nodes []*Node
+
+	// Prolog and Epilog hold the nodes found before and after the
+	// root element, such as the xml declaration and the doctype.
+	Prolog []*Node
+	Epilog []*Node
+
+	// Position is the location of the start tag in the source and
+	// AttributePositions the location of each attribute name. They are
+	// not set on elements which were not parsed.
+	Position           Position
+	AttributePositions map[string]Position
+
+	parent *Element
+	// document indexing the tree, set on the root element only
+	doc *Document
 }
 
-func canonizedName(name xml.Name, namespaces map[string]string) string {
-	if namespaces == nil {
-		return name.Local
-	}
+// documentDecoder wraps xml.Decoder with the state needed while
+// decoding a document.
+type documentDecoder struct {
+	*xml.Decoder
+
+	// input recorder, used to inspect the markup of tokens and to locate
+	// them. May be nil.
+	source *recorder
 
-	// lookup tag key
-	tag := name.Local
-	if name.Space != "" {
-		prefix := namespaces[name.Space]
+	// path of the open elements
+	path []string
+}
 
-		// ignore default svg prefix
-		if prefix != "svg" {
-			tag = prefix + ":" + name.Local
+// newDocumentDecoder creates a decoder which records its input.
+func newDocumentDecoder(r io.Reader) *documentDecoder {
+	d := &documentDecoder{source: newRecorder(r)}
+	d.Decoder = xml.NewDecoder(d.source)
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		converted, err := charset.NewReaderLabel(label, input)
+		if err != nil {
+			return nil, err
 		}
+		// offsets count converted bytes from here on
+		d.source = continueRecorder(d.source, converted, d.InputOffset())
+		return d.source, nil
+	}
+	return d
+}
+
+// position returns the current input position. It is called before
+// reading a token, so recorded input before it is dropped.
+func (d *documentDecoder) position() Position {
+	offset := d.InputOffset()
+	if d.source != nil {
+		d.source.discard(offset)
+	}
+	line, column := d.inputPos(offset)
+	return Position{Line: line, Column: column, Offset: offset}
+}
+
+// inputPos returns the line and column of an input offset, zero when
+// the input is not recorded.
+func (d *documentDecoder) inputPos(offset int64) (line, column int) {
+	if d.source == nil {
+		return 0, 0
+	}
+	return d.source.position(offset)
+}
+
+// markup returns the source of the token read from start, or nil when
+// it is not available.
+func (d *documentDecoder) markup(start Position) []byte {
+	if d.source == nil {
+		return nil
 	}
 
-	return tag
+	markup := d.source.bytes(start.Offset, d.InputOffset())
+	if len(markup) == 0 || markup[0] != '<' {
+		// input was converted from another charset
+		return nil
+	}
+	return markup
+}
+
+// locate sets the source position of an element read from start.
+func (d *documentDecoder) locate(e *Element, start Position) {
+	e.Position = start
+	if markup := d.markup(start); markup != nil {
+		e.AttributePositions = attributePositions(markup, start)
+	}
+}
+
+// isCData reports whether the character data token read from start is
+// a CDATA section.
+func (d *documentDecoder) isCData(start Position) bool {
+	return bytes.HasPrefix(d.markup(start), []byte("<![CDATA["))
 }
 
-// NewElement creates element from decoder token.
-func NewElement(root *Element, token xml.StartElement) *Element {
+// syntaxError creates an error at the current input position.
+func (d *documentDecoder) syntaxError(msg string) error {
+	line, _ := d.inputPos(d.InputOffset())
+	return &xml.SyntaxError{Msg: msg, Line: line}
+}
+
+// wrap turns an error into a ParseError locating the current position.
+func (d *documentDecoder) wrap(err error) error {
+	if _, ok := err.(*ParseError); ok || err == nil || err == io.EOF {
+		return err
+	}
+
+	offset := d.InputOffset()
+	line, column := d.inputPos(offset)
+	path := ""
+	if len(d.path) > 0 {
+		path = "/" + strings.Join(d.path, "/")
+	}
+
+	return &ParseError{
+		Path:     path,
+		Position: Position{Line: line, Column: column, Offset: offset},
+		Err:      err,
+	}
+}
+
+// NewElement creates element from a decoder token. Tokens returned by
+// xml.Decoder.RawToken keep the prefixes of the source, which are
+// resolved against the bindings in scope of parent; parent may be nil.
+// Tokens returned by xml.Decoder.Token, whose names carry namespace
+// URIs, are given the prefixes bound to these URIs. The parent of the
+// new element is set but it is not added to the parent children.
+func NewElement(parent *Element, token xml.StartElement) *Element {
 	element := &Element{}
 	attributes := make(map[string]string)
+
 	var namespaces map[string]string
-	if root == nil {
-		namespaces = make(map[string]string)
-	} else {
-		namespaces = root.Namespaces
+	if parent != nil {
+		namespaces = parent.Namespaces
 	}
+	namespaces = declareNamespaces(namespaces, token.Attr)
 
 	for _, attr := range token.Attr {
-		key := attr.Name.Local
-		if attr.Name.Space == "xmlns" {
-			namespaces[attr.Value] = attr.Name.Local
-			key = attr.Name.Space + ":" + attr.Name.Local
-		} else if attr.Name.Space != "" {
-			tag, ok := namespaces[attr.Name.Space]
-			if ok {
-				key = tag + ":" + attr.Name.Local
-			}
+		name := rawName(attr.Name, namespaces, true)
+		key := name.Local
+		if name.Space != "" {
+			key = name.Space + ":" + name.Local
 		}
 
 		attributes[key] = attr.Value
 	}
 
-	element.Name = canonizedName(token.Name, namespaces)
+	element.Name, element.Space = canonizedName(rawName(token.Name, namespaces, false), namespaces)
 	element.Attributes = attributes
-	if root == nil {
-		element.Namespaces = namespaces
-	}
+	element.Namespaces = namespaces
+	element.parent = parent
 
 	return element
 }
@@ -107,82 +226,220 @@ func (e *Element) Compare(o *Element) bool {
 	return true
 }
 
-// DecodeFirst creates the first element from the decoder.
+// DecodeFirst creates the first element from the decoder. Nodes found
+// before the element are kept in its Prolog.
 func DecodeFirst(decoder *xml.Decoder) (*Element, error) {
+	return decodeFirst(&documentDecoder{Decoder: decoder})
+}
+
+func decodeFirst(decoder *documentDecoder) (*Element, error) {
+	var prolog []*Node
 	for {
-		token, err := decoder.Token()
+		start := decoder.position()
+		token, err := decoder.RawToken()
 		if token == nil && err == io.EOF {
 			break
 		}
 
 		if err != nil {
-			return nil, err
+			return nil, decoder.wrap(err)
 		}
 
 		switch element := token.(type) {
 		case xml.StartElement:
-			return NewElement(nil, element), nil
+			first := NewElement(nil, element)
+			first.Prolog = prolog
+			decoder.locate(first, start)
+			return first, nil
+
+		default:
+			if node := newNode(xml.CopyToken(token)); node != nil {
+				prolog = append(prolog, node)
+			}
 		}
 	}
 	return &Element{}, nil
 }
 
-// Decode decodes the child elements of element.
+// Decode decodes the child elements of element. The decoder is read
+// with RawToken, whatever was used to read the start of element, and
+// namespace prefixes are resolved against the bindings in scope of
+// element. Bindings of root which element does not override are added
+// to its scope, for elements created without their parent; root may be
+// nil.
 func (e *Element) Decode(root *Element, decoder *xml.Decoder) error {
+	if root != nil && root != e {
+		e.inheritNamespaces(root.Namespaces)
+	}
+	return e.decode(&documentDecoder{Decoder: decoder}, e.endName())
+}
+
+// inheritNamespaces adds the bindings the element does not override.
+func (e *Element) inheritNamespaces(namespaces map[string]string) {
+	var inherited map[string]string
+	for prefix, uri := range namespaces {
+		if _, ok := e.Namespaces[prefix]; ok {
+			continue
+		}
+		if inherited == nil {
+			// the map may be shared with ancestors
+			inherited = make(map[string]string, len(e.Namespaces)+len(namespaces))
+			for k, v := range e.Namespaces {
+				inherited[k] = v
+			}
+		}
+		inherited[prefix] = uri
+	}
+
+	if inherited != nil {
+		e.Namespaces = inherited
+		if e.Space == "" {
+			// the prefix of the element may be bound by now
+			e.Name, e.Space = canonizedName(xml.Name{Space: e.Prefix(), Local: e.LocalName()}, inherited)
+		}
+	}
+}
+
+// endName returns the raw name expected to close the element, or nil
+// when it can not be told from the element name.
+func (e *Element) endName() *xml.Name {
+	prefix, local := splitName(e.Name)
+	if prefix == "" && e.Space != "" {
+		// svg elements may have been written with any prefix
+		return nil
+	}
+	return &xml.Name{Space: prefix, Local: local}
+}
+
+// decode decodes the content of the element until its end tag. When
+// end is not nil the end tag must match it exactly.
+func (e *Element) decode(decoder *documentDecoder, end *xml.Name) error {
+	if len(decoder.path) == 0 {
+		decoder.path = append(decoder.path, e.Name)
+		defer func() { decoder.path = decoder.path[:0] }()
+	}
+
+	counts := make(map[string]int)
 	for {
-		token, err := decoder.Token()
+		start := decoder.position()
+		token, err := decoder.RawToken()
 		if token == nil && err == io.EOF {
-			break
+			return decoder.wrap(decoder.syntaxError("unexpected EOF"))
 		}
 
 		if err != nil {
-			return err
+			return decoder.wrap(err)
 		}
 
 		switch element := token.(type) {
 		case xml.StartElement:
-			nextElement := NewElement(root, element)
-			err := nextElement.Decode(root, decoder)
+			nextElement := NewElement(e, element)
+			decoder.locate(nextElement, start)
+
+			counts[nextElement.Name]++
+			decoder.path = append(decoder.path, fmt.Sprintf("%s[%d]", nextElement.Name, counts[nextElement.Name]))
+			err := nextElement.decode(decoder, &element.Name)
 			if err != nil {
 				return err
 			}
+			decoder.path = decoder.path[:len(decoder.path)-1]
 
 			e.Children = append(e.Children, nextElement)
+			e.Nodes = append(e.Nodes, &Node{Type: ElementNode})
 
 		case xml.CharData:
-			data := strings.TrimSpace(string(element))
-			if data != "" {
-				e.Content = string(element)
+			node := &Node{Type: TextNode, Data: string(element)}
+			if decoder.isCData(start) {
+				node.Type = CDataNode
 			}
+			e.Nodes = append(e.Nodes, node)
+
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			e.Nodes = append(e.Nodes, newNode(xml.CopyToken(token)))
 
 		case xml.EndElement:
-			namespaces := e.Namespaces
-			if root != nil {
-				namespaces = root.Namespaces
+			matches := end != nil && element.Name == *end
+			if end == nil {
+				name, _ := canonizedName(element.Name, e.Namespaces)
+				matches = name == e.Name
 			}
 
-			if canonizedName(element.Name, namespaces) == e.Name {
-				return nil
+			if !matches {
+				return decoder.wrap(decoder.syntaxError("element <" + e.Name + "> closed by </" + qualifiedName(element.Name) + ">"))
 			}
+
+			e.Content = e.text()
+			return nil
+		}
+	}
+}
+
+// qualifiedName formats a raw decoder name as written in the source.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// text returns the character data of the element, excluding its
+// children. Elements with white space only have no text.
+func (e *Element) text() string {
+	var text strings.Builder
+	blank := true
+	for _, node := range e.Nodes {
+		if node.isText() {
+			text.WriteString(node.Data)
+			blank = blank && node.isBlank()
 		}
 	}
-	return nil
+
+	if blank {
+		return ""
+	}
+	return text.String()
 }
 
-// Parse creates an Element instance from an SVG input.
+// Parse creates an Element instance from an SVG input. Malformed input
+// is reported as a ParseError.
 func Parse(source io.Reader, validate bool) (*Element, error) {
-	raw, err := ioutil.ReadAll(source)
+	decoder := newDocumentDecoder(source)
+	element, err := decodeFirst(decoder)
 	if err != nil {
 		return nil, err
 	}
-	decoder := xml.NewDecoder(bytes.NewReader(raw))
-	decoder.CharsetReader = charset.NewReaderLabel
-	element, err := DecodeFirst(decoder)
-	if err != nil {
+	if element.Name == "" {
+		// empty document
+		return element, nil
+	}
+	if err := element.decode(decoder, element.endName()); err != nil {
 		return nil, err
 	}
-	if err := element.Decode(element, decoder); err != nil && err != io.EOF {
+	if err := element.decodeEpilog(decoder); err != nil {
 		return nil, err
 	}
+	if validate {
+		if err := Validate(element); err != nil {
+			return nil, err
+		}
+	}
 	return element, nil
 }
+
+// decodeEpilog collects the nodes which follow the root element.
+func (e *Element) decodeEpilog(decoder *documentDecoder) error {
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return decoder.wrap(err)
+		}
+
+		if node := newNode(xml.CopyToken(token)); node != nil {
+			e.Epilog = append(e.Epilog, node)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package svg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+)
+
+// pointsPerPixel converts user units, CSS pixels, to PDF points.
+const pointsPerPixel = 0.75
+
+// PDFOptions controls how documents are written as PDF.
+type PDFOptions struct {
+	// Fonts draws text with the fonts of the registry, which are
+	// embedded: TrueType fonts with the glyphs shown only, OpenType fonts
+	// with CFF outlines whole. Text is not drawn without fonts.
+	Fonts *FontRegistry
+
+	// ReadAnyFile allows images linked to any file the process can read,
+	// as RasterOptions.ReadAnyFile does.
+	ReadAnyFile bool
+}
+
+// WritePDF writes the document of the root element as a PDF. Each page
+// of an Inkscape document, an inkscape:page of its sodipodi:namedview,
+// becomes a page, other documents make a single page of their size.
+//
+// The document is drawn with vector content: paths and basic shapes are
+// filled and stroked with colors, and linear or radial gradients as
+// shadings, with stroke width, line joins, line caps, dashes, fill rules
+// and opacities. Elements are placed by their transforms, and by the
+// viewBox and preserveAspectRatio of svg elements. Images embedded as
+// data URIs, or linked to files within the base directory of the
+// document (see ReadAnyFile), are embedded, as are referenced elements
+// of use. Text is drawn with its fonts embedded, or as outlines when it
+// is painted with gradients. Clip paths, masks, filters, patterns and
+// markers are not drawn.
+func WritePDF(w io.Writer, root *Element, options PDFOptions) error {
+	return WritePDFPages(w, []*Element{root}, options)
+}
+
+// WritePDFPages writes documents as the pages of a single PDF, in order,
+// as WritePDF does. Fonts and images shared by the documents are only
+// embedded once. It returns ErrNoPage when there are no pages.
+func WritePDFPages(w io.Writer, roots []*Element, options PDFOptions) error {
+	p := newPDFWriter()
+	if options.Fonts != nil && !options.Fonts.empty() {
+		p.fonts = options.Fonts
+	}
+	p.anyFile = options.ReadAnyFile
+	for _, root := range roots {
+		p.addDocument(root)
+	}
+	if len(p.pages) == 0 {
+		return ErrNoPage
+	}
+	return p.writeTo(w)
+}
+
+// WritePDF writes the document as a PDF.
+func (d *Document) WritePDF(w io.Writer, options PDFOptions) error {
+	return WritePDF(w, d.Root, options)
+}
+
+// pdfWriter builds the objects of a PDF file.
+type pdfWriter struct {
+	// objects are numbered from 1
+	objects [][]byte
+	pages   []int
+
+	catalog, pageTree, resources int
+
+	fonts *FontRegistry
+	// anyFile allows images linked to files outside the base directory
+	anyFile bool
+	// resources of the pages by category, such as Font or XObject, and
+	// name
+	names     map[string]map[string]int
+	pdfFonts  map[*Font]*pdfFont
+	fontOrder []*pdfFont
+	images    map[string]*pdfImage
+	states    map[string]string
+}
+
+func newPDFWriter() *pdfWriter {
+	p := &pdfWriter{
+		names:    make(map[string]map[string]int),
+		pdfFonts: make(map[*Font]*pdfFont),
+		images:   make(map[string]*pdfImage),
+		states:   make(map[string]string),
+	}
+	p.catalog, p.pageTree, p.resources = p.alloc(), p.alloc(), p.alloc()
+	return p
+}
+
+// alloc returns the number of a new object.
+func (p *pdfWriter) alloc() int {
+	p.objects = append(p.objects, nil)
+	return len(p.objects)
+}
+
+// object sets the content of an object.
+func (p *pdfWriter) object(id int, format string, args ...interface{}) {
+	p.objects[id-1] = []byte(fmt.Sprintf(format, args...))
+}
+
+// stream sets an object to a compressed stream, dict holding the entries
+// of its dictionary other than its length and filter.
+func (p *pdfWriter) stream(id int, dict string, data []byte) {
+	var buf bytes.Buffer
+	z := zlib.NewWriter(&buf)
+	z.Write(data)
+	z.Close()
+	p.rawStream(id, strings.TrimSpace(dict+" /Filter /FlateDecode"), buf.Bytes())
+}
+
+// rawStream sets an object to a stream of data as is.
+func (p *pdfWriter) rawStream(id int, dict string, data []byte) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	buf.Write(data)
+	buf.WriteString("\nendstream")
+	p.objects[id-1] = buf.Bytes()
+}
+
+// resource names an object among the resources of the pages.
+func (p *pdfWriter) resource(category, prefix string, id int) string {
+	names := p.names[category]
+	if names == nil {
+		names = make(map[string]int)
+		p.names[category] = names
+	}
+	name := fmt.Sprintf("%s%d", prefix, len(names)+1)
+	names[name] = id
+	return name
+}
+
+// opacityState returns the name of a graphics state setting the fill and
+// the stroke opacities.
+func (p *pdfWriter) opacityState(fill, stroke float64) string {
+	key := formatNumbers(fill, stroke)
+	if name, ok := p.states[key]; ok {
+		return name
+	}
+	id := p.alloc()
+	p.object(id, "<< /Type /ExtGState /ca %s /CA %s >>", formatNumber(fill), formatNumber(stroke))
+	name := p.resource("ExtGState", "GS", id)
+	p.states[key] = name
+	return name
+}
+
+// addDocument adds the pages of a document.
+func (p *pdfWriter) addDocument(root *Element) {
+	width, height, box := documentSize(root)
+	ctm := viewBoxTransform(box, root.Attributes["preserveAspectRatio"], width, height)
+	pages := inkscapePages(root, ctm)
+	if len(pages) == 0 {
+		pages = [][4]float64{{0, 0, width, height}}
+	}
+
+	bounds := pages[0]
+	for _, page := range pages[1:] {
+		right, bottom := maxFloat(bounds[0]+bounds[2], page[0]+page[2]), maxFloat(bounds[1]+bounds[3], page[1]+page[3])
+		bounds[0], bounds[1] = minFloat(bounds[0], page[0]), minFloat(bounds[1], page[1])
+		bounds[2], bounds[3] = right-bounds[0], bottom-bounds[1]
+	}
+
+	c := &pdfCanvas{
+		pdf:    p,
+		out:    &bytes.Buffer{},
+		root:   root,
+		lookup: referenceLookup(root),
+		bounds: bounds,
+		runs:   make(map[*Element]*glyphRun),
+	}
+	if d := root.document(); d != nil {
+		c.baseDir = d.BaseDir
+	}
+	c.inliner = &useInliner{lookup: c.lookup, visiting: make(map[*Element]bool)}
+	if ctm != identity {
+		c.printf("%s cm\n", formatNumbers(ctm[:]...))
+	}
+	c.draw(root, newPresentation(), ctm, [2]float64{box[2], box[3]})
+	content := c.out.Bytes()
+
+	if len(pages) > 1 {
+		// the pages show parts of a single drawing
+		id := p.alloc()
+		p.stream(id, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [%s] /Resources %d 0 R",
+			formatNumbers(bounds[0], bounds[1], bounds[0]+bounds[2], bounds[1]+bounds[3]), p.resources), content)
+		content = []byte("/" + p.resource("XObject", "Fm", id) + " Do\n")
+	}
+
+	for _, page := range pages {
+		width, height := page[2]*pointsPerPixel, page[3]*pointsPerPixel
+		// user units grow downwards, points upwards
+		m := matrix{pointsPerPixel, 0, 0, -pointsPerPixel, -page[0] * pointsPerPixel, height + page[1]*pointsPerPixel}
+
+		contents := p.alloc()
+		p.stream(contents, "", append([]byte(formatNumbers(m[:]...)+" cm\n"), content...))
+		id := p.alloc()
+		p.object(id, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s] /Resources %d 0 R /Contents %d 0 R >>",
+			p.pageTree, formatNumbers(width, height), p.resources, contents)
+		p.pages = append(p.pages, id)
+	}
+}
+
+// inkscapePages returns the x, y, width and height of the pages of an
+// Inkscape document in the units of its viewport, ctm mapping its user
+// space to them.
+func inkscapePages(root *Element, ctm matrix) [][4]float64 {
+	var pages [][4]float64
+	for _, view := range root.Children {
+		if view.Space != SodipodiNamespace || view.LocalName() != "namedview" {
+			continue
+		}
+		for _, page := range view.Children {
+			if page.Space != InkscapeNamespace || page.LocalName() != "page" {
+				continue
+			}
+
+			var values [4]float64
+			for i, name := range []string{"x", "y", "width", "height"} {
+				values[i], _ = parseNumber(page.Attributes[name])
+			}
+			x0, y0 := ctm.apply(values[0], values[1])
+			x1, y1 := ctm.apply(values[0]+values[2], values[1]+values[3])
+			if x1 > x0 && y1 > y0 {
+				pages = append(pages, [4]float64{x0, y0, x1 - x0, y1 - y0})
+			}
+		}
+	}
+	return pages
+}
+
+// pdfImage is a raster image embedded in a PDF.
+type pdfImage struct {
+	name          string
+	width, height int
+}
+
+// image returns the image of a data URI or of a file, embedding it the
+// first time, false when it can not be decoded.
+func (p *pdfWriter) image(href, baseDir string) (*pdfImage, bool) {
+	key := baseDir + "\x00" + href
+	img, ok := p.images[key]
+	if !ok {
+		img = p.addImage(imageData(href, baseDir, p.anyFile))
+		p.images[key] = img
+	}
+	return img, img != nil
+}
+
+// addImage embeds raster image data. JPEG data is embedded as is, other
+// images as samples, with a soft mask when they are not opaque.
+func (p *pdfWriter) addImage(data []byte) *pdfImage {
+	if data == nil {
+		return nil
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil
+	}
+
+	width, height := config.Width, config.Height
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", width, height)
+	var id int
+	switch {
+	case format == "jpeg" && config.ColorModel == color.YCbCrModel:
+		id = p.alloc()
+		p.rawStream(id, dict+" /ColorSpace /DeviceRGB /Filter /DCTDecode", data)
+	case format == "jpeg" && config.ColorModel == color.GrayModel:
+		id = p.alloc()
+		p.rawStream(id, dict+" /ColorSpace /DeviceGray /Filter /DCTDecode", data)
+	default:
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		id = p.alloc()
+
+		bounds := src.Bounds()
+		samples := make([]byte, 0, 3*width*height)
+		alpha := make([]byte, 0, width*height)
+		opaque := true
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+				samples = append(samples, c.R, c.G, c.B)
+				alpha = append(alpha, c.A)
+				opaque = opaque && c.A == 0xff
+			}
+		}
+		if !opaque {
+			mask := p.alloc()
+			p.stream(mask, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8 /ColorSpace /DeviceGray", width, height), alpha)
+			dict += fmt.Sprintf(" /SMask %d 0 R", mask)
+		}
+		p.stream(id, dict+" /ColorSpace /DeviceRGB", samples)
+	}
+	return &pdfImage{name: p.resource("XObject", "Im", id), width: width, height: height}
+}
+
+// writeTo completes the document and writes the file.
+func (p *pdfWriter) writeTo(w io.Writer) error {
+	for _, f := range p.fontOrder {
+		f.write(p)
+	}
+
+	var resources strings.Builder
+	resources.WriteString("<< /ProcSet [/PDF /Text /ImageB /ImageC]")
+	categories := make([]string, 0, len(p.names))
+	for category := range p.names {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		names := make([]string, 0, len(p.names[category]))
+		for name := range p.names[category] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(&resources, " /%s <<", category)
+		for _, name := range names {
+			fmt.Fprintf(&resources, " /%s %d 0 R", name, p.names[category][name])
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+	p.object(p.resources, "%s", resources.String())
+
+	kids := make([]string, len(p.pages))
+	for i, id := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	p.object(p.pageTree, "<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))
+	p.object(p.catalog, "<< /Type /Catalog /Pages %d 0 R >>", p.pageTree)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(p.objects))
+	for i, object := range p.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.objects)+1, p.catalog, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
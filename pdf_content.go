@@ -0,0 +1,542 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// pdfTolerance is the distance in user units of the document, pixels,
+// curves are flattened within when strokes are outlined.
+const pdfTolerance = 0.05
+
+// maxGradientTiles bounds the repetitions of reflected and repeated
+// gradients.
+const maxGradientTiles = 256
+
+// pdfCanvas draws elements into a PDF content stream.
+type pdfCanvas struct {
+	pdf     *pdfWriter
+	out     *bytes.Buffer
+	root    *Element
+	lookup  func(id string) *Element
+	inliner *useInliner
+	baseDir string
+	// bounds is the area drawn, in the units of the document viewport.
+	bounds [4]float64
+	// runs are the glyphs of the paths of outlined texts.
+	runs map[*Element]*glyphRun
+}
+
+func (c *pdfCanvas) printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.out, format, args...)
+}
+
+// draw draws an element with the properties of its parent, ctm mapping
+// its user space to the document viewport, which the content stream is
+// in at the start.
+func (c *pdfCanvas) draw(e *Element, style presentation, ctm matrix, viewport [2]float64) {
+	if value, _ := styleProperty(e, "display"); value == "none" {
+		return
+	}
+
+	switch e.LocalName() {
+	case "text":
+		if c.pdf.fonts == nil {
+			return
+		}
+		o := &textOutline{registry: c.pdf.fonts}
+		e = o.outline(e)
+		for _, run := range o.runs {
+			c.runs[run.path] = run
+		}
+	case "use":
+		if !isUse(e) {
+			return
+		}
+		group, err := c.inliner.expand(e)
+		if err != nil {
+			return
+		}
+		e = group
+	}
+
+	name := e.LocalName()
+	switch name {
+	case "svg", "g", "a", "switch", "image", "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+	default:
+		// definitions, metadata and unsupported elements
+		return
+	}
+
+	style = style.inherit(e, viewport)
+	transform := identity
+	if value, ok := e.Attributes["transform"]; ok {
+		if m, err := parseTransform(value); err == nil {
+			transform = m
+		}
+	}
+	opacity := elementOpacity(e)
+	if opacity <= 0 {
+		return
+	}
+
+	ctm = ctm.multiply(transform)
+	if transform != identity || opacity < 1 {
+		c.printf("q\n")
+		defer c.printf("Q\n")
+	}
+	if transform != identity {
+		c.printf("%s cm\n", formatNumbers(transform[:]...))
+	}
+	if opacity < 1 {
+		// the element is drawn apart as a transparency group, then blended
+		bbox, ok := c.bbox(ctm)
+		if !ok {
+			return
+		}
+		out := c.out
+		c.out = &bytes.Buffer{}
+		defer func() {
+			id := c.pdf.alloc()
+			c.pdf.stream(id, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [%s] /Group << /S /Transparency >> /Resources %d 0 R",
+				formatNumbers(bbox[:]...), c.pdf.resources), c.out.Bytes())
+			c.out = out
+			c.printf("/%s gs /%s Do\n", c.pdf.opacityState(opacity, opacity), c.pdf.resource("XObject", "Fm", id))
+		}()
+	}
+
+	switch name {
+	case "svg":
+		if e != c.root {
+			var m matrix
+			m, viewport = nestedViewport(e, identity, viewport)
+			ctm = ctm.multiply(m)
+			c.printf("%s cm\n", formatNumbers(m[:]...))
+		}
+		fallthrough
+	case "g", "a":
+		for _, child := range e.Children {
+			c.draw(child, style, ctm, viewport)
+		}
+	case "switch":
+		// the first child, conditional processing is not supported
+		if len(e.Children) > 0 {
+			c.draw(e.Children[0], style, ctm, viewport)
+		}
+	case "image":
+		c.image(e, style, viewport)
+	default:
+		if p, ok := elementPath(e, viewport); ok && style.visible {
+			if run := c.runs[e]; run != nil && c.text(run, style, viewport) {
+				return
+			}
+			c.fill(p, style, ctm, viewport)
+			c.stroke(p, style, ctm, viewport)
+		}
+	}
+}
+
+// bbox returns the bounds drawn, as the left, top, right and bottom in
+// the user space of ctm, false when it is degenerate.
+func (c *pdfCanvas) bbox(ctm matrix) ([4]float64, bool) {
+	inverse, ok := ctm.invert()
+	if !ok {
+		return [4]float64{}, false
+	}
+	b := c.bounds
+	var box [4]float64
+	for i, corner := range [4]point{{b[0], b[1]}, {b[0] + b[2], b[1]}, {b[0], b[1] + b[3]}, {b[0] + b[2], b[1] + b[3]}} {
+		x, y := inverse.apply(corner.x, corner.y)
+		if i == 0 {
+			box = [4]float64{x, y, x, y}
+			continue
+		}
+		box = [4]float64{minFloat(box[0], x), minFloat(box[1], y), maxFloat(box[2], x), maxFloat(box[3], y)}
+	}
+	return box, true
+}
+
+// path writes the construction of a path.
+func (c *pdfCanvas) path(p shapePath) {
+	for _, segment := range p {
+		pts := segment.points
+		switch segment.op {
+		case segmentMove:
+			c.printf("%s m\n", formatNumbers(pts[0].x, pts[0].y))
+		case segmentLine:
+			c.printf("%s l\n", formatNumbers(pts[0].x, pts[0].y))
+		case segmentCubic:
+			c.printf("%s c\n", formatNumbers(pts[0].x, pts[0].y, pts[1].x, pts[1].y, pts[2].x, pts[2].y))
+		case segmentClose:
+			c.printf("h\n")
+		}
+	}
+}
+
+// colorOperator returns the operator setting a fill or a stroke color.
+func colorOperator(c color.NRGBA, stroke bool) string {
+	operator := "rg"
+	if stroke {
+		operator = "RG"
+	}
+	return formatNumbers(float64(c.R)/255, float64(c.G)/255, float64(c.B)/255) + " " + operator
+}
+
+// lineStyle writes the stroke parameters.
+func (c *pdfCanvas) lineStyle(s strokeStyle) {
+	caps := map[string]int{"butt": 0, "round": 1, "square": 2}
+	joins := map[string]int{"miter": 0, "miter-clip": 0, "round": 1, "arcs": 1, "bevel": 2}
+	c.printf("%s w %d J %d j %s M\n", formatNumber(s.width), caps[s.cap], joins[s.join], formatNumber(s.miterLimit))
+
+	if s.dashes != nil {
+		total := 0.0
+		for _, dash := range s.dashes {
+			total += dash
+		}
+		// the phase is positive
+		phase := math.Mod(s.dashOffset, total)
+		if phase < 0 {
+			phase += total
+		}
+		c.printf("[%s] %s d\n", formatNumbers(s.dashes...), formatNumber(phase))
+	}
+}
+
+// fill fills a shape.
+func (c *pdfCanvas) fill(p shapePath, style presentation, ctm matrix, viewport [2]float64) {
+	fill, ok := resolvePaint(style.fill, style.color, c.lookup, viewport)
+	if !ok || style.fillOpacity <= 0 {
+		return
+	}
+	evenOdd := style.fillRule == "evenodd"
+	if fill.gradient != nil {
+		c.gradient(fill.gradient, p, evenOdd, p, style.fillOpacity, ctm)
+		return
+	}
+
+	alpha := float64(fill.color.A) / 255 * style.fillOpacity
+	if alpha <= 0 {
+		return
+	}
+	c.printf("q\n")
+	if alpha < 1 {
+		c.printf("/%s gs\n", c.pdf.opacityState(alpha, 1))
+	}
+	c.printf("%s\n", colorOperator(fill.color, false))
+	c.path(p)
+	if evenOdd {
+		c.printf("f*\nQ\n")
+	} else {
+		c.printf("f\nQ\n")
+	}
+}
+
+// stroke strokes the outline of a shape. Strokes painted with gradients
+// are outlined.
+func (c *pdfCanvas) stroke(p shapePath, style presentation, ctm matrix, viewport [2]float64) {
+	stroke, ok := resolvePaint(style.stroke, style.color, c.lookup, viewport)
+	if !ok || style.strokeOpacity <= 0 || style.strokeWidth <= 0 {
+		return
+	}
+
+	if stroke.gradient != nil {
+		scale := ctm.scale()
+		if scale == 0 {
+			return
+		}
+		tolerance := pdfTolerance / scale
+		var outline shapePath
+		for _, polygon := range strokePolygons(p.flatten(tolerance), style.strokeStyle(), tolerance) {
+			outline.moveTo(polygon[0].x, polygon[0].y)
+			for _, pt := range polygon[1:] {
+				outline.lineTo(pt.x, pt.y)
+			}
+			outline.close()
+		}
+		if len(outline) > 0 {
+			c.gradient(stroke.gradient, outline, false, p, style.strokeOpacity, ctm)
+		}
+		return
+	}
+
+	alpha := float64(stroke.color.A) / 255 * style.strokeOpacity
+	if alpha <= 0 {
+		return
+	}
+	c.printf("q\n")
+	if alpha < 1 {
+		c.printf("/%s gs\n", c.pdf.opacityState(1, alpha))
+	}
+	c.printf("%s\n", colorOperator(stroke.color, true))
+	c.lineStyle(style.strokeStyle())
+	c.path(p)
+	c.printf("S\nQ\n")
+}
+
+// gradient paints the area of a path with a gradient, shape being the
+// element painted, whose bounding box gradients may be relative to.
+func (c *pdfCanvas) gradient(g *gradient, area shapePath, evenOdd bool, shape shapePath, opacity float64, ctm matrix) {
+	space, ok := g.space(shape.bounds())
+	if !ok {
+		return
+	}
+	inverse, ok := space.invert()
+	if !ok {
+		return
+	}
+	bbox, ok := c.bbox(ctm.multiply(space))
+	if !ok {
+		return
+	}
+
+	// the offsets of the corners of the area
+	x, y, width, height := area.bounds()
+	t0, t1 := 0.0, 1.0
+	for _, corner := range [4]point{{x, y}, {x + width, y}, {x, y + height}, {x + width, y + height}} {
+		t := g.offset(inverse.apply(corner.x, corner.y))
+		t0, t1 = minFloat(t0, t), maxFloat(t1, t)
+	}
+
+	shading, mask, ok := c.pdf.shading(g, t0, t1)
+	if !ok {
+		return
+	}
+
+	c.printf("q\n")
+	c.path(area)
+	if evenOdd {
+		c.printf("W* n\n")
+	} else {
+		c.printf("W n\n")
+	}
+	if space != identity {
+		c.printf("%s cm\n", formatNumbers(space[:]...))
+	}
+	switch {
+	case mask != 0:
+		c.printf("/%s gs\n", c.pdf.maskState(mask, opacity, bbox))
+	case opacity < 1:
+		c.printf("/%s gs\n", c.pdf.opacityState(opacity, 1))
+	}
+	c.printf("/%s sh\nQ\n", shading)
+}
+
+// shading adds the shading of a gradient over offsets t0 to t1, and the
+// shading of its opacities when it is not opaque. It returns the name of
+// the first, the object of the second, 0 for opaque gradients, false
+// when there is nothing to paint.
+func (p *pdfWriter) shading(g *gradient, t0, t1 float64) (string, int, bool) {
+	if g.spread == "pad" {
+		t0, t1 = 0, 1
+	}
+	if g.radial {
+		t0 = 0
+	}
+	t1 = minFloat(t1, t0+maxGradientTiles)
+
+	// the stops, repeated or reflected past the gradient, within the
+	// offsets
+	stops := []gradientStop{{t0, g.colorAt(t0)}}
+	first, last := math.Floor(t0), math.Ceil(t1)
+	if g.spread == "pad" {
+		first, last = 0, 1
+	}
+	for k := first; k < last; k++ {
+		reflected := g.spread == "reflect" && math.Mod(math.Abs(k), 2) == 1
+		for i := range g.stops {
+			offset := k + g.stops[i].offset
+			stop := g.stops[i]
+			if reflected {
+				stop = g.stops[len(g.stops)-1-i]
+				offset = k + 1 - stop.offset
+			}
+			if offset > t0 && offset <= t1 {
+				stops = append(stops, gradientStop{offset, stop.color})
+			}
+		}
+	}
+	if stops[len(stops)-1].offset < t1 {
+		stops = append(stops, gradientStop{t1, g.colorAt(t1)})
+	}
+
+	var coords []float64
+	if g.radial {
+		if g.r <= 0 {
+			return "", 0, false
+		}
+		// the focus is kept within the circle, as offset does
+		fx, fy := g.fx, g.fy
+		if d := math.Hypot(fx-g.cx, fy-g.cy); d > g.r*0.999 {
+			fx = g.cx + (fx-g.cx)*g.r*0.999/d
+			fy = g.cy + (fy-g.cy)*g.r*0.999/d
+		}
+		coords = []float64{fx, fy, 0, fx + t1*(g.cx-fx), fy + t1*(g.cy-fy), t1 * g.r}
+	} else {
+		dx, dy := g.x2-g.x1, g.y2-g.y1
+		if dx == 0 && dy == 0 {
+			return "", 0, false
+		}
+		coords = []float64{g.x1 + t0*dx, g.y1 + t0*dy, g.x1 + t1*dx, g.y1 + t1*dy}
+	}
+
+	shadingType := 2
+	if g.radial {
+		shadingType = 3
+	}
+	add := func(space string, channels func(c color.NRGBA) []float64) int {
+		id := p.alloc()
+		p.object(id, "<< /ShadingType %d /ColorSpace /%s /Coords [%s] /Domain [%s] /Function %s /Extend [true true] >>",
+			shadingType, space, formatNumbers(coords...), formatNumbers(t0, t1), shadingFunction(stops, channels))
+		return id
+	}
+
+	name := p.resource("Shading", "Sh", add("DeviceRGB", func(c color.NRGBA) []float64 {
+		return []float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255}
+	}))
+	for _, stop := range stops {
+		if stop.color.A != 0xff {
+			return name, add("DeviceGray", func(c color.NRGBA) []float64 {
+				return []float64{float64(c.A) / 255}
+			}), true
+		}
+	}
+	return name, 0, true
+}
+
+// shadingFunction returns a function interpolating the channels of the
+// colors of stops, in increasing offsets.
+func shadingFunction(stops []gradientStop, channels func(c color.NRGBA) []float64) string {
+	var functions, bounds []string
+	for i := 1; i < len(stops); i++ {
+		from, to := stops[i-1], stops[i]
+		if to.offset <= from.offset {
+			// a sharp transition
+			continue
+		}
+		if len(functions) > 0 {
+			bounds = append(bounds, formatNumber(from.offset))
+		}
+		functions = append(functions, fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >>",
+			formatNumbers(channels(from.color)...), formatNumbers(channels(to.color)...)))
+	}
+
+	if len(functions) == 1 {
+		return functions[0]
+	}
+	encode := strings.TrimSpace(strings.Repeat("0 1 ", len(functions)))
+	return fmt.Sprintf("<< /FunctionType 3 /Domain [%s] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		formatNumbers(stops[0].offset, stops[len(stops)-1].offset), strings.Join(functions, " "), strings.Join(bounds, " "), encode)
+}
+
+// maskState returns the name of a graphics state setting an opacity and
+// a soft mask of the shading of the opacities of a gradient, bbox
+// bounding the area painted.
+func (p *pdfWriter) maskState(shading int, opacity float64, bbox [4]float64) string {
+	form := p.alloc()
+	p.stream(form, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [%s] /Group << /S /Transparency /CS /DeviceGray >> /Resources << /Shading << /Sh %d 0 R >> >>",
+		formatNumbers(bbox[:]...), shading), []byte("/Sh sh\n"))
+	id := p.alloc()
+	p.object(id, "<< /Type /ExtGState /ca %s /SMask << /Type /Mask /S /Luminosity /G %d 0 R >> >>", formatNumber(opacity), form)
+	return p.resource("ExtGState", "GS", id)
+}
+
+// image draws an image element.
+func (c *pdfCanvas) image(e *Element, style presentation, viewport [2]float64) {
+	if !style.visible {
+		return
+	}
+	img, ok := c.pdf.image(useHref(e), c.baseDir)
+	if !ok {
+		return
+	}
+
+	length := func(name string, reference, intrinsic float64) float64 {
+		n, ok := userLength(e.Attributes[name], reference)
+		if !ok {
+			return intrinsic
+		}
+		return n
+	}
+	x, y := length("x", viewport[0], 0), length("y", viewport[1], 0)
+	width := length("width", viewport[0], float64(img.width))
+	height := length("height", viewport[1], float64(img.height))
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	w, h := float64(img.width), float64(img.height)
+	preserveAspectRatio := e.Attributes["preserveAspectRatio"]
+	// images are drawn in the unit square, their first row at the top
+	m := translation(x, y).multiply(viewBoxTransform([4]float64{0, 0, w, h}, preserveAspectRatio, width, height)).multiply(matrix{w, 0, 0, -h, 0, h})
+
+	c.printf("q\n")
+	if strings.HasSuffix(strings.TrimSpace(preserveAspectRatio), "slice") {
+		// the image is clipped to its box
+		c.printf("%s re W n\n", formatNumbers(x, y, width, height))
+	}
+	c.printf("%s cm /%s Do\nQ\n", formatNumbers(m[:]...), img.name)
+}
+
+// text draws the glyphs of a path of an outlined text with its fonts,
+// false when they are drawn as outlines, for gradients or fonts which
+// can not be embedded.
+func (c *pdfCanvas) text(run *glyphRun, style presentation, viewport [2]float64) bool {
+	fill, hasFill := resolvePaint(style.fill, style.color, c.lookup, viewport)
+	stroke, hasStroke := resolvePaint(style.stroke, style.color, c.lookup, viewport)
+	hasFill = hasFill && style.fillOpacity > 0
+	hasStroke = hasStroke && style.strokeOpacity > 0 && style.strokeWidth > 0
+	if hasFill && fill.gradient != nil || hasStroke && stroke.gradient != nil {
+		return false
+	}
+	for _, g := range run.glyphs {
+		if g.font.data == nil {
+			return false
+		}
+	}
+
+	// the text rendering mode
+	var mode int
+	switch {
+	case hasFill && hasStroke:
+		mode = 2
+	case hasFill:
+		mode = 0
+	case hasStroke:
+		mode = 1
+	default:
+		return true
+	}
+
+	c.printf("q\n")
+	fillAlpha := float64(fill.color.A) / 255 * style.fillOpacity
+	strokeAlpha := float64(stroke.color.A) / 255 * style.strokeOpacity
+	if hasFill && fillAlpha < 1 || hasStroke && strokeAlpha < 1 {
+		c.printf("/%s gs\n", c.pdf.opacityState(fillAlpha, strokeAlpha))
+	}
+	if hasFill {
+		c.printf("%s\n", colorOperator(fill.color, false))
+	}
+	if hasStroke {
+		c.printf("%s\n", colorOperator(stroke.color, true))
+		c.lineStyle(style.strokeStyle())
+	}
+
+	c.printf("BT\n%d Tr\n", mode)
+	var current *pdfFont
+	size := 0.0
+	for _, g := range run.glyphs {
+		f := c.pdf.font(g.font)
+		if f != current || g.size != size {
+			c.printf("/%s %s Tf\n", f.name, formatNumber(g.size))
+			current, size = f, g.size
+		}
+		f.use(g.index, g.char)
+		// glyphs are upright in user space, which grows downwards
+		c.printf("1 0 0 -1 %s Tm <%04x> Tj\n", formatNumbers(g.x, g.y), uint16(g.index))
+	}
+	c.printf("ET\nQ\n")
+	return true
+}
@@ -0,0 +1,389 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfFont is a font embedded in a PDF as a composite font whose codes
+// are glyph indexes.
+type pdfFont struct {
+	font *Font
+	name string
+	id   int
+	// chars are the characters of the glyphs shown, for text extraction.
+	chars map[sfnt.GlyphIndex]rune
+}
+
+// font returns the embedded font of a font, adding it the first time.
+func (p *pdfWriter) font(f *Font) *pdfFont {
+	if pf, ok := p.pdfFonts[f]; ok {
+		return pf
+	}
+	id := p.alloc()
+	pf := &pdfFont{font: f, name: p.resource("Font", "F", id), id: id, chars: make(map[sfnt.GlyphIndex]rune)}
+	p.pdfFonts[f] = pf
+	p.fontOrder = append(p.fontOrder, pf)
+	return pf
+}
+
+// use records a glyph as shown.
+func (f *pdfFont) use(index sfnt.GlyphIndex, char rune) {
+	if _, ok := f.chars[index]; !ok {
+		f.chars[index] = char
+	}
+}
+
+// baseName returns the PostScript name of the font.
+func (f *pdfFont) baseName() string {
+	var buf sfnt.Buffer
+	name, err := f.font.font.Name(&buf, sfnt.NameIDPostScript)
+	if err != nil || name == "" {
+		name = f.font.family
+	}
+
+	// characters allowed in names without escapes
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "Font"
+	}
+	return name
+}
+
+// write adds the objects of the font once the glyphs shown are known.
+// TrueType fonts are embedded as a subset of these glyphs, OpenType fonts
+// with CFF outlines as a whole.
+func (f *pdfFont) write(p *pdfWriter) {
+	var buf sfnt.Buffer
+	sf := f.font.font
+	name := f.baseName()
+	scale := func(length fixed.Int26_6) float64 {
+		return f.font.scale(length, 1000)
+	}
+
+	indexes := make([]sfnt.GlyphIndex, 0, len(f.chars))
+	for index := range f.chars {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	// OpenType fonts with CFF outlines or TrueType ones
+	cff := bytes.HasPrefix(f.font.data, []byte("OTTO"))
+	file := p.alloc()
+	fileKey, subtype := "FontFile2", "CIDFontType2"
+	if cff {
+		fileKey, subtype = "FontFile3", "CIDFontType0"
+		p.stream(file, "/Subtype /OpenType", f.font.data)
+	} else {
+		data := subsetFont(f.font.data, indexes)
+		if len(data) != len(f.font.data) {
+			name = subsetTag(indexes) + "+" + name
+		}
+		p.stream(file, fmt.Sprintf("/Length1 %d", len(data)), data)
+	}
+
+	metrics, _ := sf.Metrics(&buf, f.font.ppem, font.HintingNone)
+	bounds, _ := sf.Bounds(&buf, f.font.ppem, font.HintingNone)
+	italicAngle := 0.0
+	if post := sf.PostTable(); post != nil {
+		italicAngle = post.ItalicAngle
+	}
+	// symbolic, as glyphs are not accessed by a standard encoding
+	flags := 4
+	if italicAngle != 0 || f.font.italic {
+		flags |= 64
+	}
+	descriptor := p.alloc()
+	p.object(descriptor, "<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%s] /ItalicAngle %s /Ascent %s /Descent %s /CapHeight %s /StemV 80 /%s %d 0 R >>",
+		name, flags, formatNumbers(scale(bounds.Min.X), -scale(bounds.Max.Y), scale(bounds.Max.X), -scale(bounds.Min.Y)),
+		formatNumber(italicAngle), formatNumber(scale(metrics.Ascent)), formatNumber(-scale(metrics.Descent)),
+		formatNumber(scale(metrics.CapHeight)), fileKey, file)
+
+	// the widths of the glyphs shown
+	widths := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		advance, err := sf.GlyphAdvance(&buf, index, f.font.ppem, font.HintingNone)
+		if err != nil {
+			continue
+		}
+		widths = append(widths, fmt.Sprintf("%d [%s]", index, formatNumber(scale(advance))))
+	}
+
+	cidToGID := ""
+	if !cff {
+		cidToGID = " /CIDToGIDMap /Identity"
+	}
+	descendant := p.alloc()
+	p.object(descendant, "<< /Type /Font /Subtype /%s /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /W [%s]%s >>",
+		subtype, name, descriptor, strings.Join(widths, " "), cidToGID)
+
+	toUnicode := p.alloc()
+	p.stream(toUnicode, "", f.toUnicode(indexes))
+	p.object(f.id, "<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, descendant, toUnicode)
+}
+
+// toUnicode returns the CMap of the characters of glyphs.
+func (f *pdfFont) toUnicode(indexes []sfnt.GlyphIndex) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// at most 100 mappings a block
+	for start := 0; start < len(indexes); start += 100 {
+		end := start + 100
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		fmt.Fprintf(&buf, "%d beginbfchar\n", end-start)
+		for _, index := range indexes[start:end] {
+			fmt.Fprintf(&buf, "<%04x> <", uint16(index))
+			for _, unit := range utf16.Encode([]rune{f.chars[index]}) {
+				fmt.Fprintf(&buf, "%04x", unit)
+			}
+			buf.WriteString(">\n")
+		}
+		buf.WriteString("endbfchar\n")
+	}
+
+	buf.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return buf.Bytes()
+}
+
+// subsetTables are the tables of TrueType fonts embedded in a PDF.
+var subsetTables = map[string]bool{
+	"head": true,
+	"hhea": true,
+	"hmtx": true,
+	"maxp": true,
+	"loca": true,
+	"glyf": true,
+	"cvt ": true,
+	"fpgm": true,
+	"prep": true,
+	"OS/2": true,
+	"post": true,
+}
+
+// subsetFont returns a TrueType font file keeping the outlines of the
+// glyphs, and of the glyphs they are composed of, only. Glyph indexes are
+// unchanged, the other glyphs being empty. The font file is returned as
+// it is when it has no TrueType outlines or is malformed.
+func subsetFont(data []byte, glyphs []sfnt.GlyphIndex) []byte {
+	u16 := func(b []byte, offset int) int { return int(binary.BigEndian.Uint16(b[offset:])) }
+	u32 := func(b []byte, offset int) int { return int(binary.BigEndian.Uint32(b[offset:])) }
+
+	if len(data) < 12 || 12+16*u16(data, 4) > len(data) {
+		return data
+	}
+	tables := make(map[string][]byte)
+	var tags []string
+	for i := 0; i < u16(data, 4); i++ {
+		record := 12 + 16*i
+		tag := string(data[record : record+4])
+		offset, length := u32(data, record+8), u32(data, record+12)
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return data
+		}
+		if subsetTables[tag] {
+			tables[tag] = data[offset : offset+length]
+			tags = append(tags, tag)
+		}
+	}
+
+	head, maxp, loca, glyf := tables["head"], tables["maxp"], tables["loca"], tables["glyf"]
+	if len(head) < 54 || len(maxp) < 6 || glyf == nil {
+		return data
+	}
+	long := u16(head, 50) == 1
+	count := u16(maxp, 4)
+	location := func(index int) int {
+		if long {
+			return u32(loca, 4*index)
+		}
+		return 2 * u16(loca, 2*index)
+	}
+	if (long && len(loca) < 4*(count+1)) || (!long && len(loca) < 2*(count+1)) {
+		return data
+	}
+	for index := 0; index < count; index++ {
+		if location(index) > location(index+1) || location(index+1) > len(glyf) {
+			return data
+		}
+	}
+
+	// the glyphs kept, .notdef and the components of composite glyphs
+	// included
+	keep := make(map[int]bool)
+	var pending []int
+	add := func(index int) {
+		if index < count && !keep[index] {
+			keep[index] = true
+			pending = append(pending, index)
+		}
+	}
+	add(0)
+	for _, index := range glyphs {
+		add(int(index))
+	}
+	for len(pending) > 0 {
+		glyph := glyf[location(pending[0]):location(pending[0]+1)]
+		pending = pending[1:]
+		for _, component := range glyphComponents(glyph) {
+			add(component)
+		}
+	}
+
+	// the glyphs not kept become empty
+	subsetGlyf := make([]byte, 0, len(glyf)/4)
+	subsetLoca := make([]byte, len(loca))
+	setLocation := func(index, offset int) {
+		if long {
+			binary.BigEndian.PutUint32(subsetLoca[4*index:], uint32(offset))
+		} else {
+			binary.BigEndian.PutUint16(subsetLoca[2*index:], uint16(offset/2))
+		}
+	}
+	for index := 0; index < count; index++ {
+		setLocation(index, len(subsetGlyf))
+		if keep[index] {
+			subsetGlyf = append(subsetGlyf, glyf[location(index):location(index+1)]...)
+		}
+	}
+	setLocation(count, len(subsetGlyf))
+	tables["glyf"], tables["loca"] = subsetGlyf, subsetLoca
+
+	// glyphs are shown by index, an empty character map is enough, and
+	// the names of glyphs are left out
+	tables["cmap"] = emptyCmap
+	tags = append(tags, "cmap")
+	if post := tables["post"]; len(post) >= 32 {
+		post = append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(post, 0x00030000)
+		tables["post"] = post
+	}
+
+	// the checksum adjustment is computed over the whole file
+	head = append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	tables["head"] = head
+
+	sort.Strings(tags)
+	selector := 0
+	for 2<<selector <= len(tags) {
+		selector++
+	}
+	font := make([]byte, 12+16*len(tags), 12+16*len(tags)+len(data)/4)
+	copy(font, data[:4])
+	binary.BigEndian.PutUint16(font[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(font[6:], uint16(16<<selector))
+	binary.BigEndian.PutUint16(font[8:], uint16(selector))
+	binary.BigEndian.PutUint16(font[10:], uint16(16*len(tags)-16<<selector))
+
+	adjustment := 0
+	for i, tag := range tags {
+		table := tables[tag]
+		record := font[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], fontChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(font)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		if tag == "head" {
+			adjustment = len(font) + 8
+		}
+
+		font = append(font, table...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(font[adjustment:], 0xB1B0AFBA-fontChecksum(font))
+	return font
+}
+
+// glyphComponents returns the indexes of the glyphs a composite glyph of
+// the glyf table is made of, none for simple glyphs.
+func glyphComponents(glyph []byte) []int {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	var components []int
+	for offset := 10; offset+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[offset:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[offset+2:])))
+
+		// the arguments, then the scale
+		offset += 6
+		if flags&0x0001 != 0 {
+			offset += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			offset += 2
+		case flags&0x0040 != 0:
+			offset += 4
+		case flags&0x0080 != 0:
+			offset += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// emptyCmap is a character map of the Windows Unicode encoding mapping
+// no characters.
+var emptyCmap = []byte{
+	0, 0, 0, 1, // version, number of encodings
+	0, 3, 0, 1, 0, 0, 0, 12, // platform, encoding, offset
+	0, 4, 0, 24, 0, 0, // format 4, length, language
+	0, 2, 0, 2, 0, 0, 0, 0, // segments, search range, entry selector, range shift
+	0xff, 0xff, 0, 0, 0xff, 0xff, // end, padding, start
+	0, 1, 0, 0, // delta, range offset
+}
+
+// fontChecksum returns the checksum of a font table.
+func fontChecksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetTag returns the tag prefixed to the name of a font subset, six
+// uppercase letters depending on the glyphs.
+func subsetTag(glyphs []sfnt.GlyphIndex) string {
+	h := fnv.New32a()
+	for _, index := range glyphs {
+		h.Write([]byte{byte(index >> 8), byte(index)})
+	}
+	sum := h.Sum32()
+
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
@@ -0,0 +1,465 @@
+package svg
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+var pdfObjectRegex = regexp.MustCompile(`(?s)(\d+) 0 obj\n(.*?)\nendobj\n`)
+
+// pdfFile is a written PDF, with its objects and decoded streams.
+type pdfFile struct {
+	data    []byte
+	objects map[int]string
+	streams map[int]string
+}
+
+// writePDF writes documents as a PDF and reads it back, checking its
+// cross-reference table.
+func writePDF(t *testing.T, options PDFOptions, svgs ...string) *pdfFile {
+	t.Helper()
+	var roots []*Element
+	for _, svg := range svgs {
+		root, err := parse(svg, false)
+		if err != nil {
+			t.Fatalf("parse: unexpected error %v\n", err)
+		}
+		roots = append(roots, root)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePDFPages(&buf, roots, options); err != nil {
+		t.Fatalf("WritePDFPages: unexpected error %v\n", err)
+	}
+	return readPDF(t, buf.Bytes())
+}
+
+func readPDF(t *testing.T, data []byte) *pdfFile {
+	t.Helper()
+	f := &pdfFile{data: data, objects: make(map[int]string), streams: make(map[int]string)}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.7\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("readPDF: expected a PDF file\n")
+	}
+
+	for _, match := range pdfObjectRegex.FindAllSubmatchIndex(data, -1) {
+		id, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		content := string(data[match[4]:match[5]])
+		f.objects[id] = content
+
+		if i := strings.Index(content, "\nstream\n"); i >= 0 {
+			stream := strings.TrimSuffix(content[i+len("\nstream\n"):], "\nendstream")
+			if strings.Contains(content[:i], "/FlateDecode") {
+				r, err := zlib.NewReader(strings.NewReader(stream))
+				if err != nil {
+					t.Fatalf("readPDF: object %d: unexpected error %v\n", id, err)
+				}
+				decoded, _ := io.ReadAll(r)
+				stream = string(decoded)
+			}
+			f.streams[id] = stream
+		}
+	}
+
+	// every object is where the table says
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	xref, _ := strconv.Atoi(strings.Fields(string(data[start+len("startxref\n"):]))[0])
+	lines := strings.Split(string(data[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("readPDF: expected the xref table at %d\n", xref)
+	}
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	if count != len(f.objects)+1 {
+		t.Errorf("readPDF: expected %d objects, actual %d\n", count-1, len(f.objects))
+	}
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(lines[2+id][:10])
+		if !bytes.HasPrefix(data[offset:], []byte(strconv.Itoa(id)+" 0 obj\n")) {
+			t.Errorf("readPDF: object %d is not at %d\n", id, offset)
+		}
+	}
+	return f
+}
+
+// find returns the objects whose content contains a string.
+func (f *pdfFile) find(s string) []string {
+	var found []string
+	for _, content := range f.objects {
+		if strings.Contains(content, s) {
+			found = append(found, content)
+		}
+	}
+	return found
+}
+
+// content returns the decoded streams of the pages and forms.
+func (f *pdfFile) content() string {
+	var b strings.Builder
+	for id, content := range f.objects {
+		if _, ok := f.streams[id]; ok && !strings.Contains(content, "/Subtype") && !strings.Contains(content, "/Length1") {
+			b.WriteString(f.streams[id])
+		}
+		if strings.Contains(content, "/Subtype /Form") {
+			b.WriteString(f.streams[id])
+		}
+	}
+	return b.String()
+}
+
+func TestWritePDFPages(t *testing.T) {
+	var testCases = []struct {
+		svgs  []string
+		boxes []string
+	}{
+		{[]string{`<svg width="200" height="100"/>`}, []string{"[0 0 150 75]"}},
+		{[]string{`<svg width="210mm" height="297mm" viewBox="0 0 210 297"/>`}, []string{"[0 0 595.275591 841.889764]"}},
+		{[]string{`<svg width="80" height="40"/>`, `<svg viewBox="0 0 40 80"/>`}, []string{"[0 0 60 30]", "[0 0 30 60]"}},
+		{[]string{`
+			<svg width="200" height="100" viewBox="0 0 100 50"
+				xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+				xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd">
+				<sodipodi:namedview>
+					<inkscape:page x="0" y="0" width="100" height="50"/>
+					<inkscape:page x="110" y="0" width="50" height="50"/>
+					<inkscape:page x="0" y="0" width="0" height="50"/>
+				</sodipodi:namedview>
+			</svg>
+		`}, []string{"[0 0 150 75]", "[0 0 75 75]"}},
+	}
+
+	for _, test := range testCases {
+		f := writePDF(t, PDFOptions{}, test.svgs...)
+		pages := f.find("/Type /Page ")
+		if len(pages) != len(test.boxes) || len(f.find(fmt.Sprintf("/Count %d", len(test.boxes)))) != 1 {
+			t.Errorf("WritePDFPages %v: expected %d pages, actual %d\n", test.svgs, len(test.boxes), len(pages))
+			continue
+		}
+		for _, box := range test.boxes {
+			if len(f.find("/MediaBox "+box)) == 0 {
+				t.Errorf("WritePDFPages %v: expected a page %s\n", test.svgs, box)
+			}
+		}
+	}
+
+	if err := WritePDFPages(io.Discard, nil, PDFOptions{}); !errors.Is(err, ErrNoPage) {
+		t.Errorf("WritePDFPages: expected ErrNoPage, actual %v\n", err)
+	}
+}
+
+func TestWritePDFInkscapePages(t *testing.T) {
+	f := writePDF(t, PDFOptions{}, `
+		<svg width="200" height="100" viewBox="0 0 100 50"
+			xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+			xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd">
+			<sodipodi:namedview>
+				<inkscape:page x="0" y="0" width="100" height="50"/>
+				<inkscape:page x="110" y="0" width="50" height="50"/>
+			</sodipodi:namedview>
+			<rect width="10" height="10"/>
+		</svg>
+	`)
+
+	// the drawing is shared, the second page moved to its place
+	forms := f.find("/Subtype /Form")
+	if len(forms) != 1 || !strings.Contains(forms[0], "/BBox [0 0 320 100]") {
+		t.Fatalf("WritePDF: expected the drawing in a form, actual %v\n", forms)
+	}
+	content := f.content()
+	for _, s := range []string{"0.75 0 0 -0.75 0 75 cm\n/Fm1 Do", "0.75 0 0 -0.75 -165 75 cm\n/Fm1 Do", "2 0 0 2 0 0 cm", "0 0 m\n10 0 l\n10 10 l\n0 10 l\nh\nf\n"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("WritePDF: expected %q in\n%s\n", s, content)
+		}
+	}
+}
+
+func TestWritePDFShapes(t *testing.T) {
+	f := writePDF(t, PDFOptions{}, `
+		<svg width="100" height="100">
+			<rect x="10" y="10" width="20" height="20" fill="red" fill-opacity="0.5" transform="translate(5 5)"/>
+			<path d="M0 0 L10 0 L10 10 Z M2 2 L8 2 L8 8 Z" fill="#00f" fill-rule="evenodd"/>
+			<line x2="50" stroke="lime" stroke-width="3" stroke-linecap="round" stroke-linejoin="bevel" stroke-dasharray="4 2" stroke-dashoffset="-1"/>
+			<circle r="5" fill="none" stroke="rgba(0, 0, 0, 0.25)"/>
+			<rect width="5" height="5" visibility="hidden"/>
+			<g opacity="0.5"><rect width="5" height="5"/></g>
+		</svg>
+	`)
+
+	content := f.content()
+	for _, s := range []string{
+		"q\n1 0 0 1 5 5 cm\nq\n/GS1 gs\n1 0 0 rg\n10 10 m\n30 10 l\n30 30 l\n10 30 l\nh\nf\nQ\nQ\n",
+		"0 0 1 rg\n0 0 m\n10 0 l\n10 10 l\nh\n2 2 m\n8 2 l\n8 8 l\nh\nf*\n",
+		"0 1 0 RG\n3 w 1 J 2 j 4 M\n[4 2] 5 d\n0 0 m\n50 0 l\nS\n",
+		"/GS2 gs\n0 0 0 RG\n1 w 0 J 0 j 4 M\n5 0 m\n",
+		"q\n/GS3 gs /Fm1 Do\nQ\n",
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("WritePDF: expected %q in\n%s\n", s, content)
+		}
+	}
+	if strings.Count(content, "5 0 l\n5 5 l\n") != 1 {
+		t.Errorf("WritePDF: expected no hidden rect, actual\n%s\n", content)
+	}
+	for _, s := range []string{"/ca 0.5 /CA 1", "/ca 1 /CA 0.25", "/ca 0.5 /CA 0.5", "/Group << /S /Transparency >>"} {
+		if len(f.find(s)) != 1 {
+			t.Errorf("WritePDF: expected an object with %q\n", s)
+		}
+	}
+}
+
+func TestWritePDFGradients(t *testing.T) {
+	f := writePDF(t, PDFOptions{}, `
+		<svg width="100" height="100">
+			<defs>
+				<linearGradient id="linear">
+					<stop offset="0" stop-color="red"/>
+					<stop offset="0.5" stop-color="lime"/>
+					<stop offset="1" stop-color="blue"/>
+				</linearGradient>
+				<linearGradient id="repeat" href="#linear" spreadMethod="repeat" x2="0.5"/>
+				<radialGradient id="radial" gradientUnits="userSpaceOnUse" spreadMethod="reflect" cx="50" cy="50" r="10">
+					<stop offset="0" stop-color="white" stop-opacity="0"/>
+					<stop offset="1" stop-color="black"/>
+				</radialGradient>
+			</defs>
+			<rect x="10" y="10" width="20" height="10" fill="url(#linear)"/>
+			<rect width="100" height="10" fill="url(#repeat)"/>
+			<rect width="100" height="100" fill="none" stroke="url(#radial)" stroke-width="2"/>
+		</svg>
+	`)
+
+	content := f.content()
+	for _, s := range []string{"W n\n20 0 0 10 10 10 cm\n/Sh1 sh\n", "W n\n100 0 0 10 0 0 cm\n/Sh2 sh\n", "W n\n/GS1 gs\n/Sh3 sh\n"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("WritePDF: expected %q in\n%s\n", s, content)
+		}
+	}
+
+	for _, s := range []string{
+		"<< /ShadingType 2 /ColorSpace /DeviceRGB /Coords [0 0 1 0] /Domain [0 1] /Function << /FunctionType 3 /Domain [0 1] /Functions [<< /FunctionType 2 /Domain [0 1] /C0 [1 0 0] /C1 [0 1 0] /N 1 >> << /FunctionType 2 /Domain [0 1] /C0 [0 1 0] /C1 [0 0 1] /N 1 >>] /Bounds [0.5] /Encode [0 1 0 1] >> /Extend [true true] >>",
+		// repeated twice over the rect
+		"/Coords [0 0 1 0] /Domain [0 2]",
+		"/C0 [1 0 0] /C1 [0 1 0] /N 1 >> << /FunctionType 2 /Domain [0 1] /C0 [0 1 0] /C1 [0 0 1] /N 1 >>] /Bounds [0.5 1 1.5]",
+		"<< /ShadingType 3 /ColorSpace /DeviceRGB /Coords [50 50 0 50 50 72.124892] /Domain [0 7.212489]",
+		"<< /ShadingType 3 /ColorSpace /DeviceGray",
+		"/SMask << /Type /Mask /S /Luminosity",
+	} {
+		if len(f.find(s)) != 1 {
+			t.Errorf("WritePDF: expected an object with %q\n", s)
+		}
+	}
+}
+
+func TestWritePDFImages(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	src.Set(1, 0, color.NRGBA{0, 0, 255, 128})
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+	pngURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	buf.Reset()
+	jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4)), nil)
+	jpegData := buf.Bytes()
+	jpegURI := "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(jpegData)
+
+	f := writePDF(t, PDFOptions{}, `
+		<svg width="100" height="100">
+			<image x="10" y="10" width="40" height="40" href="`+pngURI+`"/>
+			<image width="40" height="20" href="`+pngURI+`" preserveAspectRatio="xMinYMin slice"/>
+			<image width="8" height="8" href="`+jpegURI+`"/>
+			<image width="8" height="8" href="missing.png"/>
+		</svg>
+	`)
+
+	images := f.find("/Subtype /Image")
+	if len(images) != 3 {
+		t.Fatalf("WritePDF: expected 3 images, actual %d\n", len(images))
+	}
+	if len(f.find("/Width 2 /Height 1 /BitsPerComponent 8 /SMask")) != 1 || len(f.find("/ColorSpace /DeviceGray /Filter /DCTDecode")) != 1 {
+		t.Errorf("WritePDF: expected a masked image and a JPEG image, actual %v\n", images)
+	}
+	for id, content := range f.objects {
+		switch {
+		case strings.Contains(content, "/Width 2 /Height 1 /BitsPerComponent 8 /ColorSpace /DeviceGray"):
+			if f.streams[id] != "\xff\x80" {
+				t.Errorf("WritePDF: expected the image mask, actual %q\n", f.streams[id])
+			}
+		case strings.Contains(content, "/Width 2 /Height 1 /BitsPerComponent 8 /SMask"):
+			if f.streams[id] != "\xff\x00\x00\x00\x00\xff" {
+				t.Errorf("WritePDF: expected the image samples, actual %q\n", f.streams[id])
+			}
+		case strings.Contains(content, "/DCTDecode"):
+			if f.streams[id] != string(jpegData) {
+				t.Errorf("WritePDF: expected the JPEG data as is\n")
+			}
+		}
+	}
+
+	content := f.content()
+	for _, s := range []string{
+		"q\n40 0 0 -20 10 40 cm /Im1 Do\nQ\n",
+		"q\n0 0 40 20 re W n\n40 0 0 -20 0 20 cm /Im1 Do\nQ\n",
+		"q\n8 0 0 -8 0 8 cm /Im2 Do\nQ\n",
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("WritePDF: expected %q in\n%s\n", s, content)
+		}
+	}
+}
+
+func TestWritePDFImageFiles(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	path := filepath.Join(t.TempDir(), "secret.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := `<svg width="10" height="10"><image width="10" height="10" href="` + filepath.ToSlash(path) + `"/></svg>`
+	for _, options := range []PDFOptions{{}, {ReadAnyFile: true}} {
+		expected := 0
+		if options.ReadAnyFile {
+			expected = 1
+		}
+		if images := writePDF(t, options, svg).find("/Subtype /Image"); len(images) != expected {
+			t.Errorf("WritePDF %+v: expected %d images, actual %d\n", options, expected, len(images))
+		}
+	}
+}
+
+func TestWritePDFText(t *testing.T) {
+	svg := `
+		<svg width="200" height="100">
+			<defs><linearGradient id="gradient"><stop offset="0" stop-color="red"/></linearGradient></defs>
+			<text x="10" y="50" font-family="Go" font-size="20" fill="red">Hi <tspan font-weight="bold" stroke="blue">there</tspan></text>
+			<text x="10" y="80" font-family="Go" font-size="20" fill="url(#gradient)">Hi</text>
+		</svg>
+	`
+
+	f := writePDF(t, PDFOptions{}, svg)
+	if content := f.content(); strings.Contains(content, "BT") || strings.Contains(content, " f\n") {
+		t.Errorf("WritePDF: expected no text without fonts, actual\n%s\n", content)
+	}
+
+	f = writePDF(t, PDFOptions{Fonts: testRegistry(t)}, svg)
+	fonts := f.find("/Subtype /Type0")
+	if len(fonts) != 2 || len(f.find("/FontFile2")) != 2 || len(f.find("/Subtype /CIDFontType2")) != 2 {
+		t.Fatalf("WritePDF: expected the regular and the bold fonts embedded, actual %v\n", fonts)
+	}
+	if len(f.find("+GoRegular")) != 3 || len(f.find("+Go-Bold")) != 3 {
+		t.Errorf("WritePDF: expected the PostScript names of subsets, actual %v\n", fonts)
+	}
+
+	// the fonts only keep the glyphs shown
+	for id, content := range f.objects {
+		if !strings.Contains(content, "/Length1") {
+			continue
+		}
+		data := []byte(f.streams[id])
+		if !strings.Contains(content, fmt.Sprintf("/Length1 %d", len(data))) || len(data) > len(goregular.TTF)/4 {
+			t.Errorf("WritePDF: expected a font subset, actual %d bytes of %d\n", len(data), len(goregular.TTF))
+		}
+		subset, err := sfnt.Parse(data)
+		if err != nil {
+			t.Errorf("WritePDF: unexpected error %v\n", err)
+			continue
+		}
+		var buf sfnt.Buffer
+		if segments, err := subset.LoadGlyph(&buf, 0, 1<<6, nil); err != nil || len(segments) == 0 {
+			t.Errorf("WritePDF: expected .notdef kept, actual %v, %v\n", segments, err)
+		}
+	}
+
+	content := f.content()
+	for _, s := range []string{"BT\n0 Tr\n/F1 20 Tf\n1 0 0 -1 10 50 Tm <", "0 0 1 RG\n1 w 0 J 0 j 4 M\nBT\n2 Tr\n/F2 20 Tf\n"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("WritePDF: expected %q in\n%s\n", s, content)
+		}
+	}
+	// "Hi there", the text painted with a gradient being outlined
+	if n := strings.Count(content, "> Tj\n"); n != 8 {
+		t.Errorf("WritePDF: expected 8 glyphs, actual %d\n", n)
+	}
+	if !strings.Contains(content, "W n\n") {
+		t.Errorf("WritePDF: expected the outlined text clipping the gradient\n")
+	}
+
+	// text can be extracted
+	var maps []string
+	for id, content := range f.objects {
+		if strings.Contains(content, "/ToUnicode") {
+			continue
+		}
+		if strings.Contains(f.streams[id], "begincmap") {
+			maps = append(maps, f.streams[id])
+		}
+	}
+	if len(maps) != 2 || !strings.Contains(strings.Join(maps, ""), "> <0074>\n") {
+		t.Errorf("WritePDF: expected the character maps, actual %v\n", maps)
+	}
+}
+
+func TestSubsetFont(t *testing.T) {
+	original, _ := sfnt.Parse(goregular.TTF)
+	var buf sfnt.Buffer
+	index := func(r rune) sfnt.GlyphIndex {
+		i, _ := original.GlyphIndex(&buf, r)
+		return i
+	}
+
+	data := subsetFont(goregular.TTF, []sfnt.GlyphIndex{index('H'), index('é')})
+	if fontChecksum(data) != 0xB1B0AFBA {
+		t.Errorf("subsetFont: expected the checksum adjusted\n")
+	}
+	subset, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatalf("subsetFont: unexpected error %v\n", err)
+	}
+
+	for _, r := range "HéZ" {
+		expected, _ := original.LoadGlyph(&buf, index(r), 1<<6, nil)
+		if r == 'Z' {
+			expected = nil
+		}
+		var subsetBuf sfnt.Buffer
+		actual, err := subset.LoadGlyph(&subsetBuf, index(r), 1<<6, nil)
+		if err != nil || fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("subsetFont %q: expected %v, actual %v, %v\n", r, expected, actual, err)
+		}
+	}
+
+	// words as arguments and a scale, bytes and x and y scales, then a
+	// two by two transform
+	composite := []byte{
+		0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x00, 0x29, 0, 5, 0, 1, 0, 2, 0x40, 0,
+		0x00, 0x60, 0, 6, 1, 2, 0x40, 0, 0x40, 0,
+		0x00, 0x80, 0, 7, 1, 2, 0x40, 0, 0, 0, 0, 0, 0x40, 0,
+	}
+	if components := glyphComponents(composite); fmt.Sprint(components) != "[5 6 7]" {
+		t.Errorf("glyphComponents: expected %v, actual %v\n", "[5 6 7]", components)
+	}
+	if components := glyphComponents(composite[:30]); fmt.Sprint(components) != "[5 6]" {
+		t.Errorf("glyphComponents: expected %v, actual %v\n", "[5 6]", components)
+	}
+
+	if malformed := goregular.TTF[:100]; !bytes.Equal(subsetFont(malformed, nil), malformed) {
+		t.Errorf("subsetFont: expected malformed data as it is\n")
+	}
+}
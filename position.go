@@ -0,0 +1,172 @@
+package svg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Position is a location in the source document. Line and Column start
+// at 1, Column and Offset are counted in bytes. Line and Column are zero
+// for documents read from a caller's xml.Decoder, whose input is not
+// seen by the package.
+type Position struct {
+	Line   int
+	Column int
+	Offset int64
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ParseError reports a malformed document, together with the location
+// where decoding stopped.
+type ParseError struct {
+	// Path locates the innermost open element, e.g. /svg/g[1]/text[2].
+	Path string
+	Position
+	Err error
+}
+
+func (err *ParseError) Error() string {
+	if err.Path == "" {
+		return fmt.Sprintf("%s: %s", err.Position, err.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", err.Position, err.Path, err.Err)
+}
+
+// Unwrap returns the underlying decoder error.
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// maxRecorderCapacity is the capacity of the recorder buffer kept once
+// the input recorded is dropped.
+const maxRecorderCapacity = 1 << 16
+
+// recorder is the byte source of a decoder. It keeps the bytes read
+// since the start of the current token so that the markup of the token
+// can be inspected, and counts the lines of the bytes it drops.
+type recorder struct {
+	r      *bufio.Reader
+	buf    []byte
+	offset int64 // input offset of buf[0]
+
+	// line of buf[0] and the input offset that line starts at
+	line      int
+	lineStart int64
+}
+
+func newRecorder(r io.Reader) *recorder {
+	return &recorder{r: bufio.NewReader(r), line: 1}
+}
+
+// continueRecorder creates a recorder of the input following the one
+// of r, e.g. once converted from another charset.
+func continueRecorder(r *recorder, next io.Reader, offset int64) *recorder {
+	line, column := r.position(offset)
+	return &recorder{r: bufio.NewReader(next), offset: offset, line: line, lineStart: offset - int64(column-1)}
+}
+
+func (r *recorder) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.buf = append(r.buf, b)
+	}
+	return b, err
+}
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// discard drops the bytes recorded before offset.
+func (r *recorder) discard(offset int64) {
+	n := offset - r.offset
+	if n <= 0 || n > int64(len(r.buf)) {
+		return
+	}
+	line, column := r.position(offset)
+	r.line, r.lineStart = line, offset-int64(column-1)
+	if rest := len(r.buf) - int(n); cap(r.buf) > maxRecorderCapacity && rest <= maxRecorderCapacity/2 {
+		// the memory of a large token is released once it was read
+		r.buf = append(make([]byte, 0, rest), r.buf[n:]...)
+	} else {
+		r.buf = append(r.buf[:0], r.buf[n:]...)
+	}
+	r.offset = offset
+}
+
+// position returns the line and column of an input offset. Offsets
+// past the recorded bytes are located at the end of them.
+func (r *recorder) position(offset int64) (line, column int) {
+	line, start := r.line, r.lineStart
+	n := offset - r.offset
+	if n > int64(len(r.buf)) {
+		n = int64(len(r.buf))
+	}
+	for i := int64(0); i < n; i++ {
+		if r.buf[i] == '\n' {
+			line++
+			start = r.offset + i + 1
+		}
+	}
+	return line, int(offset-start) + 1
+}
+
+// bytes returns the recorded input between two offsets, or nil when it
+// is not available.
+func (r *recorder) bytes(start, end int64) []byte {
+	if start < r.offset || end < start || end-r.offset > int64(len(r.buf)) {
+		return nil
+	}
+	return r.buf[start-r.offset : end-r.offset]
+}
+
+// attributePositions locates the attribute names of a start tag given
+// its markup and position.
+func attributePositions(tag []byte, start Position) map[string]Position {
+	positions := make(map[string]Position)
+	position := func(i int) Position {
+		p := Position{Line: start.Line, Column: start.Column + i, Offset: start.Offset + int64(i)}
+		for j := 0; j < i; j++ {
+			if tag[j] == '\n' {
+				p.Line++
+				p.Column = i - j
+			}
+		}
+		return p
+	}
+
+	// skip the element name
+	i := 1
+	for i < len(tag) && !isSpace(rune(tag[i])) && tag[i] != '>' && tag[i] != '/' {
+		i++
+	}
+
+	for i < len(tag) {
+		switch c := tag[i]; {
+		case c == '"' || c == '\'':
+			// skip the attribute value
+			i++
+			for i < len(tag) && tag[i] != c {
+				i++
+			}
+			i++
+
+		case isSpace(rune(c)) || c == '=' || c == '/' || c == '>':
+			i++
+
+		default:
+			name := i
+			for i < len(tag) && !isSpace(rune(tag[i])) && tag[i] != '=' {
+				i++
+			}
+			positions[string(tag[name:i])] = position(name)
+		}
+	}
+	return positions
+}
@@ -0,0 +1,111 @@
+package svg
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	svg := `<?xml version="1.0"?>
+<svg width="10">
+  <g id="a"
+     fill="red"><rect
+       x='1' y="2"/></g>
+</svg>`
+
+	root, err := parse(svg, false)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	g := root.Children[0]
+	rect := g.Children[0]
+
+	var testCases = []struct {
+		name     string
+		actual   Position
+		expected Position
+	}{
+		{"svg", root.Position, Position{Line: 2, Column: 1, Offset: 22}},
+		{"svg width", root.AttributePositions["width"], Position{Line: 2, Column: 6, Offset: 27}},
+		{"g", g.Position, Position{Line: 3, Column: 3, Offset: 41}},
+		{"g id", g.AttributePositions["id"], Position{Line: 3, Column: 6, Offset: 44}},
+		{"g fill", g.AttributePositions["fill"], Position{Line: 4, Column: 6, Offset: 56}},
+		{"rect", rect.Position, Position{Line: 4, Column: 17, Offset: 67}},
+		{"rect x", rect.AttributePositions["x"], Position{Line: 5, Column: 8, Offset: 80}},
+		{"rect y", rect.AttributePositions["y"], Position{Line: 5, Column: 14, Offset: 86}},
+	}
+
+	for _, test := range testCases {
+		if test.actual != test.expected {
+			t.Errorf("Position %s: expected %v, actual %v\n", test.name, test.expected, test.actual)
+		}
+	}
+}
+
+func TestPositionCharset(t *testing.T) {
+	// offsets count the bytes converted to UTF-8
+	svg := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<svg>\xe9\n \xe9<rect/></svg>"
+
+	root, err := parse(svg, false)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := Position{Line: 3, Column: 4, Offset: 55}
+	if actual := root.Children[0].Position; actual != expected {
+		t.Errorf("Position rect: expected %v@%d, actual %v@%d\n", expected, expected.Offset, actual, actual.Offset)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var testCases = []struct {
+		svg      string
+		path     string
+		position Position
+	}{
+		{
+			"<svg>\n  <g>\n    <rect></g>\n</svg>",
+			"/svg/g[1]/rect[1]",
+			Position{Line: 3, Column: 15, Offset: 26},
+		},
+		{
+			"<svg>\n  <g/>\n  <g>\n    <text>a & b</text>\n  </g>\n</svg>",
+			"/svg/g[2]/text[1]",
+			Position{Line: 4, Column: 14, Offset: 32},
+		},
+		{
+			"<svg>",
+			"/svg",
+			Position{Line: 1, Column: 6, Offset: 5},
+		},
+	}
+
+	for _, test := range testCases {
+		_, err := parse(test.svg, false)
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("ParseError: expected ParseError, actual %v\n", err)
+			continue
+		}
+
+		if parseErr.Path != test.path || parseErr.Position != test.position {
+			t.Errorf("ParseError: expected %v %v, actual %v %v\n", test.path, test.position, parseErr.Path, parseErr.Position)
+		}
+
+		// the reader reports the same location
+		reader := NewReader(strings.NewReader(test.svg))
+		for err == nil || err == io.EOF {
+			_, err = reader.Next()
+		}
+
+		if !errors.As(err, &parseErr) || parseErr.Path != test.path || parseErr.Position != test.position {
+			t.Errorf("ParseError: expected %v %v, actual %v\n", test.path, test.position, err)
+		}
+	}
+}
@@ -0,0 +1,646 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+// maxRasterPixels bounds the size of rasterized images.
+const maxRasterPixels = 1 << 26
+
+// rasterTolerance is the distance in pixels curves are flattened within.
+const rasterTolerance = 0.1
+
+// RasterOptions controls how a document is rasterized.
+type RasterOptions struct {
+	// Width and Height are the size of the image in pixels. When only
+	// one is set, the other follows the aspect ratio of the document.
+	// When both are zero, the size of the document is used at DPI.
+	Width, Height int
+
+	// DPI is the resolution of the document size, 96 when zero, which
+	// draws a pixel per user unit.
+	DPI float64
+
+	// Background fills the image before drawing. It is transparent when
+	// nil, white for JPEG images.
+	Background color.Color
+
+	// Fonts draws text with the outlines of its glyphs. Text is not
+	// drawn without fonts.
+	Fonts *FontRegistry
+
+	// Quality is the JPEG quality, jpeg.DefaultQuality when zero.
+	Quality int
+
+	// ReadAnyFile allows images linked to any file the process can read,
+	// by absolute paths or file URLs. By default, only files within the
+	// base directory of the document are read, which documents without
+	// one can not link.
+	ReadAnyFile bool
+}
+
+// Rasterize draws the document of the root element into an image.
+//
+// Paths and basic shapes are filled and stroked with colors and linear
+// or radial gradients, with stroke width, line joins, line caps, dashes
+// and opacities. Elements are placed by their transforms, and by the
+// viewBox and preserveAspectRatio of svg elements. Images embedded as
+// data URIs, or linked to files within the base directory of the
+// document (see ReadAnyFile), are drawn as are referenced elements of
+// use. Clip paths, masks, filters, patterns and markers are not drawn.
+func Rasterize(root *Element, options RasterOptions) (*image.RGBA, error) {
+	width, height, box := documentSize(root)
+	pixelWidth, pixelHeight, err := rasterSize(width, height, options)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, pixelWidth, pixelHeight))
+	if options.Background != nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(options.Background), image.Point{}, draw.Src)
+	}
+
+	r := &rasterizer{
+		root:   root,
+		dst:    dst,
+		z:      vector.NewRasterizer(pixelWidth, pixelHeight),
+		lookup: referenceLookup(root),
+	}
+	if options.Fonts != nil && !options.Fonts.empty() {
+		r.fonts = options.Fonts
+	}
+	if d := root.document(); d != nil {
+		r.baseDir = d.BaseDir
+	}
+	r.anyFile = options.ReadAnyFile
+	r.inliner = &useInliner{lookup: r.lookup, visiting: make(map[*Element]bool)}
+
+	// the document viewport fits the image
+	ctm := viewBoxTransform([4]float64{0, 0, width, height}, "", float64(pixelWidth), float64(pixelHeight))
+	ctm = ctm.multiply(viewBoxTransform(box, root.Attributes["preserveAspectRatio"], width, height))
+	r.draw(root, newPresentation(), ctm, [2]float64{box[2], box[3]})
+	return dst, nil
+}
+
+// WritePNG rasterizes the document of the root element as a PNG image.
+func WritePNG(w io.Writer, root *Element, options RasterOptions) error {
+	img, err := Rasterize(root, options)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// WriteJPEG rasterizes the document of the root element as a JPEG image.
+func WriteJPEG(w io.Writer, root *Element, options RasterOptions) error {
+	if options.Background == nil {
+		options.Background = color.White
+	}
+	img, err := Rasterize(root, options)
+	if err != nil {
+		return err
+	}
+
+	quality := options.Quality
+	if quality == 0 {
+		quality = jpeg.DefaultQuality
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// Rasterize draws the document into an image.
+func (d *Document) Rasterize(options RasterOptions) (*image.RGBA, error) {
+	return Rasterize(d.Root, options)
+}
+
+// WritePNG rasterizes the document as a PNG image.
+func (d *Document) WritePNG(w io.Writer, options RasterOptions) error {
+	return WritePNG(w, d.Root, options)
+}
+
+// WriteJPEG rasterizes the document as a JPEG image.
+func (d *Document) WriteJPEG(w io.Writer, options RasterOptions) error {
+	return WriteJPEG(w, d.Root, options)
+}
+
+// documentSize returns the size of a document in user units, from its
+// width and height or else its viewBox, and its viewBox, the whole
+// document when it has none.
+func documentSize(root *Element) (float64, float64, [4]float64) {
+	box, hasBox := parseViewBox(root.Attributes["viewBox"])
+	width, hasWidth := absoluteLength(root.Attributes["width"])
+	height, hasHeight := absoluteLength(root.Attributes["height"])
+	hasWidth, hasHeight = hasWidth && width > 0, hasHeight && height > 0
+
+	switch {
+	case hasWidth && hasHeight:
+	case hasBox && hasWidth:
+		height = width * box[3] / box[2]
+	case hasBox && hasHeight:
+		width = height * box[2] / box[3]
+	case hasBox:
+		width, height = box[2], box[3]
+	default:
+		// the size of replaced elements in CSS
+		if !hasWidth {
+			width = 300
+		}
+		if !hasHeight {
+			height = 150
+		}
+	}
+
+	if !hasBox {
+		box = [4]float64{0, 0, width, height}
+	}
+	return width, height, box
+}
+
+// rasterSize returns the size in pixels of an image of a document.
+func rasterSize(width, height float64, options RasterOptions) (int, int, error) {
+	dpi := options.DPI
+	if dpi <= 0 {
+		dpi = 96
+	}
+
+	pixelWidth, pixelHeight := options.Width, options.Height
+	switch {
+	case pixelWidth > 0 && pixelHeight > 0:
+	case pixelWidth > 0:
+		pixelHeight = int(math.Round(float64(pixelWidth) * height / width))
+	case pixelHeight > 0:
+		pixelWidth = int(math.Round(float64(pixelHeight) * width / height))
+	default:
+		pixelWidth = int(math.Round(width * dpi / 96))
+		pixelHeight = int(math.Round(height * dpi / 96))
+	}
+
+	if pixelWidth <= 0 || pixelHeight <= 0 || float64(pixelWidth)*float64(pixelHeight) > maxRasterPixels {
+		return 0, 0, fmt.Errorf("%dx%d: %w", pixelWidth, pixelHeight, ErrImageSize)
+	}
+	return pixelWidth, pixelHeight, nil
+}
+
+// rasterizer draws elements into an image.
+type rasterizer struct {
+	root    *Element
+	dst     *image.RGBA
+	z       *vector.Rasterizer
+	lookup  func(id string) *Element
+	inliner *useInliner
+	fonts   *FontRegistry
+	baseDir string
+	anyFile bool
+}
+
+// draw draws an element with the properties of its parent, ctm mapping
+// its user space to pixels.
+func (r *rasterizer) draw(e *Element, style presentation, ctm matrix, viewport [2]float64) {
+	if value, _ := styleProperty(e, "display"); value == "none" {
+		return
+	}
+
+	switch e.LocalName() {
+	case "text":
+		if r.fonts == nil {
+			return
+		}
+		e = (&textOutline{registry: r.fonts}).outline(e)
+	case "use":
+		if !isUse(e) {
+			return
+		}
+		group, err := r.inliner.expand(e)
+		if err != nil {
+			return
+		}
+		e = group
+	}
+
+	name := e.LocalName()
+	switch name {
+	case "svg", "g", "a", "switch", "image", "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+	default:
+		// definitions, metadata and unsupported elements
+		return
+	}
+
+	style = style.inherit(e, viewport)
+	if value, ok := e.Attributes["transform"]; ok {
+		if m, err := parseTransform(value); err == nil {
+			ctm = ctm.multiply(m)
+		}
+	}
+
+	opacity := elementOpacity(e)
+	if opacity <= 0 {
+		return
+	}
+	if opacity < 1 {
+		// the element is drawn apart, then blended
+		dst := r.dst
+		r.dst = image.NewRGBA(dst.Bounds())
+		defer func() {
+			mask := image.NewUniform(color.Alpha{uint8(math.Round(opacity * 255))})
+			draw.DrawMask(dst, dst.Bounds(), r.dst, dst.Bounds().Min, mask, image.Point{}, draw.Over)
+			r.dst = dst
+		}()
+	}
+
+	switch name {
+	case "svg":
+		if e != r.root {
+			ctm, viewport = nestedViewport(e, ctm, viewport)
+		}
+		fallthrough
+	case "g", "a":
+		for _, child := range e.Children {
+			r.draw(child, style, ctm, viewport)
+		}
+	case "switch":
+		// the first child, conditional processing is not supported
+		if len(e.Children) > 0 {
+			r.draw(e.Children[0], style, ctm, viewport)
+		}
+	case "image":
+		r.image(e, style, ctm, viewport)
+	default:
+		if p, ok := elementPath(e, viewport); ok && style.visible {
+			r.fill(p, style, ctm, viewport)
+			r.stroke(p, style, ctm, viewport)
+		}
+	}
+}
+
+// nestedViewport returns the transform and the viewport of the content
+// of an svg element within a document.
+func nestedViewport(e *Element, ctm matrix, viewport [2]float64) (matrix, [2]float64) {
+	length := func(name, fallback string, reference float64) float64 {
+		value, ok := e.Attributes[name]
+		if !ok {
+			value = fallback
+		}
+		n, _ := userLength(value, reference)
+		return n
+	}
+	x, y := length("x", "0", viewport[0]), length("y", "0", viewport[1])
+	width, height := length("width", "100%", viewport[0]), length("height", "100%", viewport[1])
+
+	ctm = ctm.multiply(translation(x, y))
+	if box, ok := parseViewBox(e.Attributes["viewBox"]); ok {
+		return ctm.multiply(viewBoxTransform(box, e.Attributes["preserveAspectRatio"], width, height)), [2]float64{box[2], box[3]}
+	}
+	return ctm, [2]float64{width, height}
+}
+
+// fill fills a shape.
+func (r *rasterizer) fill(p shapePath, style presentation, ctm matrix, viewport [2]float64) {
+	fill, ok := resolvePaint(style.fill, style.color, r.lookup, viewport)
+	scale := ctm.scale()
+	if !ok || style.fillOpacity <= 0 || scale == 0 {
+		return
+	}
+
+	var polygons [][]point
+	for _, line := range p.flatten(rasterTolerance / scale) {
+		polygons = append(polygons, line.points)
+	}
+	r.paint(polygons, fill, style.fillOpacity, p, ctm, style.fillRule == "evenodd")
+}
+
+// stroke strokes the outline of a shape.
+func (r *rasterizer) stroke(p shapePath, style presentation, ctm matrix, viewport [2]float64) {
+	stroke, ok := resolvePaint(style.stroke, style.color, r.lookup, viewport)
+	scale := ctm.scale()
+	if !ok || style.strokeOpacity <= 0 || scale == 0 {
+		return
+	}
+
+	tolerance := rasterTolerance / scale
+	polygons := strokePolygons(p.flatten(tolerance), style.strokeStyle(), tolerance)
+	r.paint(polygons, stroke, style.strokeOpacity, p, ctm, false)
+}
+
+// paint fills polygons in user space with a paint, by the nonzero rule
+// or the evenodd one. Gradients in bounding box units are relative to
+// the bounds of the shape.
+func (r *rasterizer) paint(polygons [][]point, fill paint, opacity float64, shape shapePath, ctm matrix, evenOdd bool) {
+	var src image.Image
+	if fill.gradient != nil {
+		space, ok := fill.gradient.space(shape.bounds())
+		if !ok {
+			return
+		}
+		inverse, ok := ctm.multiply(space).invert()
+		if !ok {
+			return
+		}
+		src = &gradientImage{gradient: fill.gradient, inverse: inverse, opacity: opacity}
+	} else {
+		c := fill.color
+		c.A = uint8(math.Round(float64(c.A) * opacity))
+		if c.A == 0 {
+			return
+		}
+		src = image.NewUniform(c)
+	}
+
+	first := true
+	var left, top, right, bottom float64
+	device := make([][]point, len(polygons))
+	for i, polygon := range polygons {
+		device[i] = make([]point, len(polygon))
+		for j, p := range polygon {
+			x, y := ctm.apply(p.x, p.y)
+			device[i][j] = point{x, y}
+			if first {
+				left, top, right, bottom = x, y, x, y
+				first = false
+				continue
+			}
+			left, top = minFloat(left, x), minFloat(top, y)
+			right, bottom = maxFloat(right, x), maxFloat(bottom, y)
+		}
+	}
+	if first || math.IsNaN(left+top+right+bottom) {
+		return
+	}
+
+	bounds := r.dst.Bounds()
+	rect := image.Rect(
+		int(math.Floor(math.Max(left, float64(bounds.Min.X)-1))),
+		int(math.Floor(math.Max(top, float64(bounds.Min.Y)-1))),
+		int(math.Ceil(math.Min(right, float64(bounds.Max.X)+1))),
+		int(math.Ceil(math.Min(bottom, float64(bounds.Max.Y)+1))),
+	).Intersect(bounds)
+	if rect.Empty() {
+		return
+	}
+
+	ox, oy := float64(rect.Min.X), float64(rect.Min.Y)
+	if evenOdd {
+		// vector.Rasterizer only fills by the nonzero rule
+		c := newCoverage(rect.Dx(), rect.Dy())
+		for _, polygon := range device {
+			for i, p := range polygon {
+				q := polygon[(i+1)%len(polygon)]
+				c.line(p.x-ox, p.y-oy, q.x-ox, q.y-oy)
+			}
+		}
+		draw.DrawMask(r.dst, rect, src, rect.Min, c.evenOdd(), image.Point{}, draw.Over)
+		return
+	}
+
+	r.z.Reset(rect.Dx(), rect.Dy())
+	for _, polygon := range device {
+		if len(polygon) < 2 {
+			continue
+		}
+		r.z.MoveTo(float32(polygon[0].x-ox), float32(polygon[0].y-oy))
+		for _, p := range polygon[1:] {
+			r.z.LineTo(float32(p.x-ox), float32(p.y-oy))
+		}
+		r.z.ClosePath()
+	}
+	r.z.Draw(r.dst, rect, src, rect.Min)
+}
+
+// coverage accumulates the signed area covered by closed polygons in
+// each pixel, the way vector.Rasterizer does, so that it can be turned
+// into coverage by another fill rule.
+type coverage struct {
+	width, height int
+	// area changes, summed along the rows in turn
+	acc []float64
+}
+
+func newCoverage(width, height int) *coverage {
+	return &coverage{width: width, height: height, acc: make([]float64, width*height+1)}
+}
+
+// line accumulates the area on the right of a line.
+func (c *coverage) line(ax, ay, bx, by float64) {
+	dir := 1.0
+	if ay > by {
+		dir, ax, ay, bx, by = -1, bx, by, ax, ay
+	}
+	if by-ay <= 1e-9 {
+		// horizontal lines cover nothing
+		return
+	}
+	dxdy := (bx - ax) / (by - ay)
+
+	add := func(row []float64, x int, a float64) {
+		// areas left of the image count at its left edge, areas past
+		// its right edge at the start of the next row, where they cancel
+		// out with the row
+		if x < 0 {
+			x = 0
+		} else if x > c.width {
+			x = c.width
+		}
+		row[x] += a
+	}
+
+	x := ax
+	for y := int(math.Floor(ay)); y < int(math.Ceil(by)) && y < c.height; y++ {
+		dy := math.Min(float64(y+1), by) - math.Max(float64(y), ay)
+		next := x + dy*dxdy
+		if y < 0 {
+			x = next
+			continue
+		}
+
+		row := c.acc[y*c.width:]
+		d := dy * dir
+		x0, x1 := math.Min(x, next), math.Max(x, next)
+		x0i, x1i := int(math.Floor(x0)), int(math.Ceil(x1))
+		if x1i <= x0i+1 {
+			// within a pixel, split by the middle of the line
+			middle := (x+next)/2 - float64(x0i)
+			add(row, x0i, d*(1-middle))
+			add(row, x0i+1, d*middle)
+		} else {
+			s := 1 / (x1 - x0)
+			x0f := x0 - float64(x0i)
+			a0 := s * (1 - x0f) * (1 - x0f) / 2
+			x1f := x1 - float64(x1i) + 1
+			am := s * x1f * x1f / 2
+			add(row, x0i, d*a0)
+			if x1i == x0i+2 {
+				add(row, x0i+1, d*(1-a0-am))
+			} else {
+				a1 := s * (1.5 - x0f)
+				add(row, x0i+1, d*(a1-a0))
+				for xi := x0i + 2; xi < x1i-1; xi++ {
+					add(row, xi, d*s)
+				}
+				a2 := a1 + s*float64(x1i-x0i-3)
+				add(row, x1i-1, d*(1-a2-am))
+			}
+			add(row, x1i, d*am)
+		}
+		x = next
+	}
+}
+
+// evenOdd returns the coverage by the evenodd rule, the winding number
+// of each pixel taken modulo 2.
+func (c *coverage) evenOdd() *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, c.width, c.height))
+	sum := 0.0
+	for i := range mask.Pix {
+		sum += c.acc[i]
+		a := math.Mod(math.Abs(sum), 2)
+		if a > 1 {
+			a = 2 - a
+		}
+		mask.Pix[i] = uint8(math.Round(a * 255))
+	}
+	return mask
+}
+
+// image draws an image element.
+func (r *rasterizer) image(e *Element, style presentation, ctm matrix, viewport [2]float64) {
+	if !style.visible {
+		return
+	}
+	src := r.loadImage(useHref(e))
+	if src == nil {
+		return
+	}
+
+	bounds := src.Bounds()
+	length := func(name string, reference, intrinsic float64) float64 {
+		n, ok := userLength(e.Attributes[name], reference)
+		if !ok {
+			return intrinsic
+		}
+		return n
+	}
+	x, y := length("x", viewport[0], 0), length("y", viewport[1], 0)
+	width := length("width", viewport[0], float64(bounds.Dx()))
+	height := length("height", viewport[1], float64(bounds.Dy()))
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return
+	}
+
+	box := [4]float64{float64(bounds.Min.X), float64(bounds.Min.Y), float64(bounds.Dx()), float64(bounds.Dy())}
+	preserveAspectRatio := e.Attributes["preserveAspectRatio"]
+	m := ctm.multiply(translation(x, y)).multiply(viewBoxTransform(box, preserveAspectRatio, width, height))
+
+	var options *draw.Options
+	if strings.HasSuffix(strings.TrimSpace(preserveAspectRatio), "slice") {
+		// the image is clipped to its box
+		var clip shapePath
+		clip.moveTo(x, y)
+		clip.lineTo(x+width, y)
+		clip.lineTo(x+width, y+height)
+		clip.lineTo(x, y+height)
+		clip.close()
+
+		mask := image.NewAlpha(r.dst.Bounds())
+		r.z.Reset(mask.Bounds().Dx(), mask.Bounds().Dy())
+		for _, line := range clip.transform(ctm).flatten(rasterTolerance) {
+			r.z.MoveTo(float32(line.points[0].x), float32(line.points[0].y))
+			for _, p := range line.points[1:] {
+				r.z.LineTo(float32(p.x), float32(p.y))
+			}
+			r.z.ClosePath()
+		}
+		r.z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+		options = &draw.Options{DstMask: mask}
+	}
+
+	draw.BiLinear.Transform(r.dst, f64.Aff3{m[0], m[2], m[4], m[1], m[3], m[5]}, src, bounds, draw.Over, options)
+}
+
+// loadImage decodes the raster image of a data URI or of a file, nil
+// when it can not be.
+func (r *rasterizer) loadImage(href string) image.Image {
+	data := imageData(href, r.baseDir, r.anyFile)
+	if data == nil {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// imageData reads the content of a data URI, or of a file, nil when it
+// can not be. Unless anyFile is set, files are only read within baseDir,
+// by relative references which do not leave it.
+func imageData(href, baseDir string, anyFile bool) []byte {
+	switch {
+	case strings.HasPrefix(href, "data:"):
+		_, content, err := parseDataURI(href)
+		if err != nil {
+			return nil
+		}
+		return content
+	case href == "" || strings.HasPrefix(href, "#"):
+		return nil
+	}
+
+	u, err := url.Parse(href)
+	if err != nil || (u.Scheme != "" && u.Scheme != "file") || u.Opaque != "" {
+		return nil
+	}
+	path := filepath.FromSlash(u.Path)
+	if !anyFile {
+		clean := filepath.Clean(path)
+		if baseDir == "" || u.Scheme != "" || filepath.IsAbs(path) || filepath.VolumeName(path) != "" ||
+			clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// gradientImage is the color of a gradient at each pixel.
+type gradientImage struct {
+	gradient *gradient
+	// inverse maps pixels to gradient coordinates.
+	inverse matrix
+	opacity float64
+}
+
+func (g *gradientImage) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+func (g *gradientImage) Bounds() image.Rectangle {
+	return image.Rect(-1<<30, -1<<30, 1<<30, 1<<30)
+}
+
+func (g *gradientImage) At(x, y int) color.Color {
+	gx, gy := g.inverse.apply(float64(x)+0.5, float64(y)+0.5)
+	c := g.gradient.colorAt(g.gradient.offset(gx, gy))
+	c.A = uint8(math.Round(float64(c.A) * g.opacity))
+	return c
+}
@@ -0,0 +1,375 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rasterize parses and rasterizes a document, failing the test on errors.
+func rasterize(t *testing.T, svg string, options RasterOptions) *image.RGBA {
+	t.Helper()
+	root, err := parse(svg, false)
+	if err != nil {
+		t.Fatalf("parse: unexpected error %v\n", err)
+	}
+	img, err := Rasterize(root, options)
+	if err != nil {
+		t.Fatalf("Rasterize: unexpected error %v\n", err)
+	}
+	return img
+}
+
+// checkPixels compares the colors of pixels, allowing for rounding.
+func checkPixels(t *testing.T, name string, img *image.RGBA, expected map[image.Point]color.RGBA) {
+	t.Helper()
+	near := func(a, b uint8) bool {
+		return int(a)-int(b) <= 2 && int(b)-int(a) <= 2
+	}
+	for p, c := range expected {
+		actual := img.RGBAAt(p.X, p.Y)
+		if !near(actual.R, c.R) || !near(actual.G, c.G) || !near(actual.B, c.B) || !near(actual.A, c.A) {
+			t.Errorf("%s %v: expected %v, actual %v\n", name, p, c, actual)
+		}
+	}
+}
+
+var (
+	transparent = color.RGBA{}
+	red         = color.RGBA{255, 0, 0, 255}
+	blue        = color.RGBA{0, 0, 255, 255}
+)
+
+func TestRasterizeSize(t *testing.T) {
+	var testCases = []struct {
+		svg           string
+		options       RasterOptions
+		width, height int
+	}{
+		{`<svg width="20" height="10"/>`, RasterOptions{}, 20, 10},
+		{`<svg width="20" height="10"/>`, RasterOptions{DPI: 192}, 40, 20},
+		{`<svg width="1in" height="0.5in"/>`, RasterOptions{}, 96, 48},
+		{`<svg viewBox="0 0 40 20"/>`, RasterOptions{}, 40, 20},
+		{`<svg viewBox="0 0 40 20" width="80"/>`, RasterOptions{}, 80, 40},
+		{`<svg viewBox="0 0 40 20"/>`, RasterOptions{Width: 10}, 10, 5},
+		{`<svg viewBox="0 0 40 20"/>`, RasterOptions{Height: 10}, 20, 10},
+		{`<svg viewBox="0 0 40 20"/>`, RasterOptions{Width: 7, Height: 9}, 7, 9},
+		{`<svg/>`, RasterOptions{}, 300, 150},
+	}
+
+	for _, test := range testCases {
+		img := rasterize(t, test.svg, test.options)
+		if size := img.Bounds().Size(); size.X != test.width || size.Y != test.height {
+			t.Errorf("Rasterize %s %+v: expected %dx%d, actual %v\n", test.svg, test.options, test.width, test.height, size)
+		}
+	}
+
+	for _, options := range []RasterOptions{{DPI: 1}, {Width: 1 << 14, Height: 1 << 14}} {
+		root, _ := parse(`<svg width="20" height="10"/>`, false)
+		if _, err := Rasterize(root, options); !errors.Is(err, ErrImageSize) {
+			t.Errorf("Rasterize %+v: expected ErrImageSize, actual %v\n", options, err)
+		}
+	}
+}
+
+func TestRasterizeShapes(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="40" height="40" viewBox="0 0 20 20">
+			<rect x="1" y="1" width="4" height="4" fill="red"/>
+			<g transform="translate(10 0)">
+				<circle cx="3" cy="3" r="2" fill="#00f"/>
+			</g>
+			<path d="M1 11 H9 V19" fill="none" stroke="red" stroke-width="2"/>
+			<rect x="12" y="12" width="6" height="6" fill="red" display="none"/>
+			<rect x="12" y="12" width="6" height="6" fill="red" visibility="hidden"/>
+			<defs><rect x="12" y="12" width="6" height="6" fill="red"/></defs>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		// the viewBox is scaled twice
+		{5, 5}:   red,
+		{1, 1}:   transparent,
+		{11, 11}: transparent,
+		{26, 6}:  blue,
+		{20, 2}:  transparent,
+		{10, 21}: red,
+		{17, 30}: red,
+		{10, 30}: transparent,
+		{30, 30}: transparent,
+	})
+}
+
+func TestRasterizeFillRule(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="100" height="200">
+			<path d="M10 10H90V90H10Z M30 30H70V70H30Z" fill="red" fill-rule="evenodd"/>
+			<path d="M10 110H90V190H10Z M30 130H70V170H30Z" style="fill:blue;fill-rule:nonzero"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{20, 20}:  red,
+		{50, 50}:  transparent,
+		{69, 50}:  transparent,
+		{71, 50}:  red,
+		{95, 50}:  transparent,
+		{50, 150}: blue,
+	})
+
+	// a star crossing itself, its center wound twice
+	img = rasterize(t, `
+		<svg width="100" height="100">
+			<polygon points="50,0 79,90 2,35 98,35 21,90" fill="red" fill-rule="evenodd"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{50, 10}: red,
+		{50, 50}: transparent,
+		{10, 38}: red,
+		{50, 95}: transparent,
+	})
+
+	// edges between pixels are antialiased
+	img = rasterize(t, `
+		<svg width="10" height="10">
+			<rect x="2.5" y="2" width="5" height="6" fill="red" fill-rule="evenodd"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{2, 5}: {128, 0, 0, 128},
+		{5, 5}: red,
+		{7, 5}: {128, 0, 0, 128},
+		{8, 5}: transparent,
+	})
+}
+
+func TestRasterizeStroke(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="40" height="30">
+			<line x1="5" y1="5" x2="35" y2="5" stroke="red" stroke-width="4" stroke-dasharray="10"/>
+			<line x1="5" y1="15" x2="35" y2="15" stroke="blue" stroke-width="4" stroke-linecap="square"/>
+			<line x1="5" y1="25" x2="35" y2="25" stroke="blue" stroke-width="4"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{10, 5}:  red,
+		{20, 5}:  transparent,
+		{30, 5}:  red,
+		{10, 7}:  transparent,
+		{3, 15}:  blue,
+		{36, 15}: blue,
+		{3, 25}:  transparent,
+		{36, 25}: transparent,
+	})
+}
+
+func TestRasterizeOpacity(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="20" height="10">
+			<rect width="10" height="10" fill="red" fill-opacity="0.5"/>
+			<g opacity="0.5">
+				<rect x="10" width="10" height="10" fill="red"/>
+				<rect x="10" width="10" height="10" fill="blue"/>
+			</g>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		// premultiplied colors
+		{5, 5}: {128, 0, 0, 128},
+		// the group is blended once, the red hidden under the blue
+		{15, 5}: {0, 0, 128, 128},
+	})
+}
+
+func TestRasterizeGradient(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="100" height="20">
+			<defs>
+				<linearGradient id="linear">
+					<stop offset="0" stop-color="red"/>
+					<stop offset="1" stop-color="blue"/>
+				</linearGradient>
+				<radialGradient id="radial" gradientUnits="userSpaceOnUse" cx="50" cy="15" r="5">
+					<stop offset="0" stop-color="blue"/>
+					<stop offset="1" stop-color="red"/>
+				</radialGradient>
+			</defs>
+			<rect width="100" height="10" fill="url(#linear)"/>
+			<rect y="10" width="100" height="10" fill="url(#radial)"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{0, 5}:   {254, 0, 1, 255},
+		{49, 5}:  {128, 0, 127, 255},
+		{99, 5}:  {1, 0, 254, 255},
+		{49, 14}: {36, 0, 219, 255},
+		{10, 15}: red,
+	})
+}
+
+func TestRasterizeImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	img := rasterize(t, `
+		<svg width="40" height="40">
+			<image width="40" height="20" preserveAspectRatio="none" href="`+uri+`"/>
+			<image y="20" width="20" height="20" href="`+uri+`"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{2, 10}:  red,
+		{38, 10}: blue,
+		// the image is centered, 2 by 1
+		{10, 22}: transparent,
+		{1, 30}:  red,
+		{18, 30}: blue,
+		{30, 30}: transparent,
+	})
+}
+
+func TestRasterizeImageFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "templates")
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	src.SetRGBA(0, 0, red)
+	for _, path := range []string{filepath.Join(base, "my image.png"), filepath.Join(dir, "secret.png")} {
+		var buf bytes.Buffer
+		png.Encode(&buf, src)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secret := filepath.ToSlash(filepath.Join(dir, "secret.png"))
+	var testCases = []struct {
+		href    string
+		options RasterOptions
+		drawn   bool
+	}{
+		{"my%20image.png", RasterOptions{}, true},
+		{"./sub/../my%20image.png", RasterOptions{}, true},
+		{"../secret.png", RasterOptions{}, false},
+		{secret, RasterOptions{}, false},
+		{"file://" + secret, RasterOptions{}, false},
+		{secret, RasterOptions{ReadAnyFile: true}, true},
+		{"file://" + secret, RasterOptions{ReadAnyFile: true}, true},
+	}
+
+	for _, test := range testCases {
+		root, _ := parse(`<svg width="10" height="10"><image width="10" height="10" href="`+test.href+`"/></svg>`, false)
+		doc := NewDocument(root)
+		doc.BaseDir = base
+		img, err := doc.Rasterize(test.options)
+		if err != nil {
+			t.Fatalf("Rasterize: unexpected error %v\n", err)
+		}
+		if drawn := img.RGBAAt(5, 5) == red; drawn != test.drawn {
+			t.Errorf("Rasterize %s %+v: expected drawn %v, actual %v\n", test.href, test.options, test.drawn, drawn)
+		}
+	}
+
+	// no file is read without a base directory
+	href := strings.TrimPrefix(filepath.ToSlash(filepath.Join(base, "my%20image.png")), "/")
+	img := rasterize(t, `<svg width="10" height="10"><image width="10" height="10" href="`+href+`"/></svg>`, RasterOptions{})
+	if img.RGBAAt(5, 5) == red {
+		t.Errorf("Rasterize %s: expected no image without a base directory\n", href)
+	}
+}
+
+func TestRasterizeUse(t *testing.T) {
+	img := rasterize(t, `
+		<svg width="30" height="10" xmlns:xlink="http://www.w3.org/1999/xlink">
+			<defs>
+				<rect id="box" width="10" height="10"/>
+			</defs>
+			<use xlink:href="#box" fill="red"/>
+			<use href="#box" x="20" fill="blue"/>
+		</svg>
+	`, RasterOptions{})
+
+	checkPixels(t, "Rasterize", img, map[image.Point]color.RGBA{
+		{5, 5}:  red,
+		{15, 5}: transparent,
+		{25, 5}: blue,
+	})
+}
+
+func TestRasterizeText(t *testing.T) {
+	svg := `
+		<svg width="100" height="40">
+			<text x="0" y="30" font-family="Go" font-size="40" fill="red">HHH</text>
+		</svg>
+	`
+
+	count := func(img *image.RGBA) int {
+		n := 0
+		for i := 3; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(rasterize(t, svg, RasterOptions{})); n != 0 {
+		t.Errorf("Rasterize: expected no text without fonts, actual %d pixels\n", n)
+	}
+	if n := count(rasterize(t, svg, RasterOptions{Fonts: testRegistry(t)})); n == 0 {
+		t.Errorf("Rasterize: expected text drawn with fonts\n")
+	}
+}
+
+func TestWriteImage(t *testing.T) {
+	doc, _ := parse(`<svg width="4" height="2"><rect width="2" height="2" fill="red"/></svg>`, false)
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, doc, RasterOptions{}); err != nil {
+		t.Fatalf("WritePNG: unexpected error %v\n", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode: unexpected error %v\n", err)
+	}
+	if r, _, _, a := img.At(1, 1).RGBA(); r != 0xffff || a != 0xffff {
+		t.Errorf("WritePNG: expected red, actual %v\n", img.At(1, 1))
+	}
+	if _, _, _, a := img.At(3, 1).RGBA(); a != 0 {
+		t.Errorf("WritePNG: expected transparent, actual %v\n", img.At(3, 1))
+	}
+
+	buf.Reset()
+	if err := WriteJPEG(&buf, doc, RasterOptions{Quality: 100}); err != nil {
+		t.Fatalf("WriteJPEG: unexpected error %v\n", err)
+	}
+	img, err = jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("jpeg.Decode: unexpected error %v\n", err)
+	}
+	if size := img.Bounds().Size(); size.X != 4 || size.Y != 2 {
+		t.Errorf("WriteJPEG: expected 4x2, actual %v\n", size)
+	}
+	// the background is white
+	if r, g, b, _ := img.At(3, 1).RGBA(); r < 0xf000 || g < 0xf000 || b < 0xf000 {
+		t.Errorf("WriteJPEG: expected white, actual %v\n", img.At(3, 1))
+	}
+}
@@ -0,0 +1,298 @@
+package svg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/galihrivanto/svg/utils"
+)
+
+// Reference is a link from an element to another one by id, through a
+// href attribute or a url(#id) value such as fill="url(#grad)".
+type Reference struct {
+	// Element holds the reference in Attribute. Property is set when the
+	// reference is a property of the style attribute.
+	Element   *Element
+	Attribute string
+	Property  string
+
+	ID string
+	// Target is the referenced element, nil when it does not exist.
+	Target *Element
+}
+
+// References returns the references held by the element and its
+// descendants, in document order. Targets are looked up in the whole
+// tree the element belongs to.
+func (e *Element) References() []Reference {
+	lookup := referenceLookup(e)
+
+	var references []Reference
+	e.Walk(func(el *Element, depth int) WalkAction {
+		for _, r := range elementReferences(el) {
+			r.Target = lookup(r.ID)
+			references = append(references, r)
+		}
+		return WalkContinue
+	})
+	return references
+}
+
+// DanglingReferences returns the references of the element and its
+// descendants whose target does not exist.
+func (e *Element) DanglingReferences() []Reference {
+	var dangling []Reference
+	for _, r := range e.References() {
+		if r.Target == nil {
+			dangling = append(dangling, r)
+		}
+	}
+	return dangling
+}
+
+// elementReferences returns the references of a single element, sorted
+// by attribute.
+func elementReferences(e *Element) []Reference {
+	var references []Reference
+	for _, name := range sortedKeys(e.Attributes) {
+		value := e.Attributes[name]
+		if isHref(name) {
+			if strings.HasPrefix(value, "#") && len(value) > 1 {
+				references = append(references, Reference{Element: e, Attribute: name, ID: value[1:]})
+			}
+			continue
+		}
+
+		if name == "style" {
+			for _, style := range utils.StyleParser(value) {
+				for _, id := range urlReferences(style.Value) {
+					references = append(references, Reference{Element: e, Attribute: name, Property: style.Property, ID: id})
+				}
+			}
+			continue
+		}
+
+		for _, id := range urlReferences(value) {
+			references = append(references, Reference{Element: e, Attribute: name, ID: id})
+		}
+	}
+	return references
+}
+
+// urlReferences returns the ids referenced by url(#id) in a value.
+func urlReferences(value string) []string {
+	if !strings.Contains(value, "url(") {
+		return nil
+	}
+
+	var ids []string
+	for _, match := range urlReference.FindAllStringSubmatch(value, -1) {
+		ids = append(ids, match[2])
+	}
+	return ids
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// referenceLookup returns a function finding elements by id in the tree
+// the element belongs to, using the document index when there is one.
+func referenceLookup(e *Element) func(id string) *Element {
+	if d := e.document(); d != nil {
+		return d.ElementByID
+	}
+
+	root := e
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+
+	ids := collectIDs(root)
+	return func(id string) *Element {
+		return ids[id]
+	}
+}
+
+// InlineUses replaces the <use> elements within the element by groups
+// holding a copy of the element they reference, translated by the x and
+// y of the use, the way SVG renders them. Referenced symbols become
+// nested svg elements. Ids within the copies are prefixed with the id
+// of the use, or a generated one, to keep them unique.
+//
+// Nothing is changed when a use references a missing element or itself.
+// Uses referencing other documents are kept.
+func (e *Element) InlineUses() error {
+	inliner := &useInliner{lookup: referenceLookup(e), visiting: make(map[*Element]bool)}
+
+	var uses []*Element
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if isUse(el) {
+			uses = append(uses, el)
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+
+	if len(uses) > 0 && uses[0].Parent() == nil {
+		// a use without parent can not be replaced
+		return ErrNotChild
+	}
+
+	groups := make([]*Element, len(uses))
+	for i, use := range uses {
+		group, err := inliner.expand(use)
+		if err != nil {
+			return err
+		}
+
+		prefix := use.Attributes["id"]
+		if prefix == "" {
+			inliner.generated++
+			prefix = "use" + strconv.Itoa(inliner.generated)
+		}
+		prefixIDs(group.Children[0], prefix+"-")
+		groups[i] = group
+	}
+
+	for i, use := range uses {
+		if err := use.ReplaceWith(groups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isUse reports whether the element is a use referencing an element of
+// the same document.
+func isUse(e *Element) bool {
+	return e.LocalName() == "use" && strings.HasPrefix(useHref(e), "#")
+}
+
+func useHref(e *Element) string {
+	if href, ok := e.Attributes["href"]; ok {
+		return href
+	}
+	return e.Attributes["xlink:href"]
+}
+
+type useInliner struct {
+	lookup func(id string) *Element
+	// referenced elements being expanded, to detect cycles
+	visiting  map[*Element]bool
+	generated int
+}
+
+// expand returns the group replacing a use. The tree is not changed.
+func (u *useInliner) expand(use *Element) (*Element, error) {
+	id := useHref(use)[1:]
+	target := u.lookup(id)
+	if target == nil {
+		return nil, fmt.Errorf("use %q: %w", "#"+id, ErrDanglingRef)
+	}
+	if u.visiting[target] || target.contains(use) {
+		return nil, fmt.Errorf("use %q: %w", "#"+id, ErrReferenceCycle)
+	}
+
+	u.visiting[target] = true
+	defer delete(u.visiting, target)
+
+	content := target.Clone()
+	if isUse(content) {
+		expanded, err := u.expand(content)
+		if err != nil {
+			return nil, err
+		}
+		content = expanded
+	} else if err := u.expandWithin(content); err != nil {
+		return nil, err
+	}
+
+	group := &Element{
+		Name:       "g",
+		Space:      use.Space,
+		Namespaces: use.Namespaces,
+		Attributes: make(map[string]string),
+	}
+	if prefix := use.Prefix(); prefix != "" {
+		group.Name = prefix + ":g"
+	}
+
+	x, y := useCoordinate(use, "x"), useCoordinate(use, "y")
+	for name, value := range use.Attributes {
+		switch name {
+		case "x", "y", "width", "height", "href", "xlink:href", "transform":
+		default:
+			group.Attributes[name] = value
+		}
+	}
+
+	var transform []string
+	if t := strings.TrimSpace(use.Attributes["transform"]); t != "" {
+		transform = append(transform, t)
+	}
+	if x != 0 || y != 0 {
+		transform = append(transform, fmt.Sprintf("translate(%s %s)", formatNumber(x), formatNumber(y)))
+	}
+	if len(transform) > 0 {
+		group.Attributes["transform"] = strings.Join(transform, " ")
+	}
+
+	switch content.LocalName() {
+	case "symbol":
+		content.Name = strings.TrimSuffix(content.Name, "symbol") + "svg"
+		content.Attributes["width"] = "100%"
+		content.Attributes["height"] = "100%"
+		fallthrough
+	case "svg":
+		for _, name := range []string{"width", "height"} {
+			if value, ok := use.Attributes[name]; ok {
+				content.Attributes[name] = value
+			}
+		}
+	}
+
+	group.Children = []*Element{content}
+	content.parent = group
+	return group, nil
+}
+
+// expandWithin replaces the uses within a copied element.
+func (u *useInliner) expandWithin(e *Element) error {
+	var uses []*Element
+	e.Walk(func(el *Element, depth int) WalkAction {
+		if el != e && isUse(el) {
+			uses = append(uses, el)
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+
+	for _, use := range uses {
+		group, err := u.expand(use)
+		if err != nil {
+			return err
+		}
+		if err := use.ReplaceWith(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// useCoordinate returns the x or y of a use, 0 when it is not a number.
+func useCoordinate(use *Element, name string) float64 {
+	value := strings.TrimSuffix(strings.TrimSpace(use.Attributes[name]), "px")
+	n, err := parseNumber(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	svg := `<svg>
+		<defs>
+			<linearGradient id="grad"/>
+			<clipPath id="clip"/>
+			<marker id="arrow"/>
+		</defs>
+		<rect id="r" fill="url(#grad)" clip-path="url('#clip')" mask="url(#missing)"/>
+		<path id="p" style="stroke:url(#grad);marker-end:url(#arrow)" marker-start="url(#arrow)"/>
+		<use id="u" xlink:href="#r"/>
+		<a href="#nowhere"/>
+		<image href="photo.png"/>
+	</svg>`
+	root, _ := parse(svg, false)
+
+	var actual []string
+	for _, r := range root.References() {
+		target := "<nil>"
+		if r.Target != nil {
+			target = r.Target.Name
+		}
+		actual = append(actual, fmt.Sprintf("%s %s %s #%s %s", r.Element.Attributes["id"], r.Attribute, r.Property, r.ID, target))
+	}
+
+	expected := []string{
+		"r clip-path  #clip clipPath",
+		"r fill  #grad linearGradient",
+		"r mask  #missing <nil>",
+		"p marker-start  #arrow marker",
+		"p style stroke #grad linearGradient",
+		"p style marker-end #arrow marker",
+		"u xlink:href  #r rect",
+		" href  #nowhere <nil>",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("References: expected %v, actual %v\n", expected, actual)
+	}
+
+	dangling := root.DanglingReferences()
+	if len(dangling) != 2 || dangling[0].ID != "missing" || dangling[1].ID != "nowhere" {
+		t.Errorf("DanglingReferences: expected %v, actual %v\n", "missing nowhere", dangling)
+	}
+
+	// targets are looked up in the whole tree
+	rect := root.FindID("r")
+	if refs := rect.References(); len(refs) != 3 || refs[0].Target != root.FindID("clip") {
+		t.Errorf("References: expected targets outside of the element, actual %v\n", refs)
+	}
+}
+
+func TestInlineUses(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><g id="card"><rect id="bg" fill="url(#grad)"/><use xlink:href="#dot" x="1"/></g><circle id="dot" r="1"/><symbol id="sym" viewBox="0 0 10 10"><path d="M0 0"/></symbol><linearGradient id="grad"/></defs><use id="u1" xlink:href="#card" x="10" y="20" fill="red"/><use href="#sym" width="5" height="5" transform="scale(2)"/><use xlink:href="other.svg#x"/></svg>`
+
+	doc, err := ParseDocument(strings.NewReader(svg), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := doc.Root
+
+	if err := root.InlineUses(); err != nil {
+		t.Fatalf("InlineUses: unexpected error %v\n", err)
+	}
+
+	expected := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><g id="card"><rect fill="url(#grad)" id="bg"></rect><g transform="translate(1 0)"><circle id="use1-dot" r="1"></circle></g></g><circle id="dot" r="1"></circle><symbol id="sym" viewBox="0 0 10 10"><path d="M0 0"></path></symbol><linearGradient id="grad"></linearGradient></defs>` +
+		`<g fill="red" id="u1" transform="translate(10 20)"><g id="u1-card"><rect fill="url(#grad)" id="u1-bg"></rect><g transform="translate(1 0)"><circle id="u1-dot" r="1"></circle></g></g></g>` +
+		`<g transform="scale(2)"><svg height="5" id="use2-sym" viewBox="0 0 10 10" width="5"><path d="M0 0"></path></svg></g>` +
+		`<use xlink:href="other.svg#x"></use></svg>`
+
+	SetSortAttributes(true)
+	if actual, _ := render(root); actual != expected {
+		t.Errorf("InlineUses: expected %v, actual %v\n", expected, actual)
+	}
+
+	if doc.ElementByID("u1-bg") == nil || doc.ElementByID("u1").Name != "g" {
+		t.Errorf("InlineUses: expected document index updated\n")
+	}
+}
+
+func TestInlineUsesErrors(t *testing.T) {
+	var testCases = []struct {
+		svg string
+		err error
+	}{
+		{`<svg><use href="#missing"/></svg>`, ErrDanglingRef},
+		{`<svg><g id="a"><use href="#a"/></g></svg>`, ErrReferenceCycle},
+		{`<svg><use id="a" href="#b"/><use id="b" href="#a"/></svg>`, ErrReferenceCycle},
+	}
+
+	for _, test := range testCases {
+		root, _ := parse(test.svg, false)
+		before, _ := render(root)
+
+		err := root.InlineUses()
+		if !errors.Is(err, test.err) {
+			t.Errorf("InlineUses: expected %v, actual %v\n", test.err, err)
+		}
+		if after, _ := render(root); after != before {
+			t.Errorf("InlineUses: expected unchanged tree, actual %v\n", after)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 )
 
 var sortAttributes = false
@@ -15,6 +16,13 @@ func SetSortAttributes(v bool) {
 	sortAttributes = v
 }
 
+// sortAttrs sorts attributes by name.
+func sortAttrs(attributes []xml.Attr) {
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Name.Local < attributes[j].Name.Local
+	})
+}
+
 // Serialize serializes element
 func (e *Element) Serialize() xml.StartElement {
 	var attributes []xml.Attr
@@ -53,40 +61,211 @@ func (e *Element) Serialize() xml.StartElement {
 	}
 }
 
-// Encode encodes the element
+// serializer writes elements and nodes with an xml.Encoder. w is the
+// writer of the encoder, nil when it is not known.
+type serializer struct {
+	*xml.Encoder
+	w io.Writer
+
+	// indent of the encoder, suspended within mixed content
+	prefix, indent string
+	suspended      bool
+}
+
+// Indent sets the indent of the encoder.
+func (s *serializer) Indent(prefix, indent string) {
+	s.prefix, s.indent = prefix, indent
+	s.Encoder.Indent(prefix, indent)
+}
+
+// suspend turns the indent off, returning false when it is not on.
+func (s *serializer) suspend() bool {
+	if s.suspended || (s.prefix == "" && s.indent == "") {
+		return false
+	}
+	s.suspended = true
+	s.Encoder.Indent("", "")
+	return true
+}
+
+// resume turns the indent back on.
+func (s *serializer) resume() {
+	s.suspended = false
+	s.Encoder.Indent(s.prefix, s.indent)
+}
+
+// Encode encodes the element. Namespaces used by the element and its
+// descendants are declared where they are not declared by an ancestor.
 func (e *Element) Encode(encoder *xml.Encoder) error {
-	start := e.Serialize()
+	return e.encode(&serializer{Encoder: encoder}, nil)
+}
 
-	if err := encoder.EncodeToken(start); err != nil {
+func (e *Element) encode(s *serializer, scope namespaceScope) error {
+	if err := encodeNodes(s, e.Prolog, false); err != nil {
+		return err
+	}
+
+	start, scope := scope.startElement(e)
+
+	if err := s.EncodeToken(start); err != nil {
 		return err
 	}
 	end := start.End()
 
-	var content xml.Token
+	// the indent would add white space to text, the tags of the
+	// element are indented but not what they enclose
+	suspended := e.mixed() && s.suspend()
+	if err := e.encodeContent(s, scope); err != nil {
+		return err
+	}
+	if suspended {
+		s.resume()
+	}
+
+	if err := s.EncodeToken(end); err != nil {
+		return err
+	}
+
+	return encodeNodes(s, e.Epilog, true)
+}
+
+// encodeNodes writes document level nodes, each on its own line. Line
+// breaks are written before the nodes which follow the root element.
+func encodeNodes(s *serializer, nodes []*Node, epilog bool) error {
+	newline := xml.CharData("\n")
+	for _, node := range nodes {
+		if epilog {
+			if err := s.EncodeToken(newline); err != nil {
+				return err
+			}
+		}
+		if err := node.encode(s); err != nil {
+			return err
+		}
+		if !epilog {
+			if err := s.EncodeToken(newline); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeContent writes content and children of the element. Text,
+// comments, processing instructions and CDATA sections are written in
+// document order when the element keeps them in Nodes.
+func (e *Element) encodeContent(s *serializer, scope namespaceScope) error {
+	if len(e.Nodes) == 0 {
+		s.EncodeToken(xml.CharData(e.Content))
+		return e.encodeChildren(s, scope, 0)
+	}
+
+	// when Content was replaced, the new text is written in place of
+	// the first non-blank text node and the other text is dropped
+	replaced := e.Content != e.text()
+	content := -1
+	if replaced {
+		for i, node := range e.Nodes {
+			if node.isText() && !node.isBlank() {
+				content = i
+				break
+			}
+		}
+
+		if content < 0 {
+			s.EncodeToken(xml.CharData(e.Content))
+		}
+	}
+
+	// white space between children is only kept where it may be
+	// significant, otherwise it would add up with the encoder indent
+	keepBlank := e.Content != "" || e.Attributes["xml:space"] == "preserve" || textElements[e.Name]
 
-	content = xml.CharData(e.Content)
-	encoder.EncodeToken(content)
+	next := 0
+	for i, node := range e.Nodes {
+		switch {
+		case node.Type == ElementNode:
+			if next < len(e.Children) {
+				if err := e.Children[next].encode(s, scope); err != nil {
+					return err
+				}
+				next++
+			}
+
+		case i == content:
+			data := &Node{Type: node.Type, Data: e.Content}
+			if err := data.encode(s); err != nil {
+				return err
+			}
 
-	for _, child := range e.Children {
-		if err := child.Encode(encoder); err != nil {
+		case node.isBlank():
+			if keepBlank {
+				if err := node.encode(s); err != nil {
+					return err
+				}
+			}
+
+		case node.isText() && replaced:
+			// superseded by Content
+
+		default:
+			if err := node.encode(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	// children added without a matching node
+	return e.encodeChildren(s, scope, next)
+}
+
+// mixed reports whether the element has text, or may have significant
+// white space, among its children.
+func (e *Element) mixed() bool {
+	if len(e.Children) == 0 {
+		return false
+	}
+	if textElements[e.Name] || e.Attributes["xml:space"] == "preserve" || strings.TrimSpace(e.Content) != "" {
+		return true
+	}
+	for _, node := range e.Nodes {
+		if node.isText() && !node.isBlank() {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeChildren writes children of the element starting at index.
+func (e *Element) encodeChildren(s *serializer, scope namespaceScope, index int) error {
+	for _, child := range e.Children[index:] {
+		if err := child.encode(s, scope); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	return encoder.EncodeToken(end)
+// elements whose content is rendered text
+var textElements = map[string]bool{
+	"text":     true,
+	"tspan":    true,
+	"textPath": true,
 }
 
-// Render renders element to SVG
+// Render renders element to SVG. Output is indented unless the first of
+// vars is false; elements with text among their children are written
+// without indentation within them, which would change the text.
 func Render(e *Element, w io.Writer, vars ...bool) error {
-	encoder := xml.NewEncoder(w)
+	s := &serializer{Encoder: xml.NewEncoder(w), w: w}
 
 	if len(vars) == 0 || vars[0] == true {
-		encoder.Indent("", "  ")
+		s.Indent("", "  ")
 	}
 
-	if err := e.Encode(encoder); err != nil {
+	if err := e.encode(s, nil); err != nil {
 		return fmt.Errorf("Could not render element: %s", err)
 	}
 
-	return encoder.Flush()
+	return s.Flush()
 }
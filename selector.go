@@ -0,0 +1,829 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SelectorError describes a malformed CSS selector.
+type SelectorError struct {
+	Selector string
+	Offset   int
+	Msg      string
+}
+
+func (err *SelectorError) Error() string {
+	return fmt.Sprintf("invalid selector %q at offset %d: %s", err.Selector, err.Offset, err.Msg)
+}
+
+// Selector is a compiled group of CSS level 3 selectors.
+//
+// Namespaced names may be written in the CSS form, inkscape|label, or
+// as they appear in the source, inkscape:label, for attributes and
+// sodipodi\:namedview for elements.
+type Selector struct {
+	source  string
+	complex []complexSelector
+}
+
+// complexSelector is a chain of compound selectors joined by
+// combinators, combinators[i] joining compounds[i] and compounds[i+1].
+type complexSelector struct {
+	compounds   []compoundSelector
+	combinators []byte
+}
+
+// compoundSelector is a sequence of simple selectors applying to a
+// single element.
+type compoundSelector struct {
+	// element name, empty for any element
+	name string
+	// namespace prefix, nil when not given; "*" for any and "" for none
+	prefix *string
+
+	attributes []attributeSelector
+	pseudos    []pseudoSelector
+}
+
+type attributeSelector struct {
+	prefix *string
+	name   string
+	// operator, empty when only testing presence
+	operator string
+	value    string
+}
+
+type pseudoSelector struct {
+	name string
+	// a and b of an+b for nth pseudo-classes
+	a, b int
+	// argument of :not
+	not *compoundSelector
+}
+
+// CompileSelector parses a group of CSS selectors.
+func CompileSelector(selector string) (*Selector, error) {
+	p := &selectorParser{source: selector}
+	s := &Selector{source: selector}
+
+	for {
+		p.skipSpace()
+		c, err := p.complex()
+		if err != nil {
+			return nil, err
+		}
+		s.complex = append(s.complex, c)
+
+		p.skipSpace()
+		if p.eof() {
+			return s, nil
+		}
+		if p.peek() != ',' {
+			return nil, p.error("unexpected character %q", p.peek())
+		}
+		p.pos++
+	}
+}
+
+// MustCompileSelector is like CompileSelector but panics if the
+// selector can not be parsed.
+func MustCompileSelector(selector string) *Selector {
+	s, err := CompileSelector(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// String returns the source of the selector.
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match reports whether the element matches the selector. Ancestors and
+// siblings are found through the parent links of the element.
+func (s *Selector) Match(e *Element) bool {
+	return s.match(e, ancestors(e))
+}
+
+// match reports whether the element matches with chain holding its
+// ancestors from the outermost one.
+func (s *Selector) match(e *Element, chain []*Element) bool {
+	for i := range s.complex {
+		if s.complex[i].match(e, chain, len(s.complex[i].compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuerySelector returns the first descendant of the element, in document
+// order, matching the selector.
+func (e *Element) QuerySelector(selector string) (*Element, error) {
+	s, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return s.First(e), nil
+}
+
+// QuerySelectorAll returns the descendants of the element matching the
+// selector, in document order.
+func (e *Element) QuerySelectorAll(selector string) ([]*Element, error) {
+	s, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return s.All(e), nil
+}
+
+// First returns the first descendant of root matching the selector.
+func (s *Selector) First(root *Element) *Element {
+	var found *Element
+	s.query(root, func(e *Element) bool {
+		found = e
+		return false
+	})
+	return found
+}
+
+// All returns the descendants of root matching the selector.
+func (s *Selector) All(root *Element) []*Element {
+	var found []*Element
+	s.query(root, func(e *Element) bool {
+		found = append(found, e)
+		return true
+	})
+	return found
+}
+
+// query calls fn for the matching descendants of root until it returns
+// false. Selectors may refer to ancestors of root.
+func (s *Selector) query(root *Element, fn func(e *Element) bool) {
+	chain := append(ancestors(root), root)
+
+	var visit func(e *Element) bool
+	visit = func(e *Element) bool {
+		for _, child := range e.Children {
+			if s.match(child, chain) && !fn(child) {
+				return false
+			}
+
+			chain = append(chain, child)
+			more := visit(child)
+			chain = chain[:len(chain)-1]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	visit(root)
+}
+
+// ancestors returns the ancestors of the element from the outermost one.
+func ancestors(e *Element) []*Element {
+	var chain []*Element
+	for p := e.Parent(); p != nil; p = p.Parent() {
+		chain = append(chain, p)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// siblings returns the children of the parent of the element, the
+// element alone when it has no parent.
+func siblings(e *Element, chain []*Element) []*Element {
+	if len(chain) == 0 {
+		return []*Element{e}
+	}
+	return chain[len(chain)-1].Children
+}
+
+func (c *complexSelector) match(e *Element, chain []*Element, i int) bool {
+	if !c.compounds[i].match(e, chain) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch c.combinators[i-1] {
+	case '>':
+		n := len(chain)
+		return n > 0 && c.match(chain[n-1], chain[:n-1], i-1)
+
+	case ' ':
+		for n := len(chain); n > 0; n-- {
+			if c.match(chain[n-1], chain[:n-1], i-1) {
+				return true
+			}
+		}
+
+	case '+', '~':
+		all := siblings(e, chain)
+		for j := 0; j < len(all) && all[j] != e; j++ {
+			if c.combinators[i-1] == '+' && (j+1 >= len(all) || all[j+1] != e) {
+				continue
+			}
+			if c.match(all[j], chain, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *compoundSelector) match(e *Element, chain []*Element) bool {
+	if c.name != "" && !matchName(e.Name, e.Namespaces, c.prefix, c.name) {
+		return false
+	}
+	if c.name == "" && c.prefix != nil && !matchName(e.Name, e.Namespaces, c.prefix, e.LocalName()) {
+		return false
+	}
+
+	for i := range c.attributes {
+		if !c.attributes[i].match(e) {
+			return false
+		}
+	}
+
+	for i := range c.pseudos {
+		if !c.pseudos[i].match(e, chain) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchName matches a qualified name against a name selector. Without a
+// prefix the local name is compared unless the selector names a prefix
+// itself, as in sodipodi\:namedview.
+func matchName(qualified string, namespaces map[string]string, prefix *string, name string) bool {
+	actualPrefix, local := splitName(qualified)
+	if prefix == nil {
+		if strings.Contains(name, ":") {
+			return qualified == name
+		}
+		return local == name
+	}
+
+	if local != name {
+		return false
+	}
+
+	switch *prefix {
+	case "*":
+		return true
+	case "":
+		return actualPrefix == ""
+	}
+
+	if actualPrefix == *prefix {
+		return true
+	}
+	// the same namespace may be bound to other prefixes
+	expected, ok := lookupNamespace(namespaces, *prefix)
+	actual, found := lookupNamespace(namespaces, actualPrefix)
+	return ok && found && expected == actual
+}
+
+func (a *attributeSelector) match(e *Element) bool {
+	for key, value := range e.Attributes {
+		prefix, _ := splitName(key)
+		switch {
+		case a.prefix == nil:
+			// unprefixed attributes are in no namespace
+			if key != a.name {
+				continue
+			}
+		case *a.prefix != "*" && *a.prefix != "" && prefix == "":
+			continue
+		case !matchName(key, e.Namespaces, a.prefix, a.name):
+			continue
+		}
+		if a.matchValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *attributeSelector) matchValue(value string) bool {
+	switch a.operator {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		for _, word := range strings.FieldsFunc(value, isSpace) {
+			if word == a.value {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+func (p *pseudoSelector) match(e *Element, chain []*Element) bool {
+	switch p.name {
+	case "root":
+		return len(chain) == 0
+	case "empty":
+		return isEmpty(e)
+	case "not":
+		return !p.not.match(e, chain)
+	case "first-child":
+		return nthPosition(e, chain, false, false) == 1
+	case "last-child":
+		return nthPosition(e, chain, true, false) == 1
+	case "only-child":
+		return len(siblings(e, chain)) == 1
+	case "first-of-type":
+		return nthPosition(e, chain, false, true) == 1
+	case "last-of-type":
+		return nthPosition(e, chain, true, true) == 1
+	case "only-of-type":
+		return nthPosition(e, chain, false, true) == 1 && nthPosition(e, chain, true, true) == 1
+	case "nth-child":
+		return p.matchNth(nthPosition(e, chain, false, false))
+	case "nth-last-child":
+		return p.matchNth(nthPosition(e, chain, true, false))
+	case "nth-of-type":
+		return p.matchNth(nthPosition(e, chain, false, true))
+	case "nth-last-of-type":
+		return p.matchNth(nthPosition(e, chain, true, true))
+	}
+	return false
+}
+
+// matchNth reports whether the one based position is an+b for some
+// non negative n.
+func (p *pseudoSelector) matchNth(position int) bool {
+	if p.a == 0 {
+		return position == p.b
+	}
+	n := position - p.b
+	return n%p.a == 0 && n/p.a >= 0
+}
+
+// nthPosition returns the one based position of the element among its
+// siblings, counted from the end when last is set and among siblings of
+// the same name when ofType is set.
+func nthPosition(e *Element, chain []*Element, last, ofType bool) int {
+	all := siblings(e, chain)
+	position := 0
+	for i := range all {
+		sibling := all[i]
+		if last {
+			sibling = all[len(all)-1-i]
+		}
+		if ofType && sibling.Name != e.Name {
+			continue
+		}
+		position++
+		if sibling == e {
+			return position
+		}
+	}
+	return 0
+}
+
+// isEmpty reports whether the element has neither children nor text.
+func isEmpty(e *Element) bool {
+	if len(e.Children) > 0 || e.Content != "" {
+		return false
+	}
+	for _, node := range e.Nodes {
+		if node.isText() && node.Data != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorParser is a recursive descent parser of CSS selectors.
+type selectorParser struct {
+	source string
+	pos    int
+}
+
+func (p *selectorParser) error(format string, args ...interface{}) error {
+	return &SelectorError{Selector: p.source, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *selectorParser) eof() bool {
+	return p.pos >= len(p.source)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.source[p.pos]
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\r\n\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) complex() (complexSelector, error) {
+	var c complexSelector
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return c, err
+		}
+		c.compounds = append(c.compounds, compound)
+
+		space := p.skipSpace()
+		combinator := p.peek()
+		switch {
+		case combinator == '>' || combinator == '+' || combinator == '~':
+			p.pos++
+			p.skipSpace()
+		case space && !p.eof() && combinator != ',':
+			combinator = ' '
+		default:
+			return c, nil
+		}
+		c.combinators = append(c.combinators, combinator)
+	}
+}
+
+func (p *selectorParser) compound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+
+	if p.peek() == '*' || p.peek() == '|' || isNameStart(p.peek()) {
+		prefix, name, err := p.qualifiedName(false)
+		if err != nil {
+			return c, err
+		}
+		c.prefix = prefix
+		if name != "*" {
+			c.name = name
+		}
+	}
+
+	for !p.eof() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			id, err := p.name()
+			if err != nil {
+				return c, err
+			}
+			c.attributes = append(c.attributes, attributeSelector{name: "id", operator: "=", value: id})
+
+		case '.':
+			p.pos++
+			class, err := p.name()
+			if err != nil {
+				return c, err
+			}
+			c.attributes = append(c.attributes, attributeSelector{name: "class", operator: "~=", value: class})
+
+		case '[':
+			a, err := p.attribute()
+			if err != nil {
+				return c, err
+			}
+			c.attributes = append(c.attributes, a)
+
+		case ':':
+			pseudo, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, pseudo)
+
+		default:
+			if p.pos == start {
+				return c, p.error("expected selector")
+			}
+			return c, nil
+		}
+	}
+
+	if p.pos == start {
+		return c, p.error("expected selector")
+	}
+	return c, nil
+}
+
+// qualifiedName parses a name with an optional namespace prefix. Within
+// attribute selectors a colon may separate the prefix as well.
+func (p *selectorParser) qualifiedName(attribute bool) (*string, string, error) {
+	var first string
+	if p.peek() == '*' {
+		p.pos++
+		first = "*"
+	} else if p.peek() != '|' {
+		name, err := p.name()
+		if err != nil {
+			return nil, "", err
+		}
+		first = name
+	}
+
+	if p.peek() != '|' || strings.HasPrefix(p.source[p.pos:], "|=") {
+		if first == "" {
+			return nil, "", p.error("expected name")
+		}
+		if attribute && first != "*" && strings.Contains(first, ":") {
+			prefix, local := splitName(first)
+			return &prefix, local, nil
+		}
+		return nil, first, nil
+	}
+
+	p.pos++
+	prefix := first
+	if p.peek() == '*' && !attribute {
+		p.pos++
+		return &prefix, "*", nil
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, "", err
+	}
+	return &prefix, name, nil
+}
+
+func (p *selectorParser) attribute() (attributeSelector, error) {
+	var a attributeSelector
+	p.pos++ // [
+	p.skipSpace()
+
+	if p.peek() == '*' && !strings.HasPrefix(p.source[p.pos:], "*|") {
+		return a, p.error("expected attribute name")
+	}
+	prefix, name, err := p.attributeName()
+	if err != nil {
+		return a, err
+	}
+	a.prefix, a.name = prefix, name
+	p.skipSpace()
+
+	if p.peek() == ']' {
+		p.pos++
+		return a, nil
+	}
+
+	for _, operator := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.source[p.pos:], operator) {
+			a.operator = operator
+		}
+	}
+	if a.operator == "" {
+		return a, p.error("expected attribute operator")
+	}
+	p.pos += len(a.operator)
+	p.skipSpace()
+
+	if p.peek() == '"' || p.peek() == '\'' {
+		a.value, err = p.string()
+	} else {
+		a.value, err = p.name()
+	}
+	if err != nil {
+		return a, err
+	}
+
+	p.skipSpace()
+	if p.peek() != ']' {
+		return a, p.error("expected ]")
+	}
+	p.pos++
+	return a, nil
+}
+
+// attributeName parses an attribute name in which a colon is part of
+// the name, as in inkscape:label.
+func (p *selectorParser) attributeName() (*string, string, error) {
+	prefix, name, err := p.qualifiedName(true)
+	if err != nil || p.peek() != ':' || prefix != nil {
+		return prefix, name, err
+	}
+
+	p.pos++
+	local, err := p.name()
+	if err != nil {
+		return nil, "", err
+	}
+	return &name, local, nil
+}
+
+func (p *selectorParser) pseudo() (pseudoSelector, error) {
+	var pseudo pseudoSelector
+	p.pos++ // :
+	if p.peek() == ':' {
+		return pseudo, p.error("pseudo-elements are not supported")
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return pseudo, err
+	}
+	pseudo.name = strings.ToLower(name)
+
+	switch pseudo.name {
+	case "root", "empty", "first-child", "last-child", "only-child",
+		"first-of-type", "last-of-type", "only-of-type":
+		return pseudo, nil
+
+	case "not":
+		if err := p.open(); err != nil {
+			return pseudo, err
+		}
+		not, err := p.compound()
+		if err != nil {
+			return pseudo, err
+		}
+		for _, inner := range not.pseudos {
+			if inner.name == "not" {
+				return pseudo, p.error(":not can not be nested")
+			}
+		}
+		pseudo.not = &not
+		return pseudo, p.close()
+
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		if err := p.open(); err != nil {
+			return pseudo, err
+		}
+		end := strings.IndexByte(p.source[p.pos:], ')')
+		if end < 0 {
+			return pseudo, p.error("expected )")
+		}
+		pseudo.a, pseudo.b, err = parseNth(p.source[p.pos : p.pos+end])
+		if err != nil {
+			return pseudo, p.error("%v", err)
+		}
+		p.pos += end
+		return pseudo, p.close()
+	}
+
+	return pseudo, p.error("unsupported pseudo-class :%s", name)
+}
+
+func (p *selectorParser) open() error {
+	if p.peek() != '(' {
+		return p.error("expected (")
+	}
+	p.pos++
+	p.skipSpace()
+	return nil
+}
+
+func (p *selectorParser) close() error {
+	p.skipSpace()
+	if p.peek() != ')' {
+		return p.error("expected )")
+	}
+	p.pos++
+	return nil
+}
+
+// parseNth parses the an+b argument of nth pseudo-classes.
+func parseNth(arg string) (int, int, error) {
+	arg = strings.ToLower(strings.Join(strings.Fields(arg), ""))
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+
+	i := strings.IndexByte(arg, 'n')
+	if i < 0 {
+		b, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", arg)
+		}
+		return 0, b, nil
+	}
+
+	var a, b int
+	switch arg[:i] {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(arg[:i]); err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", arg)
+		}
+	}
+
+	if rest := arg[i+1:]; rest != "" {
+		if rest[0] != '+' && rest[0] != '-' {
+			return 0, 0, fmt.Errorf("invalid argument %q", arg)
+		}
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", arg)
+		}
+	}
+	return a, b, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c == '-' || c == '\\' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9')
+}
+
+// name parses an identifier, resolving escapes.
+func (p *selectorParser) name() (string, error) {
+	var name strings.Builder
+	for !p.eof() && isNameChar(p.peek()) {
+		if p.peek() != '\\' {
+			name.WriteByte(p.peek())
+			p.pos++
+			continue
+		}
+		if err := p.escape(&name); err != nil {
+			return "", err
+		}
+	}
+
+	if name.Len() == 0 {
+		return "", p.error("expected name")
+	}
+	return name.String(), nil
+}
+
+// string parses a quoted string.
+func (p *selectorParser) string() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var value strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		switch c {
+		case quote:
+			p.pos++
+			return value.String(), nil
+		case '\\':
+			if err := p.escape(&value); err != nil {
+				return "", err
+			}
+		default:
+			value.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.error("unterminated string")
+}
+
+// escape parses a backslash escape, either up to six hex digits followed
+// by an optional space or a single character.
+func (p *selectorParser) escape(out *strings.Builder) error {
+	p.pos++ // backslash
+	if p.eof() {
+		return p.error("unterminated escape")
+	}
+
+	end := p.pos
+	for end < len(p.source) && end-p.pos < 6 && strings.IndexByte("0123456789abcdefABCDEF", p.source[end]) >= 0 {
+		end++
+	}
+	if end > p.pos {
+		code, _ := strconv.ParseUint(p.source[p.pos:end], 16, 32)
+		out.WriteRune(rune(code))
+		p.pos = end
+		if !p.eof() && strings.IndexByte(" \t\r\n\f", p.peek()) >= 0 {
+			p.pos++
+		}
+		return nil
+	}
+
+	r, size := utf8.DecodeRuneInString(p.source[p.pos:])
+	out.WriteRune(r)
+	p.pos += size
+	return nil
+}
@@ -0,0 +1,149 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+)
+
+func selectorTestElement() *Element {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd" xmlns:xlink="http://www.w3.org/1999/xlink">
+		<sodipodi:namedview id="view"/>
+		<g id="layer1" inkscape:label="Header" class="layer top">
+			<text id="t1" class="label title">A</text>
+			<rect id="r1" fill="red"/>
+			<text id="t2" class="label">B</text>
+			<g id="inner"><text id="t3" class="label">C</text></g>
+		</g>
+		<g id="layer2" inkscape:label="Footer">
+			<rect id="r2"/>
+			<rect id="r3" fill="url(#grad)" lang="en-US"/>
+			<use id="u1" xlink:href="#r1"/>
+			<text id="t4"></text>
+		</g>
+	</svg>`
+	element, _ := parse(svg, false)
+	return element
+}
+
+func TestQuerySelectorAll(t *testing.T) {
+	root := selectorTestElement()
+
+	var testCases = []struct {
+		selector string
+		ids      string
+	}{
+		{"text", "t1 t2 t3 t4"},
+		{"g#layer1 > text.label", "t1 t2"},
+		{"g#layer1 text.label", "t1 t2 t3"},
+		{".label.title", "t1"},
+		{`[inkscape:label="Header"]`, "layer1"},
+		{`[inkscape|label^=Foo]`, "layer2"},
+		{`[inkscape:label]`, "layer1 layer2"},
+		{"rect:not([fill])", "r2"},
+		{"g > :nth-child(2)", "r1 r3"},
+		{"g > :nth-child(2n+1)", "t1 t2 t3 r2 u1"},
+		{"g > :nth-last-child(1)", "inner t3 t4"},
+		{"text:nth-of-type(2)", "t2"},
+		{"rect:first-of-type", "r1 r2"},
+		{"text:only-child", "t3"},
+		{":root > g:last-child", "layer2"},
+		{"text:empty", "t4"},
+		{"text + rect", "r1"},
+		{"rect ~ use", "u1"},
+		{"[class~=top]", "layer1"},
+		{"[lang|=en]", "r3"},
+		{"[fill*=grad]", "r3"},
+		{"[fill$=red]", "r1"},
+		{"[href]", ""},
+		{"[xlink|href='#r1']", "u1"},
+		{"[*|href]", "u1"},
+		{`sodipodi\:namedview`, "view"},
+		{"sodipodi|namedview", "view"},
+		{"namedview", "view"},
+		{"#t4, #t1", "t1 t4"},
+		{"svg > g > g > text", "t3"},
+		{"circle", ""},
+	}
+
+	for _, test := range testCases {
+		elements, err := root.QuerySelectorAll(test.selector)
+		if err != nil {
+			t.Errorf("QuerySelectorAll(%s): unexpected error %v\n", test.selector, err)
+			continue
+		}
+
+		ids := make([]string, len(elements))
+		for i, e := range elements {
+			ids[i] = e.Attributes["id"]
+		}
+		if actual := strings.Join(ids, " "); actual != test.ids {
+			t.Errorf("QuerySelectorAll(%s): expected %v, actual %v\n", test.selector, test.ids, actual)
+		}
+	}
+}
+
+func TestQuerySelector(t *testing.T) {
+	root := selectorTestElement()
+	inner := root.FindID("inner")
+
+	var testCases = []struct {
+		root     *Element
+		selector string
+		id       string
+	}{
+		{root, "text.label", "t1"},
+		{inner, "g#layer1 text", "t3"},
+		{inner, "#t1", ""},
+		{root, "svg", ""},
+	}
+
+	for _, test := range testCases {
+		e, err := test.root.QuerySelector(test.selector)
+		if err != nil {
+			t.Errorf("QuerySelector(%s): unexpected error %v\n", test.selector, err)
+			continue
+		}
+
+		actual := ""
+		if e != nil {
+			actual = e.Attributes["id"]
+		}
+		if actual != test.id {
+			t.Errorf("QuerySelector(%s): expected %v, actual %v\n", test.selector, test.id, actual)
+		}
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	root := selectorTestElement()
+
+	if s := MustCompileSelector("#layer2 > rect:nth-child(2)"); !s.Match(root.FindID("r3")) {
+		t.Errorf("Match: expected %v, actual %v\n", true, false)
+	}
+	if s := MustCompileSelector("#layer1 rect"); s.Match(root.FindID("r3")) {
+		t.Errorf("Match: expected %v, actual %v\n", false, true)
+	}
+}
+
+func TestCompileSelectorError(t *testing.T) {
+	var testCases = []string{
+		"",
+		"g >",
+		"g,",
+		"[fill",
+		"[fill=]",
+		"[fill=='red']",
+		"rect:hover",
+		"rect::before",
+		":nth-child(x)",
+		":not(:not(g))",
+		"g $ rect",
+	}
+
+	for _, selector := range testCases {
+		_, err := CompileSelector(selector)
+		if _, ok := err.(*SelectorError); !ok {
+			t.Errorf("CompileSelector(%s): expected SelectorError, actual %v\n", selector, err)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package svg
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EventType identifies the kind of a stream Event.
+type EventType int
+
+// event types
+const (
+	StartEvent EventType = iota
+	EndEvent
+	NodeEvent
+)
+
+// Event is a token read from a stream.
+type Event struct {
+	Type EventType
+
+	// Element is the element started or ended. Its name, attributes and
+	// namespaces are set but its children are not read.
+	Element *Element
+
+	// Node holds text, comments, processing instructions, directives
+	// and CDATA sections.
+	Node *Node
+
+	// Depth is the number of ancestors of the element or node.
+	Depth int
+
+	// Path holds the ancestors from the root down to the parent. It is
+	// only valid until the next call to Next.
+	Path []*Element
+}
+
+// StreamAction tells Transform what to do with an event.
+type StreamAction int
+
+// stream actions
+const (
+	// Emit writes the event, including changes made to its element.
+	Emit StreamAction = iota
+
+	// Drop discards the event. Dropping a start event discards the
+	// whole element; end events can not be dropped.
+	Drop
+)
+
+var errNoStartEvent = errors.New("svg: no start event to read from")
+
+// Reader reads an SVG document token by token without building the
+// element tree, so that documents of any size can be processed.
+type Reader struct {
+	decoder *documentDecoder
+
+	// open elements, the raw names they were started with and the
+	// number of children of each name met so far
+	stack  []*Element
+	names  []xml.Name
+	counts []map[string]int
+
+	// whether the last event read was a start event
+	started bool
+}
+
+// NewReader creates a reader from an SVG input. Malformed input is
+// reported as a ParseError.
+func NewReader(source io.Reader) *Reader {
+	return &Reader{
+		decoder: newDocumentDecoder(source),
+		counts:  []map[string]int{make(map[string]int)},
+	}
+}
+
+// Next returns the next event of the document, or io.EOF at its end.
+func (r *Reader) Next() (*Event, error) {
+	r.started = false
+	for {
+		start := r.decoder.position()
+		token, err := r.decoder.RawToken()
+		if err == io.EOF && len(r.stack) > 0 {
+			return nil, r.decoder.wrap(r.decoder.syntaxError("unexpected EOF"))
+		}
+
+		if err != nil {
+			return nil, r.decoder.wrap(err)
+		}
+
+		depth := len(r.stack)
+		switch t := token.(type) {
+		case xml.StartElement:
+			var parent *Element
+			if depth > 0 {
+				parent = r.stack[depth-1]
+			}
+
+			element := NewElement(parent, t)
+			r.decoder.locate(element, start)
+			event := &Event{Type: StartEvent, Element: element, Depth: depth, Path: r.path(depth)}
+
+			r.push(element, t.Name)
+			r.started = true
+			return event, nil
+
+		case xml.EndElement:
+			if depth == 0 || r.names[depth-1] != t.Name {
+				return nil, r.decoder.wrap(r.decoder.syntaxError("unexpected end element </" + qualifiedName(t.Name) + ">"))
+			}
+
+			element := r.pop()
+			return &Event{Type: EndEvent, Element: element, Depth: depth - 1, Path: r.path(depth - 1)}, nil
+
+		case xml.CharData:
+			node := &Node{Type: TextNode, Data: string(t)}
+			if r.decoder.isCData(start) {
+				node.Type = CDataNode
+			}
+			return &Event{Type: NodeEvent, Node: node, Depth: depth, Path: r.path(depth)}, nil
+
+		default:
+			if node := newNode(xml.CopyToken(token)); node != nil {
+				return &Event{Type: NodeEvent, Node: node, Depth: depth, Path: r.path(depth)}, nil
+			}
+		}
+	}
+}
+
+func (r *Reader) path(depth int) []*Element {
+	return r.stack[:depth:depth]
+}
+
+func (r *Reader) push(element *Element, name xml.Name) {
+	counts := r.counts[len(r.counts)-1]
+	counts[element.Name]++
+
+	segment := element.Name
+	if len(r.stack) > 0 {
+		segment = fmt.Sprintf("%s[%d]", element.Name, counts[element.Name])
+	}
+
+	r.stack = append(r.stack, element)
+	r.names = append(r.names, name)
+	r.counts = append(r.counts, make(map[string]int))
+	r.decoder.path = append(r.decoder.path, segment)
+}
+
+func (r *Reader) pop() *Element {
+	depth := len(r.stack) - 1
+	element := r.stack[depth]
+	r.stack = r.stack[:depth]
+	r.names = r.names[:depth]
+	r.counts = r.counts[:depth+1]
+	r.decoder.path = r.decoder.path[:depth]
+	return element
+}
+
+// Skip discards the content of the element started by the last event,
+// including its end. The next event follows the element.
+func (r *Reader) Skip() error {
+	if !r.started {
+		return errNoStartEvent
+	}
+	r.started = false
+
+	for depth := 1; depth > 0; {
+		// drops the recorded input of the skipped tokens
+		r.decoder.position()
+		token, err := r.decoder.RawToken()
+		if err == io.EOF {
+			return r.decoder.wrap(r.decoder.syntaxError("unexpected EOF"))
+		}
+
+		if err != nil {
+			return r.decoder.wrap(err)
+		}
+
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	r.pop()
+	return nil
+}
+
+// ReadElement reads the whole element started by the last event,
+// including its children, and returns it. The next event follows the
+// element.
+func (r *Reader) ReadElement() (*Element, error) {
+	if !r.started {
+		return nil, errNoStartEvent
+	}
+	r.started = false
+
+	depth := len(r.stack) - 1
+	element := r.stack[depth]
+	if err := element.decode(r.decoder, &r.names[depth]); err != nil {
+		return nil, err
+	}
+	r.pop()
+	return element, nil
+}
+
+// Writer writes a document from stream events.
+type Writer struct {
+	encoder *serializer
+
+	// start tags written and the namespaces in scope of each
+	starts []xml.StartElement
+	scopes []namespaceScope
+}
+
+// NewWriter creates a writer to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{encoder: &serializer{Encoder: xml.NewEncoder(w), w: w}}
+}
+
+func (w *Writer) scope() namespaceScope {
+	if len(w.scopes) == 0 {
+		return nil
+	}
+	return w.scopes[len(w.scopes)-1]
+}
+
+// WriteEvent writes the token of an event.
+func (w *Writer) WriteEvent(event *Event) error {
+	switch event.Type {
+	case StartEvent:
+		start, scope := w.scope().startElement(event.Element)
+
+		if err := w.encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		w.starts = append(w.starts, start)
+		w.scopes = append(w.scopes, scope)
+		return nil
+
+	case EndEvent:
+		depth := len(w.starts) - 1
+		if depth < 0 {
+			return errors.New("svg: end event without start")
+		}
+
+		end := w.starts[depth].End()
+		w.starts = w.starts[:depth]
+		w.scopes = w.scopes[:depth]
+		return w.encoder.EncodeToken(end)
+	}
+
+	return event.Node.encode(w.encoder)
+}
+
+// WriteElement writes a whole element, e.g. one returned by
+// Reader.ReadElement.
+func (w *Writer) WriteElement(e *Element) error {
+	return e.encode(w.encoder, w.scope())
+}
+
+// Flush flushes buffered output to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.encoder.Flush()
+}
+
+// Transform reads a document from source and writes it to w, calling fn
+// for every event. Elements and nodes can be modified or dropped on the
+// fly; the document is never held in memory as a whole.
+func Transform(source io.Reader, w io.Writer, fn func(event *Event) StreamAction) error {
+	reader := NewReader(source)
+	writer := NewWriter(w)
+
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if fn(event) == Drop {
+			switch event.Type {
+			case StartEvent:
+				if err := reader.Skip(); err != nil {
+					return err
+				}
+				continue
+			case NodeEvent:
+				continue
+			}
+		}
+
+		if err := writer.WriteEvent(event); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,180 @@
+package svg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	f, err := os.Open("./inkscape.svg")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer f.Close()
+
+	reader := NewReader(f)
+	tspans := 0
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if event.Type == StartEvent && event.Element.Name == "metadata" {
+			if err := reader.Skip(); err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+		}
+
+		if event.Type == StartEvent && event.Element.Name == "tspan" {
+			tspans++
+
+			var path []string
+			for _, e := range event.Path {
+				path = append(path, e.Name)
+			}
+			if event.Depth != 3 || strings.Join(path, "/") != "svg/g/text" {
+				t.Errorf("Reader: expected %v, actual %v %v\n", "svg/g/text", event.Depth, path)
+			}
+		}
+
+		if event.Type == StartEvent && event.Element.Name == "rdf:RDF" {
+			t.Errorf("Reader: metadata should have been skipped\n")
+		}
+	}
+
+	if tspans != 7 {
+		t.Errorf("Reader: expected %v, actual %v\n", 7, tspans)
+	}
+}
+
+func TestReaderSkipMemory(t *testing.T) {
+	// a large subtree, then an element to read after it
+	var b strings.Builder
+	b.WriteString("<svg><g>")
+	for i := 0; i < 100000; i++ {
+		b.WriteString(`<rect x="1" y="2" width="3" height="4"/>`)
+	}
+	b.WriteString("</g><circle/></svg>")
+
+	// the recorded input is measured as the input is read
+	var reader *Reader
+	recorded := 0
+	input := &probeReader{r: strings.NewReader(b.String()), probe: func() {
+		if reader != nil && len(reader.decoder.source.buf) > recorded {
+			recorded = len(reader.decoder.source.buf)
+		}
+	}}
+
+	reader = NewReader(input)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Reader: unexpected error %v\n", err)
+		}
+		if event.Type == StartEvent && event.Element.Name == "g" {
+			if err := reader.Skip(); err != nil {
+				t.Fatalf("Reader: unexpected error %v\n", err)
+			}
+			break
+		}
+	}
+
+	event, err := reader.Next()
+	if err != nil || event.Type != StartEvent || event.Element.Name != "circle" {
+		t.Fatalf("Reader: expected circle, actual %v %v\n", event, err)
+	}
+	if recorded > maxRecorderCapacity {
+		t.Errorf("Reader: expected at most %d bytes recorded, actual %d\n", maxRecorderCapacity, recorded)
+	}
+	if source := reader.decoder.source; cap(source.buf) > maxRecorderCapacity {
+		t.Errorf("Reader: expected at most %d bytes kept, actual %d\n", maxRecorderCapacity, cap(source.buf))
+	}
+}
+
+// probeReader calls probe before each read.
+type probeReader struct {
+	r     io.Reader
+	probe func()
+}
+
+func (p *probeReader) Read(b []byte) (int, error) {
+	p.probe()
+	return p.r.Read(b)
+}
+
+func TestReadElement(t *testing.T) {
+	reader := NewReader(strings.NewReader(`<svg><g id="a"><rect/><!-- c --></g><g id="b"/></svg>`))
+	w := &bytes.Buffer{}
+	writer := NewWriter(w)
+
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if event.Type == StartEvent && event.Element.Attributes["id"] == "a" {
+			element, err := reader.ReadElement()
+			if err != nil || len(element.Children) != 1 {
+				t.Errorf("ReadElement: expected 1 child, actual %v %v\n", element, err)
+				t.FailNow()
+			}
+
+			element.Children[0].Attributes["fill"] = "red"
+			writer.WriteElement(element)
+			continue
+		}
+
+		writer.WriteEvent(event)
+	}
+	writer.Flush()
+
+	expected := `<svg><g id="a"><rect fill="red"></rect><!-- c --></g><g id="b"></g></svg>`
+	if w.String() != expected {
+		t.Errorf("ReadElement: expected %v, actual %v\n", expected, w.String())
+	}
+}
+
+func TestTransform(t *testing.T) {
+	svg := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:x="urn:x">
+  <x:meta><x:a/></x:meta>
+  <rect fill="red"/>
+  <!-- drop me -->
+</svg>`
+
+	SetSortAttributes(true)
+	w := &bytes.Buffer{}
+	err := Transform(strings.NewReader(svg), w, func(event *Event) StreamAction {
+		switch {
+		case event.Type == StartEvent && event.Element.Space == "urn:x":
+			return Drop
+		case event.Type == StartEvent && event.Element.Name == "rect":
+			event.Element.Attributes["fill"] = "blue"
+		case event.Type == NodeEvent && event.Node.Type == CommentNode:
+			return Drop
+		}
+		return Emit
+	})
+
+	expected := `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:x="urn:x">
+  
+  <rect fill="blue"></rect>
+  
+</svg>`
+	if err != nil || w.String() != expected {
+		t.Errorf("Transform: expected %v, actual %v %v\n", expected, w.String(), err)
+	}
+}
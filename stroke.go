@@ -0,0 +1,269 @@
+package svg
+
+import "math"
+
+// strokeStyle tells how the lines of a path are stroked.
+type strokeStyle struct {
+	width      float64
+	cap, join  string
+	miterLimit float64
+	// dashes is nil for solid strokes.
+	dashes     []float64
+	dashOffset float64
+}
+
+// strokeStyle returns the stroke of the properties.
+func (p presentation) strokeStyle() strokeStyle {
+	return strokeStyle{
+		width:      p.strokeWidth,
+		cap:        p.lineCap,
+		join:       p.lineJoin,
+		miterLimit: p.miterLimit,
+		dashes:     p.dashArray,
+		dashOffset: p.dashOffset,
+	}
+}
+
+// strokePolygons returns the outline of the stroke of lines as polygons
+// all wound the same way, the stroke being their union. Round joins and
+// caps are approximated within tolerance.
+func strokePolygons(lines []polyline, style strokeStyle, tolerance float64) [][]point {
+	if style.width <= 0 {
+		return nil
+	}
+	if style.dashes != nil {
+		lines = dashLines(lines, style.dashes, style.dashOffset)
+	}
+
+	s := &stroker{style: style, half: style.width / 2, tolerance: tolerance}
+	for _, line := range lines {
+		s.stroke(line)
+	}
+	return s.polygons
+}
+
+type stroker struct {
+	style     strokeStyle
+	half      float64
+	tolerance float64
+	polygons  [][]point
+}
+
+func (s *stroker) add(polygon ...point) {
+	// wind every polygon the same way so that overlaps do not cancel
+	area := 0.0
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		area += p.x*q.y - q.x*p.y
+	}
+	if area < 0 {
+		for i, j := 0, len(polygon)-1; i < j; i, j = i+1, j-1 {
+			polygon[i], polygon[j] = polygon[j], polygon[i]
+		}
+	}
+	s.polygons = append(s.polygons, polygon)
+}
+
+func (s *stroker) stroke(line polyline) {
+	// drop repeated points
+	points := make([]point, 0, len(line.points))
+	for _, p := range line.points {
+		if len(points) == 0 || p != points[len(points)-1] {
+			points = append(points, p)
+		}
+	}
+	if line.closed && len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+
+	if len(points) == 1 {
+		// a zero length subpath shows its caps
+		p := points[0]
+		switch s.style.cap {
+		case "round":
+			s.circle(p)
+		case "square":
+			h := s.half
+			s.add(point{p.x - h, p.y - h}, point{p.x + h, p.y - h}, point{p.x + h, p.y + h}, point{p.x - h, p.y + h})
+		}
+		return
+	}
+	if len(points) == 0 {
+		return
+	}
+
+	n := len(points) - 1
+	if line.closed && len(points) > 2 {
+		n = len(points)
+	}
+	for i := 0; i < n; i++ {
+		a, b := points[i], points[(i+1)%len(points)]
+		d := direction(a, b)
+		h := s.half
+		s.add(
+			point{a.x - d.y*h, a.y + d.x*h},
+			point{b.x - d.y*h, b.y + d.x*h},
+			point{b.x + d.y*h, b.y - d.x*h},
+			point{a.x + d.y*h, a.y - d.x*h},
+		)
+
+		if i+1 < n || line.closed && len(points) > 2 {
+			c := points[(i+2)%len(points)]
+			s.joint(b, d, direction(b, c))
+		}
+	}
+
+	if !line.closed || len(points) == 2 {
+		s.cap(points[0], direction(points[1], points[0]))
+		s.cap(points[len(points)-1], direction(points[len(points)-2], points[len(points)-1]))
+	}
+}
+
+// direction returns the unit vector from a to b.
+func direction(a, b point) point {
+	length := math.Hypot(b.x-a.x, b.y-a.y)
+	return point{(b.x - a.x) / length, (b.y - a.y) / length}
+}
+
+// joint joins at p a segment of direction d1 to the next of direction d2.
+func (s *stroker) joint(p, d1, d2 point) {
+	cross := d1.x*d2.y - d1.y*d2.x
+	dot := d1.x*d2.x + d1.y*d2.y
+	if math.Abs(cross) < 1e-12 && dot > 0 {
+		return
+	}
+	if s.style.join == "round" || s.style.join == "arcs" {
+		s.circle(p)
+		return
+	}
+
+	// the outer side of the turn
+	side := 1.0
+	if cross > 0 {
+		side = -1
+	}
+	h := s.half * side
+	a := point{p.x - d1.y*h, p.y + d1.x*h}
+	b := point{p.x - d2.y*h, p.y + d2.x*h}
+
+	if s.style.join != "bevel" {
+		// the miter length in stroke widths is 1 / sin(angle / 2)
+		if sin := math.Sqrt((1 + dot) / 2); sin > 0 && 1/sin <= s.style.miterLimit {
+			mx, my := -d1.y-d2.y, d1.x+d2.x
+			length := math.Hypot(mx, my)
+			if length > 0 {
+				// the tip is half a miter length away from p
+				k := h / sin / length
+				s.add(p, a, point{p.x + mx*k, p.y + my*k}, b)
+				return
+			}
+		}
+	}
+	s.add(p, a, b)
+}
+
+// cap ends a line at p, d pointing outwards.
+func (s *stroker) cap(p, d point) {
+	h := s.half
+	switch s.style.cap {
+	case "round":
+		s.circle(p)
+	case "square":
+		s.add(
+			point{p.x - d.y*h, p.y + d.x*h},
+			point{p.x - d.y*h + d.x*h, p.y + d.x*h + d.y*h},
+			point{p.x + d.y*h + d.x*h, p.y - d.x*h + d.y*h},
+			point{p.x + d.y*h, p.y - d.x*h},
+		)
+	}
+}
+
+// circle adds a disc of the stroke width at p.
+func (s *stroker) circle(p point) {
+	n := 8
+	if s.tolerance < s.half {
+		n = int(math.Ceil(math.Pi / math.Acos(1-s.tolerance/s.half)))
+	}
+	if n < 8 {
+		n = 8
+	} else if n > 256 {
+		n = 256
+	}
+
+	polygon := make([]point, n)
+	for i := range polygon {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		polygon[i] = point{p.x + cos*s.half, p.y + sin*s.half}
+	}
+	s.add(polygon...)
+}
+
+// dashLines cuts lines into dashes, starting offset into the pattern.
+func dashLines(lines []polyline, dashes []float64, offset float64) []polyline {
+	total := 0.0
+	for _, dash := range dashes {
+		total += dash
+	}
+	if total <= 0 {
+		return lines
+	}
+
+	var dashed []polyline
+	for _, line := range lines {
+		points := line.points
+		if line.closed && len(points) > 0 {
+			points = append(append([]point{}, points...), points[0])
+		}
+		if len(points) < 2 {
+			continue
+		}
+
+		// find where the pattern starts
+		i := 0
+		position := math.Mod(offset, total)
+		if position < 0 {
+			position += total
+		}
+		for position >= dashes[i] {
+			position -= dashes[i]
+			i = (i + 1) % len(dashes)
+		}
+		remaining := dashes[i] - position
+		on := i%2 == 0
+
+		var current []point
+		if on {
+			current = []point{points[0]}
+		}
+		for j := 1; j < len(points); j++ {
+			a, b := points[j-1], points[j]
+			length := math.Hypot(b.x-a.x, b.y-a.y)
+			done := 0.0
+			for length-done > remaining {
+				done += remaining
+				t := done / length
+				p := point{a.x + (b.x-a.x)*t, a.y + (b.y-a.y)*t}
+				if on {
+					if p != current[len(current)-1] {
+						current = append(current, p)
+					}
+					dashed = append(dashed, polyline{points: current})
+					current = nil
+				} else {
+					current = []point{p}
+				}
+				on = !on
+				i = (i + 1) % len(dashes)
+				remaining = dashes[i]
+			}
+			remaining -= length - done
+			if on {
+				current = append(current, b)
+			}
+		}
+		if on && len(current) > 0 {
+			dashed = append(dashed, polyline{points: current})
+		}
+	}
+	return dashed
+}
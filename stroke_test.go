@@ -0,0 +1,104 @@
+package svg
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// polygonArea returns the signed area of a polygon.
+func polygonArea(polygon []point) float64 {
+	area := 0.0
+	for i, p := range polygon {
+		q := polygon[(i+1)%len(polygon)]
+		area += p.x*q.y - q.x*p.y
+	}
+	return area / 2
+}
+
+func TestDashLines(t *testing.T) {
+	line := polyline{points: []point{{0, 0}, {10, 0}, {10, 10}}}
+	var testCases = []struct {
+		dashes   []float64
+		offset   float64
+		expected []polyline
+	}{
+		{[]float64{4, 2}, 0, []polyline{
+			{points: []point{{0, 0}, {4, 0}}},
+			{points: []point{{6, 0}, {10, 0}}},
+			{points: []point{{10, 2}, {10, 6}}},
+			{points: []point{{10, 8}, {10, 10}}},
+		}},
+		{[]float64{4, 2}, 5, []polyline{
+			{points: []point{{1, 0}, {5, 0}}},
+			{points: []point{{7, 0}, {10, 0}, {10, 1}}},
+			{points: []point{{10, 3}, {10, 7}}},
+			{points: []point{{10, 9}, {10, 10}}},
+		}},
+		{[]float64{4, 2}, -2, []polyline{
+			{points: []point{{2, 0}, {6, 0}}},
+			{points: []point{{8, 0}, {10, 0}, {10, 2}}},
+			{points: []point{{10, 4}, {10, 8}}},
+		}},
+	}
+
+	for _, test := range testCases {
+		actual := dashLines([]polyline{line}, test.dashes, test.offset)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("dashLines %v %v: expected %v, actual %v\n", test.dashes, test.offset, test.expected, actual)
+		}
+	}
+
+	square := polyline{points: []point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, closed: true}
+	total := 0.0
+	for _, dash := range dashLines([]polyline{square}, []float64{3, 1}, 0) {
+		for i := 1; i < len(dash.points); i++ {
+			total += math.Hypot(dash.points[i].x-dash.points[i-1].x, dash.points[i].y-dash.points[i-1].y)
+		}
+	}
+	if total != 30 {
+		t.Errorf("dashLines: expected 30 dashed around the square, actual %v\n", total)
+	}
+}
+
+func TestStrokePolygons(t *testing.T) {
+	line := []polyline{{points: []point{{0, 0}, {10, 0}, {10, 10}}}}
+	var testCases = []struct {
+		style strokeStyle
+		area  float64
+	}{
+		// two segments and the square miter
+		{strokeStyle{width: 2, cap: "butt", join: "miter", miterLimit: 4}, 20 + 20 + 1},
+		{strokeStyle{width: 2, cap: "butt", join: "bevel", miterLimit: 4}, 20 + 20 + 0.5},
+		// the miter is 1.414 widths long
+		{strokeStyle{width: 2, cap: "butt", join: "miter", miterLimit: 1}, 20 + 20 + 0.5},
+		{strokeStyle{width: 2, cap: "square", join: "bevel", miterLimit: 4}, 20 + 20 + 0.5 + 2 + 2},
+		{strokeStyle{width: 2, cap: "butt", join: "miter", miterLimit: 4, dashes: []float64{5, 5}}, 10 + 10},
+		{strokeStyle{width: 0, cap: "butt", join: "miter", miterLimit: 4}, 0},
+	}
+
+	for _, test := range testCases {
+		polygons := strokePolygons(line, test.style, 0.01)
+		area := 0.0
+		for _, polygon := range polygons {
+			a := polygonArea(polygon)
+			if a < 0 {
+				t.Errorf("strokePolygons %+v: expected polygons wound the same way, actual %v\n", test.style, polygon)
+			}
+			area += a
+		}
+		if math.Abs(area-test.area) > 1e-9 {
+			t.Errorf("strokePolygons %+v: expected area %v, actual %v\n", test.style, test.area, area)
+		}
+	}
+
+	// round caps on a zero length subpath make a disc
+	dot := []polyline{{points: []point{{5, 5}, {5, 5}}}}
+	polygons := strokePolygons(dot, strokeStyle{width: 4, cap: "round"}, 0.001)
+	if len(polygons) != 1 || math.Abs(polygonArea(polygons[0])-4*math.Pi) > 0.01 {
+		t.Errorf("strokePolygons: expected a disc, actual %v\n", polygons)
+	}
+	if polygons := strokePolygons(dot, strokeStyle{width: 4, cap: "butt"}, 0.001); len(polygons) != 0 {
+		t.Errorf("strokePolygons: expected nothing for butt caps, actual %v\n", polygons)
+	}
+}
@@ -0,0 +1,369 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// template directives
+const (
+	templateIf     = "data-if"
+	templateRepeat = "data-repeat"
+	templateOffset = "data-repeat-offset"
+)
+
+// placeholder matches {{name}} and ${name}.
+var placeholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}|\$\{\s*([^{}]*?)\s*\}`)
+
+// Template is a document holding placeholders, such as {{customer.name}}
+// or ${total}, in its text and attribute values. Names are looked up in
+// maps with string keys, in struct fields and, with numbers, in slices.
+// Struct fields are matched ignoring case.
+//
+// Elements with a data-if attribute are dropped when the named value is
+// false, zero, empty or missing, or, when the name starts with "!", when
+// it is not. Elements with a data-repeat attribute, such as
+// data-repeat="items" or data-repeat="item in items", are copied for
+// each entry of the named list. Within a copy, names are first looked up
+// in the entry, or the entry is named by the alias, and index is the
+// position of the entry. Ids of the copies are prefixed with item1-,
+// item2-... to keep them unique. Copies are moved by the x and y of a
+// data-repeat-offset attribute, such as "0 20", times their index.
+type Template struct {
+	Root *Element
+
+	// Strict makes a missing value an error. Otherwise it is replaced
+	// by nothing.
+	Strict bool
+}
+
+// NewTemplate creates a template of the element, which is not changed
+// by Execute.
+func NewTemplate(root *Element) *Template {
+	return &Template{Root: root}
+}
+
+// ParseTemplate parses a template from an SVG input.
+func ParseTemplate(source io.Reader) (*Template, error) {
+	root, err := Parse(source, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(root), nil
+}
+
+// Execute returns a copy of the template with the placeholders replaced
+// by the values of data, a map or a struct, and the directives applied.
+func (t *Template) Execute(data interface{}) (*Element, error) {
+	for _, directive := range []string{templateIf, templateRepeat} {
+		if _, ok := t.Root.Attributes[directive]; ok {
+			return nil, fmt.Errorf("%s on the root element: %w", directive, ErrNotChild)
+		}
+	}
+
+	root := t.Root.Clone()
+	ex := &templateExecutor{strict: t.Strict}
+	if err := ex.execute(root, []templateScope{{dot: data}}); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// templateScope holds the names visible in a part of the template.
+type templateScope struct {
+	names map[string]interface{}
+	// dot is looked up when no name matches
+	dot interface{}
+}
+
+type templateExecutor struct {
+	strict bool
+}
+
+func (ex *templateExecutor) execute(e *Element, scopes []templateScope) error {
+	for name, value := range e.Attributes {
+		replaced, err := ex.replace(value, scopes)
+		if err != nil {
+			return fmt.Errorf("attribute %s of <%s>: %w", name, e.Name, err)
+		}
+		e.Attributes[name] = replaced
+	}
+
+	if err := ex.replaceText(e, scopes); err != nil {
+		return fmt.Errorf("text of <%s>: %w", e.Name, err)
+	}
+
+	children := make([]*Element, len(e.Children))
+	copy(children, e.Children)
+	for _, child := range children {
+		if _, ok := child.Attributes[templateRepeat]; ok {
+			if err := ex.repeat(child, scopes); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !ex.condition(child, scopes) {
+			child.Remove()
+			continue
+		}
+		if err := ex.execute(child, scopes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceText replaces the placeholders of the text nodes, keeping mixed
+// content in place, or of Content when it was set.
+func (ex *templateExecutor) replaceText(e *Element, scopes []templateScope) error {
+	if e.Content != e.text() {
+		replaced, err := ex.replace(e.Content, scopes)
+		e.Content = replaced
+		return err
+	}
+
+	for _, node := range e.Nodes {
+		if node.isText() {
+			replaced, err := ex.replace(node.Data, scopes)
+			if err != nil {
+				return err
+			}
+			node.Data = replaced
+		}
+	}
+	e.Content = e.text()
+	return nil
+}
+
+// condition evaluates and removes the data-if attribute of an element.
+func (ex *templateExecutor) condition(e *Element, scopes []templateScope) bool {
+	name, ok := e.Attributes[templateIf]
+	if !ok {
+		return true
+	}
+	delete(e.Attributes, templateIf)
+
+	name = strings.TrimSpace(name)
+	negate := strings.HasPrefix(name, "!")
+	if negate {
+		name = strings.TrimSpace(name[1:])
+	}
+
+	// a missing condition is false, even when strict
+	value, _ := lookupTemplate(name, scopes)
+	return truth(value) != negate
+}
+
+// repeat replaces an element with a data-repeat attribute by its copies.
+func (ex *templateExecutor) repeat(e *Element, scopes []templateScope) error {
+	expr := strings.TrimSpace(e.Attributes[templateRepeat])
+	alias, name := "", expr
+	if fields := strings.Fields(expr); len(fields) == 3 && fields[1] == "in" {
+		alias, name = fields[0], fields[2]
+	}
+
+	dx, dy, err := repeatOffset(e.Attributes[templateOffset])
+	if err != nil {
+		return fmt.Errorf("%s of <%s>: %w", templateOffset, e.Name, err)
+	}
+	delete(e.Attributes, templateRepeat)
+	delete(e.Attributes, templateOffset)
+
+	value, ok := lookupTemplate(name, scopes)
+	if !ok && ex.strict {
+		return fmt.Errorf("%s %q: %w", templateRepeat, name, ErrMissingValue)
+	}
+
+	list := reflect.ValueOf(value)
+	for list.Kind() == reflect.Ptr || list.Kind() == reflect.Interface {
+		list = list.Elem()
+	}
+	if ok && value != nil && list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return fmt.Errorf("%s %q: %T is not a list", templateRepeat, name, value)
+	}
+
+	parent := e.Parent()
+	for i := 0; list.IsValid() && i < list.Len(); i++ {
+		item := list.Index(i).Interface()
+		scope := templateScope{names: map[string]interface{}{"index": i}}
+		if alias != "" {
+			scope.names[alias] = item
+		} else {
+			scope.dot = item
+		}
+		inner := append(append([]templateScope{}, scopes...), scope)
+
+		clone := e.Clone()
+		if !ex.condition(clone, inner) {
+			continue
+		}
+
+		if i > 0 && (dx != 0 || dy != 0) {
+			transform := fmt.Sprintf("translate(%s %s)", formatNumber(dx*float64(i)), formatNumber(dy*float64(i)))
+			if t := strings.TrimSpace(clone.Attributes["transform"]); t != "" {
+				transform += " " + t
+			}
+			clone.Attributes["transform"] = transform
+		}
+
+		if err := ex.execute(clone, inner); err != nil {
+			return err
+		}
+		prefixIDs(clone, "item"+strconv.Itoa(i+1)+"-")
+		if err := parent.InsertBefore(clone, e); err != nil {
+			return err
+		}
+	}
+
+	e.Remove()
+	return nil
+}
+
+// repeatOffset parses the "x y" offset of repeated elements.
+func repeatOffset(value string) (float64, float64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	var offset [2]float64
+	if len(fields) > 2 {
+		return 0, 0, fmt.Errorf("%q is not an offset", value)
+	}
+	for i, field := range fields {
+		n, err := parseNumber(field)
+		if err != nil {
+			return 0, 0, err
+		}
+		offset[i] = n
+	}
+	return offset[0], offset[1], nil
+}
+
+// replace substitutes the placeholders of a value.
+func (ex *templateExecutor) replace(value string, scopes []templateScope) (string, error) {
+	if !strings.Contains(value, "{{") && !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var err error
+	replaced := placeholder.ReplaceAllStringFunc(value, func(match string) string {
+		groups := placeholder.FindStringSubmatch(match)
+		name := groups[1] + groups[2]
+
+		v, ok := lookupTemplate(name, scopes)
+		if !ok {
+			if ex.strict && err == nil {
+				err = fmt.Errorf("%q: %w", name, ErrMissingValue)
+			}
+			return ""
+		}
+		return formatTemplateValue(v)
+	})
+	return replaced, err
+}
+
+// lookupTemplate finds a dotted name in the scopes, the innermost first.
+func lookupTemplate(name string, scopes []templateScope) (interface{}, bool) {
+	path := strings.Split(name, ".")
+	for i := len(scopes) - 1; i >= 0; i-- {
+		scope := scopes[i]
+		if v, ok := scope.names[path[0]]; ok {
+			return lookupPath(v, path[1:])
+		}
+		if scope.dot != nil {
+			if v, ok := lookupPath(scope.dot, path); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookupPath follows the names of a path from a value.
+func lookupPath(value interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			found := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !found.IsValid() {
+				return nil, false
+			}
+			v = found
+
+		case reflect.Struct:
+			field, ok := v.Type().FieldByNameFunc(func(field string) bool {
+				return strings.EqualFold(field, name)
+			})
+			if !ok || field.PkgPath != "" {
+				return nil, false
+			}
+			v = v.FieldByIndex(field.Index)
+
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= v.Len() {
+				return nil, false
+			}
+			v = v.Index(i)
+
+		default:
+			return nil, false
+		}
+		value = v.Interface()
+	}
+	return value, true
+}
+
+// formatTemplateValue formats a value as text.
+func formatTemplateValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+	if s, ok := value.(fmt.Stringer); ok && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		return s.String()
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	if s, err := formatValue(v); err == nil {
+		return s
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// truth reports whether a value is neither false, zero, empty nor nil.
+func truth(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && truth(v.Elem().Interface())
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() > 0
+	case reflect.Struct:
+		return true
+	}
+	return !v.IsZero()
+}
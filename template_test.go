@@ -0,0 +1,114 @@
+package svg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type templateCustomer struct {
+	Name  string
+	Email *string
+}
+
+type templateLine struct {
+	Label  string
+	Amount float64
+	Paid   bool
+}
+
+func TestTemplate(t *testing.T) {
+	source := `<svg xmlns="http://www.w3.org/2000/svg">` +
+		`<text id="name">Dear {{customer.name}},<tspan> total ${ total }</tspan></text>` +
+		`<rect fill="{{brandColor}}" width="{{size}}"></rect>` +
+		`<image id="logo" data-if="showLogo"></image>` +
+		`<text id="empty" data-if="!lines">No lines</text>` +
+		`<g id="line" data-repeat="lines" data-repeat-offset="0 20" transform="translate(10 0)">` +
+		`<text>{{index}}. {{label}}: {{amount}}</text><circle data-if="paid"></circle></g>` +
+		`<text data-repeat="tag in tags" x="{{index}}">{{tag}}</text>` +
+		`</svg>`
+
+	template, err := ParseTemplate(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("ParseTemplate: unexpected error %v\n", err)
+	}
+
+	data := map[string]interface{}{
+		"customer":   templateCustomer{Name: "Ada"},
+		"total":      12.5,
+		"brandColor": "#f60",
+		"size":       10,
+		"showLogo":   false,
+		"lines": []templateLine{
+			{"Tea", 2.5, true},
+			{"Cake", 10, false},
+		},
+		"tags": []string{"a", "b"},
+	}
+
+	expected := `<svg xmlns="http://www.w3.org/2000/svg">` +
+		`<text id="name">Dear Ada,<tspan> total 12.5</tspan></text>` +
+		`<rect fill="#f60" width="10"></rect>` +
+		`<g id="item1-line" transform="translate(10 0)"><text>0. Tea: 2.5</text><circle></circle></g>` +
+		`<g id="item2-line" transform="translate(0 20) translate(10 0)"><text>1. Cake: 10</text></g>` +
+		`<text x="0">a</text><text x="1">b</text>` +
+		`</svg>`
+
+	SetSortAttributes(true)
+	for i := 0; i < 2; i++ {
+		// the template is left unchanged
+		result, err := template.Execute(data)
+		if err != nil {
+			t.Fatalf("Execute: unexpected error %v\n", err)
+		}
+		if actual, _ := render(result); actual != expected {
+			t.Errorf("Execute: expected %v, actual %v\n", expected, actual)
+		}
+	}
+
+	// conditions and lists which are empty
+	data["showLogo"] = true
+	data["lines"] = nil
+	result, err := template.Execute(data)
+	if err != nil {
+		t.Fatalf("Execute: unexpected error %v\n", err)
+	}
+	if result.FindID("logo") == nil || result.FindID("empty") == nil || len(result.FindAll("g")) != 0 {
+		t.Errorf("Execute: unexpected %v\n", result)
+	}
+}
+
+func TestTemplateStruct(t *testing.T) {
+	email := "ada@example.com"
+	template := NewTemplate(element("text", map[string]string{"title": "{{customer.email}}"}))
+
+	result, err := template.Execute(struct{ Customer templateCustomer }{templateCustomer{"Ada", &email}})
+	if err != nil {
+		t.Fatalf("Execute: unexpected error %v\n", err)
+	}
+	if actual := result.Attributes["title"]; actual != email {
+		t.Errorf("Execute: expected %v, actual %v\n", email, actual)
+	}
+}
+
+func TestTemplateMissing(t *testing.T) {
+	root, _ := parse(`<svg><text>{{missing}}!</text><g data-repeat="nothing"><rect/></g></svg>`, false)
+	template := NewTemplate(root)
+
+	result, err := template.Execute(map[string]string{})
+	if err != nil {
+		t.Fatalf("Execute: unexpected error %v\n", err)
+	}
+	if actual := result.Children[0].Content; actual != "!" || len(result.Children) != 1 {
+		t.Errorf("Execute: unexpected %v\n", result)
+	}
+
+	template.Strict = true
+	if _, err := template.Execute(map[string]string{}); !errors.Is(err, ErrMissingValue) {
+		t.Errorf("Execute: expected %v, actual %v\n", ErrMissingValue, err)
+	}
+
+	if _, err := NewTemplate(element("g", map[string]string{"data-if": "x"})).Execute(nil); !errors.Is(err, ErrNotChild) {
+		t.Errorf("Execute: expected %v, actual %v\n", ErrNotChild, err)
+	}
+}
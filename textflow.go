@@ -0,0 +1,514 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/galihrivanto/svg/utils"
+)
+
+// FontFace describes the font text is set in.
+type FontFace struct {
+	Family string
+	// Size is the font size in user units.
+	Size float64
+	// Weight is 400 for normal text, 700 for bold.
+	Weight int
+	Italic bool
+}
+
+// TextMeasurer measures text, such as a Font.
+type TextMeasurer interface {
+	// MeasureText returns the advance width of the text in user units.
+	MeasureText(text string, face FontFace) float64
+}
+
+// FlowOptions controls how SetContentFlow lays text out.
+type FlowOptions struct {
+	// Measurer measures the lines, it is required.
+	Measurer TextMeasurer
+
+	// Width and Height of the box the text flows into, when not zero.
+	// Otherwise they come from the flowRegion of a flowRoot, the shape
+	// of shape-inside or the inline-size of the text.
+	Width, Height float64
+
+	// LineHeight is the distance between baselines in font sizes. The
+	// line-height of the text is used when zero, 1.25 when it is normal.
+	LineHeight float64
+
+	// ShrinkToFit decreases the font size until the text fits the box,
+	// but not below MinFontSize, 1 when zero.
+	ShrinkToFit bool
+	MinFontSize float64
+}
+
+// SetContentFlow replaces the text of the element of the id, wrapping it
+// to the width of its box. Line breaks in text start new paragraphs.
+//
+// A <text> gets one <tspan> per line, copied from its first tspan, at the
+// x of the text and one line height apart. Its box is the shape its
+// shape-inside style references, or its inline-size. An Inkscape
+// <flowRoot> gets one <flowPara> per paragraph, the box being the first
+// rect of its <flowRegion>, and wraps them itself.
+func SetContentFlow(root *Element, id string, text string, options FlowOptions) error {
+	el := findID(root, id)
+	if el == nil {
+		return ErrElementNotFound
+	}
+	if options.Measurer == nil {
+		return ErrNoMeasurer
+	}
+
+	// lines and paragraphs belong to their text
+	for el != nil && el.LocalName() != "text" && el.LocalName() != "flowRoot" {
+		el = el.Parent()
+	}
+	if el == nil {
+		return fmt.Errorf("%q is not text: %w", id, ErrNoTextBox)
+	}
+
+	flow, err := newTextFlow(el, options)
+	if err != nil {
+		return fmt.Errorf("%q: %w", id, err)
+	}
+
+	paragraphs := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lines := flow.fit(paragraphs, options)
+	if el.LocalName() == "flowRoot" {
+		flow.setParagraphs(paragraphs)
+	} else {
+		flow.setLines(lines)
+	}
+	return nil
+}
+
+// SetContentFlow replaces the text of the element of the id, wrapping it
+// to the width of its box.
+func (d *Document) SetContentFlow(id string, text string, options FlowOptions) error {
+	return SetContentFlow(d.Root, id, text, options)
+}
+
+// textFlow is the layout of a text element.
+type textFlow struct {
+	text *Element
+	// first line or paragraph, copied for the others
+	line *Element
+
+	face       FontFace
+	lineHeight float64
+	measurer   TextMeasurer
+
+	// x and y of the first baseline, or of the top of the box when top
+	// is set
+	x, y          float64
+	top           bool
+	width, height float64
+}
+
+func newTextFlow(text *Element, options FlowOptions) (*textFlow, error) {
+	f := &textFlow{text: text, measurer: options.Measurer}
+
+	lineName := "tspan"
+	if text.LocalName() == "flowRoot" {
+		lineName = "flowPara"
+	}
+	for _, child := range text.Children {
+		if child.LocalName() == lineName {
+			f.line = child
+			break
+		}
+	}
+
+	styled := text
+	if f.line != nil {
+		styled = f.line
+	}
+	f.face = computedFace(styled)
+
+	f.lineHeight = options.LineHeight
+	if f.lineHeight <= 0 {
+		f.lineHeight = lineHeight(computedStyle(styled, "line-height"), f.face.Size)
+	}
+
+	switch box := textBox(text); {
+	case box != nil:
+		f.x, _ = imageLength(box.Attributes["x"])
+		f.y, _ = imageLength(box.Attributes["y"])
+		f.width, _ = imageLength(box.Attributes["width"])
+		f.height, _ = imageLength(box.Attributes["height"])
+		f.top = true
+
+	default:
+		f.x, f.y = textPosition(text, f.line)
+		if size, ok := absoluteLength(computedStyle(styled, "inline-size")); ok {
+			f.width = size
+		}
+	}
+
+	if options.Width > 0 {
+		f.width = options.Width
+	}
+	if options.Height > 0 {
+		f.height = options.Height
+	}
+	if f.width <= 0 {
+		return nil, ErrNoTextBox
+	}
+	return f, nil
+}
+
+// textBox returns the shape text flows into, the first rect of the
+// flowRegion of a flowRoot or the shape referenced by shape-inside.
+func textBox(text *Element) *Element {
+	if text.LocalName() == "flowRoot" {
+		for _, child := range text.Children {
+			if child.LocalName() != "flowRegion" {
+				continue
+			}
+			for _, shape := range child.Children {
+				if shape.LocalName() == "rect" {
+					return shape
+				}
+			}
+		}
+		return nil
+	}
+
+	ids := urlReferences(computedStyle(text, "shape-inside"))
+	if len(ids) == 0 {
+		return nil
+	}
+	if shape := referenceLookup(text)(ids[0]); shape != nil && shape.LocalName() == "rect" {
+		return shape
+	}
+	return nil
+}
+
+// textPosition returns the position of the first line of a text.
+func textPosition(text, line *Element) (float64, float64) {
+	position := func(name string) float64 {
+		for _, el := range []*Element{line, text} {
+			if el == nil {
+				continue
+			}
+			// the first of a list of coordinates
+			if fields := strings.Fields(strings.ReplaceAll(el.Attributes[name], ",", " ")); len(fields) > 0 {
+				n, _ := imageLength(fields[0])
+				return n
+			}
+		}
+		return 0
+	}
+	return position("x"), position("y")
+}
+
+// fit wraps the paragraphs, shrinking the font when needed and asked to.
+func (f *textFlow) fit(paragraphs []string, options FlowOptions) []string {
+	lines, fits := f.wrap(paragraphs, f.face.Size)
+	if fits || !options.ShrinkToFit {
+		return lines
+	}
+
+	min := options.MinFontSize
+	if min <= 0 {
+		min = 1
+	}
+	if min >= f.face.Size {
+		return lines
+	}
+
+	// the largest size fitting, to a hundredth
+	low, high := min, f.face.Size
+	for high-low > 0.01 {
+		middle := (low + high) / 2
+		if _, fits := f.wrap(paragraphs, middle); fits {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	size := math.Floor(high*100) / 100
+	if _, fits := f.wrap(paragraphs, size); !fits {
+		size = math.Floor(low*100) / 100
+	}
+	f.setFontSize(size)
+	lines, _ = f.wrap(paragraphs, f.face.Size)
+	return lines
+}
+
+// wrap breaks paragraphs into lines at a font size, reporting whether
+// they fit in the box.
+func (f *textFlow) wrap(paragraphs []string, size float64) ([]string, bool) {
+	face := f.face
+	face.Size = size
+
+	fits := true
+	var lines []string
+	for _, paragraph := range paragraphs {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := words[0]
+		for _, word := range words[1:] {
+			if f.measurer.MeasureText(line+" "+word, face) <= f.width {
+				line += " " + word
+				continue
+			}
+			fits = fits && f.measurer.MeasureText(line, face) <= f.width
+			lines = append(lines, line)
+			line = word
+		}
+		fits = fits && f.measurer.MeasureText(line, face) <= f.width
+		lines = append(lines, line)
+	}
+
+	if f.height > 0 {
+		// the first line takes one font size, the others a line height
+		used := size + float64(len(lines)-1)*f.lineHeight*size
+		fits = fits && used <= f.height
+	}
+	return lines, fits
+}
+
+// setFontSize changes the font size where it is set, on the first line
+// or on the text.
+func (f *textFlow) setFontSize(size float64) {
+	f.face.Size = size
+
+	target := f.text
+	if f.line != nil {
+		if _, ok := styleProperty(f.line, "font-size"); ok {
+			target = f.line
+		}
+	}
+
+	value := formatNumber(size) + "px"
+	if _, ok := target.Attributes["font-size"]; ok && !strings.Contains(target.Attributes["style"], "font-size") {
+		target.Attributes["font-size"] = value
+		return
+	}
+	target.Attributes["style"] = setStyle(target.Attributes["style"], "font-size", value)
+}
+
+// setLines replaces the content of a text by a tspan per line.
+func (f *textFlow) setLines(lines []string) {
+	template := f.lineTemplate("tspan")
+	delete(template.Attributes, "dx")
+	delete(template.Attributes, "dy")
+
+	y := f.y
+	if f.top {
+		y += f.face.Size
+	}
+	for i, line := range lines {
+		tspan := template.Clone()
+		if i == 0 && f.line != nil {
+			if id, ok := f.line.Attributes["id"]; ok {
+				tspan.Attributes["id"] = id
+			}
+		}
+		tspan.Attributes["x"] = formatNumber(f.x)
+		tspan.Attributes["y"] = formatNumber(y + float64(i)*f.lineHeight*f.face.Size)
+		tspan.Content = line
+		f.text.AppendChild(tspan)
+	}
+}
+
+// setParagraphs replaces the paragraphs of a flowRoot.
+func (f *textFlow) setParagraphs(paragraphs []string) {
+	template := f.lineTemplate("flowPara")
+	for i, paragraph := range paragraphs {
+		para := template.Clone()
+		if i == 0 && f.line != nil {
+			if id, ok := f.line.Attributes["id"]; ok {
+				para.Attributes["id"] = id
+			}
+		}
+		para.Content = paragraph
+		f.text.AppendChild(para)
+	}
+}
+
+// lineTemplate removes the lines of the text, and its own text, and
+// returns an empty copy of the first line.
+func (f *textFlow) lineTemplate(name string) *Element {
+	var template *Element
+	if f.line != nil {
+		template = f.line.Clone()
+		template.Children = []*Element{}
+		template.Nodes = nil
+		template.Content = ""
+		delete(template.Attributes, "id")
+	} else {
+		template = &Element{
+			Name:       strings.TrimSuffix(f.text.Name, f.text.LocalName()) + name,
+			Space:      f.text.Space,
+			Namespaces: f.text.Namespaces,
+			Attributes: make(map[string]string),
+			Children:   []*Element{},
+		}
+	}
+
+	children := make([]*Element, len(f.text.Children))
+	copy(children, f.text.Children)
+	for _, child := range children {
+		if child.LocalName() == name || child.LocalName() == "flowDiv" {
+			child.Remove()
+		}
+	}
+
+	var nodes []*Node
+	for _, node := range f.text.Nodes {
+		if !node.isText() {
+			nodes = append(nodes, node)
+		}
+	}
+	f.text.Nodes = nodes
+	f.text.Content = ""
+	return template
+}
+
+// styleProperty returns a property of the style attribute of the
+// element, or else its presentation attribute.
+func styleProperty(e *Element, property string) (string, bool) {
+	value, found := "", false
+	for _, style := range utils.StyleParser(e.Attributes["style"]) {
+		if style.Property == property {
+			value, found = strings.TrimSpace(style.Value), true
+		}
+	}
+	if found {
+		return value, true
+	}
+
+	value, found = e.Attributes[property]
+	return strings.TrimSpace(value), found
+}
+
+// computedStyle returns a property of the element, inherited from its
+// ancestors.
+func computedStyle(e *Element, property string) string {
+	for el := e; el != nil; el = el.Parent() {
+		if value, ok := styleProperty(el, property); ok && value != "inherit" {
+			return value
+		}
+	}
+	return ""
+}
+
+// computedFace returns the font of an element.
+func computedFace(e *Element) FontFace {
+	face := FontFace{
+		Family: "sans-serif",
+		Size:   fontSize(e),
+		Weight: 400,
+	}
+
+	if family := computedStyle(e, "font-family"); family != "" {
+		face.Family = strings.Trim(strings.TrimSpace(strings.Split(family, ",")[0]), `'"`)
+	}
+
+	switch weight := computedStyle(e, "font-weight"); weight {
+	case "bold", "bolder":
+		face.Weight = 700
+	case "lighter":
+		face.Weight = 300
+	default:
+		if n, err := strconv.Atoi(weight); err == nil {
+			face.Weight = n
+		}
+	}
+
+	switch computedStyle(e, "font-style") {
+	case "italic", "oblique":
+		face.Italic = true
+	}
+	return face
+}
+
+// fontSize returns the font size of an element in user units, 16 by
+// default.
+func fontSize(e *Element) float64 {
+	const medium = 16
+
+	for el := e; el != nil; el = el.Parent() {
+		value, ok := styleProperty(el, "font-size")
+		if !ok || value == "inherit" {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(value, "%"):
+			if n, err := parseNumber(strings.TrimSuffix(value, "%")); err == nil {
+				return n / 100 * fontSize(el.Parent())
+			}
+		case strings.HasSuffix(value, "rem"):
+			if n, err := parseNumber(strings.TrimSuffix(value, "rem")); err == nil {
+				return n * medium
+			}
+		case strings.HasSuffix(value, "em"):
+			if n, err := parseNumber(strings.TrimSuffix(value, "em")); err == nil {
+				return n * fontSize(el.Parent())
+			}
+		default:
+			if n, ok := absoluteLength(value); ok {
+				return n
+			}
+		}
+	}
+	return medium
+}
+
+// lineHeight returns a line-height in font sizes.
+func lineHeight(value string, size float64) float64 {
+	switch {
+	case value == "" || value == "normal":
+		return 1.25
+	case strings.HasSuffix(value, "%"):
+		if n, err := parseNumber(strings.TrimSuffix(value, "%")); err == nil {
+			return n / 100
+		}
+	default:
+		if n, err := parseNumber(value); err == nil {
+			return n
+		}
+		if n, ok := absoluteLength(value); ok && size > 0 {
+			return n / size
+		}
+	}
+	return 1.25
+}
+
+// lengthUnits are the sizes of the absolute length units in user units.
+var lengthUnits = map[string]float64{
+	"px": 1,
+	"pt": 4.0 / 3,
+	"pc": 16,
+	"mm": 96 / 25.4,
+	"cm": 96 / 2.54,
+	"in": 96,
+}
+
+// absoluteLength parses a length in user units or in an absolute unit.
+func absoluteLength(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	factor := 1.0
+	if len(value) > 2 {
+		if unit, ok := lengthUnits[value[len(value)-2:]]; ok {
+			value, factor = value[:len(value)-2], unit
+		}
+	}
+
+	n, err := parseNumber(value)
+	if err != nil {
+		return 0, false
+	}
+	return n * factor, true
+}
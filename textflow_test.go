@@ -0,0 +1,128 @@
+package svg
+
+import (
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
+
+// monospace measures every character half a font size wide.
+type monospace struct{}
+
+func (monospace) MeasureText(text string, face FontFace) float64 {
+	return float64(utf8.RuneCountInString(text)) * face.Size / 2
+}
+
+func TestSetContentFlow(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		svg      string
+		id       string
+		text     string
+		options  FlowOptions
+		expected string
+	}{
+		{
+			"text with lines",
+			`<svg><text id="t" x="10" y="20" style="font-size:10px;line-height:1.5"><tspan id="first" x="10" y="20" style="fill:red">old</tspan><tspan x="10" y="35">stale</tspan></text></svg>`,
+			"t", "one two three four\nfive", FlowOptions{Width: 40},
+			`<svg><text id="t" style="font-size:10px;line-height:1.5" x="10" y="20">` +
+				`<tspan id="first" style="fill:red" x="10" y="20">one two</tspan>` +
+				`<tspan style="fill:red" x="10" y="35">three</tspan>` +
+				`<tspan style="fill:red" x="10" y="50">four</tspan>` +
+				`<tspan style="fill:red" x="10" y="65">five</tspan></text></svg>`,
+		},
+		{
+			"plain text by its tspan",
+			`<svg font-size="8"><text x="5" y="5" inline-size="24"><tspan id="t">old</tspan></text></svg>`,
+			"t", "aa bb cc", FlowOptions{LineHeight: 2},
+			`<svg font-size="8"><text inline-size="24" x="5" y="5">` +
+				`<tspan id="t" x="5" y="5">aa bb</tspan><tspan x="5" y="21">cc</tspan></text></svg>`,
+		},
+		{
+			"shape-inside",
+			`<svg><defs><rect id="box" x="10" y="10" width="50" height="100"/></defs>` +
+				`<text id="t" style="shape-inside:url(#box);font-size:10px">old</text></svg>`,
+			"t", "hello world", FlowOptions{},
+			`<svg><defs><rect height="100" id="box" width="50" x="10" y="10"></rect></defs>` +
+				`<text id="t" style="shape-inside:url(#box);font-size:10px">` +
+				`<tspan x="10" y="20">hello</tspan><tspan x="10" y="32.5">world</tspan></text></svg>`,
+		},
+		{
+			"shrink to fit",
+			`<svg><text id="t" x="0" y="0" font-size="20">old</text></svg>`,
+			"t", "abcdefgh ij", FlowOptions{Width: 40, Height: 30, ShrinkToFit: true},
+			`<svg><text font-size="10px" id="t" x="0" y="0">` +
+				`<tspan x="0" y="0">abcdefgh</tspan><tspan x="0" y="12.5">ij</tspan></text></svg>`,
+		},
+		{
+			"shrink to the minimum",
+			`<svg><text id="t" x="0" y="0" style="font-size:20px">old</text></svg>`,
+			"t", "abcdefgh", FlowOptions{Width: 10, ShrinkToFit: true, MinFontSize: 4},
+			`<svg><text id="t" style="font-size:4px" x="0" y="0"><tspan x="0" y="0">abcdefgh</tspan></text></svg>`,
+		},
+		{
+			"flowRoot",
+			`<svg><flowRoot id="t" style="font-size:10px"><flowRegion><rect x="0" y="0" width="30" height="20"/></flowRegion>` +
+				`<flowPara style="fill:blue">old</flowPara><flowPara>stale</flowPara></flowRoot></svg>`,
+			"t", "wrapped by the renderer\nsecond", FlowOptions{ShrinkToFit: true},
+			`<svg><flowRoot id="t" style="font-size:5px"><flowRegion><rect height="20" width="30" x="0" y="0"></rect></flowRegion>` +
+				`<flowPara style="fill:blue">wrapped by the renderer</flowPara><flowPara style="fill:blue">second</flowPara></flowRoot></svg>`,
+		},
+	}
+
+	SetSortAttributes(true)
+	for _, test := range testCases {
+		root, _ := parse(test.svg, false)
+		test.options.Measurer = monospace{}
+		if err := SetContentFlow(root, test.id, test.text, test.options); err != nil {
+			t.Errorf("%s: unexpected error %v\n", test.name, err)
+			continue
+		}
+		if actual, _ := render(root); actual != test.expected {
+			t.Errorf("%s: expected %v, actual %v\n", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestSetContentFlowErrors(t *testing.T) {
+	root, _ := parse(`<svg><text id="t">old</text><rect id="r"/></svg>`, false)
+	doc := NewDocument(root)
+
+	var testCases = []struct {
+		id       string
+		options  FlowOptions
+		expected error
+	}{
+		{"missing", FlowOptions{Measurer: monospace{}}, ErrElementNotFound},
+		{"t", FlowOptions{}, ErrNoMeasurer},
+		{"t", FlowOptions{Measurer: monospace{}}, ErrNoTextBox},
+		{"r", FlowOptions{Measurer: monospace{}, Width: 10}, ErrNoTextBox},
+	}
+
+	for _, test := range testCases {
+		if err := doc.SetContentFlow(test.id, "text", test.options); !errors.Is(err, test.expected) {
+			t.Errorf("SetContentFlow %s: expected %v, actual %v\n", test.id, test.expected, err)
+		}
+	}
+}
+
+func TestComputedFace(t *testing.T) {
+	root, _ := parse(`<svg style="font-family:'DejaVu Sans', sans-serif;font-size:12pt">`+
+		`<g font-weight="bold" font-size="150%"><text id="a" style="font-style:italic;font-size:0.5em"/></g>`+
+		`<text id="b" font-size="1rem" font-weight="300"/></svg>`, false)
+
+	var testCases = []struct {
+		id       string
+		expected FontFace
+	}{
+		{"a", FontFace{Family: "DejaVu Sans", Size: 12, Weight: 700, Italic: true}},
+		{"b", FontFace{Family: "DejaVu Sans", Size: 16, Weight: 300}},
+	}
+
+	for _, test := range testCases {
+		if actual := computedFace(root.FindID(test.id)); actual != test.expected {
+			t.Errorf("computedFace %s: expected %+v, actual %+v\n", test.id, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package svg
+
+// Parent returns the parent of the element, or nil for the root and for
+// elements which are not part of a tree.
+func (e *Element) Parent() *Element {
+	return e.parent
+}
+
+// Index returns the position of the element among the children of its
+// parent, or -1 when it has no parent.
+func (e *Element) Index() int {
+	if e.parent == nil {
+		return -1
+	}
+
+	for i, child := range e.parent.Children {
+		if child == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// Siblings returns the other children of the parent of the element.
+func (e *Element) Siblings() []*Element {
+	if e.parent == nil {
+		return nil
+	}
+
+	var siblings []*Element
+	for _, child := range e.parent.Children {
+		if child != e {
+			siblings = append(siblings, child)
+		}
+	}
+	return siblings
+}
+
+// AppendChild adds child as the last child of the element. The child is
+// removed from its previous parent first.
+func (e *Element) AppendChild(child *Element) error {
+	return e.insert(child, true, func() int { return len(e.Children) })
+}
+
+// InsertBefore adds child to the element right before ref, which must be
+// a child of the element.
+func (e *Element) InsertBefore(child, ref *Element) error {
+	if child == ref && e.childIndex(ref) >= 0 {
+		return nil
+	}
+	return e.insert(child, false, func() int { return e.childIndex(ref) })
+}
+
+// InsertAfter adds child to the element right after ref, which must be
+// a child of the element.
+func (e *Element) InsertAfter(child, ref *Element) error {
+	if child == ref && e.childIndex(ref) >= 0 {
+		return nil
+	}
+	return e.insert(child, true, func() int {
+		if i := e.childIndex(ref); i >= 0 {
+			return i + 1
+		}
+		return -1
+	})
+}
+
+// Remove detaches the element from its parent.
+func (e *Element) Remove() {
+	if i := e.Index(); i >= 0 {
+		if d := e.document(); d != nil {
+			d.remove(e)
+		}
+		e.parent.removeAt(i)
+	}
+	e.parent = nil
+}
+
+// ReplaceWith puts other in place of the element, which is detached.
+func (e *Element) ReplaceWith(other *Element) error {
+	if e.parent == nil {
+		return ErrNotChild
+	}
+	if other == e {
+		return nil
+	}
+
+	parent := e.parent
+	if err := parent.InsertBefore(other, e); err != nil {
+		return err
+	}
+	e.Remove()
+	return nil
+}
+
+func (e *Element) childIndex(child *Element) int {
+	if child == nil || child.parent != e {
+		return -1
+	}
+	return child.Index()
+}
+
+// contains reports whether other is the element or one of its
+// descendants.
+func (e *Element) contains(other *Element) bool {
+	for ; other != nil; other = other.parent {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+// insert moves child into the element at the index returned by at, which
+// is evaluated once the child was detached. See insertAt for after.
+func (e *Element) insert(child *Element, after bool, at func() int) error {
+	if child == nil || child.contains(e) {
+		return ErrHierarchy
+	}
+
+	// the reference must exist before anything is changed
+	if at() < 0 {
+		return ErrNotChild
+	}
+
+	child.Remove()
+	e.insertAt(at(), child, after)
+	return nil
+}
+
+// insertAt adds child at index i of Children, keeping Nodes in line. The
+// child node is put right after the previous child when after is set,
+// otherwise right before the next child, so that comments and text
+// stay attached to the other side.
+func (e *Element) insertAt(i int, child *Element, after bool) {
+	if len(e.Nodes) > 0 {
+		slot := &Node{Type: ElementNode}
+		previous, next := e.elementNode(i-1), e.elementNode(i)
+		switch {
+		case previous >= 0 && (after || next < 0):
+			e.Nodes = insertNode(e.Nodes, previous+1, slot)
+		case next >= 0:
+			e.Nodes = insertNode(e.Nodes, next, slot)
+		default:
+			e.Nodes = append(e.Nodes, slot)
+		}
+	}
+
+	e.Children = append(e.Children, nil)
+	copy(e.Children[i+1:], e.Children[i:])
+	e.Children[i] = child
+	child.parent = e
+
+	if d := e.document(); d != nil {
+		d.add(child)
+	}
+}
+
+// removeAt removes the child at index i of Children, keeping Nodes in
+// line.
+func (e *Element) removeAt(i int) {
+	if n := e.elementNode(i); n >= 0 {
+		e.Nodes = append(e.Nodes[:n], e.Nodes[n+1:]...)
+	}
+
+	e.Children = append(e.Children[:i], e.Children[i+1:]...)
+}
+
+// elementNode returns the index in Nodes of the placeholder of the i-th
+// child, or -1 when there is none.
+func (e *Element) elementNode(i int) int {
+	if i < 0 {
+		return -1
+	}
+
+	for n, node := range e.Nodes {
+		if node.Type == ElementNode {
+			if i == 0 {
+				return n
+			}
+			i--
+		}
+	}
+	return -1
+}
+
+func insertNode(nodes []*Node, i int, node *Node) []*Node {
+	nodes = append(nodes, nil)
+	copy(nodes[i+1:], nodes[i:])
+	nodes[i] = node
+	return nodes
+}
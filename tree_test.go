@@ -0,0 +1,97 @@
+package svg
+
+import (
+	"testing"
+)
+
+func names(elements []*Element) []string {
+	var result []string
+	for _, e := range elements {
+		result = append(result, e.Attributes["id"])
+	}
+	return result
+}
+
+func equalNames(t *testing.T, name string, expected []string, elements []*Element) {
+	actual := names(elements)
+	if len(expected) != len(actual) {
+		t.Errorf("%s: expected %v, actual %v\n", name, expected, actual)
+		return
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Errorf("%s: expected %v, actual %v\n", name, expected, actual)
+			return
+		}
+	}
+}
+
+func TestParent(t *testing.T) {
+	root := testElement()
+	rect := root.FindID("inFirst")
+
+	if rect.Parent() != root.FindID("first") || rect.Parent().Parent() != root || root.Parent() != nil {
+		t.Errorf("Parent: unexpected parent chain\n")
+	}
+
+	if rect.Index() != 0 || root.FindID("second").Index() != 1 || root.Index() != -1 {
+		t.Errorf("Index: unexpected index\n")
+	}
+
+	equalNames(t, "Siblings", []string{"second"}, root.FindID("first").Siblings())
+}
+
+func TestTreeMutation(t *testing.T) {
+	root, _ := parse(`<svg><g id="a"/><g id="b"/><g id="c"/></svg>`, false)
+	a, b, c := root.Children[0], root.Children[1], root.Children[2]
+	d := element("g", map[string]string{"id": "d"})
+
+	if err := root.AppendChild(d); err != nil || d.Parent() != root {
+		t.Errorf("AppendChild: unexpected error %v\n", err)
+	}
+	equalNames(t, "AppendChild", []string{"a", "b", "c", "d"}, root.Children)
+
+	root.InsertBefore(d, a)
+	equalNames(t, "InsertBefore", []string{"d", "a", "b", "c"}, root.Children)
+
+	root.InsertAfter(a, c)
+	equalNames(t, "InsertAfter", []string{"d", "b", "c", "a"}, root.Children)
+
+	b.Remove()
+	equalNames(t, "Remove", []string{"d", "c", "a"}, root.Children)
+	if b.Parent() != nil {
+		t.Errorf("Remove: parent should be cleared\n")
+	}
+
+	c.ReplaceWith(b)
+	equalNames(t, "ReplaceWith", []string{"d", "b", "a"}, root.Children)
+
+	a.AppendChild(c)
+	equalNames(t, "AppendChild", []string{"c"}, a.Children)
+
+	if err := c.AppendChild(root); err != ErrHierarchy {
+		t.Errorf("AppendChild: expected %v, actual %v\n", ErrHierarchy, err)
+	}
+
+	if err := root.InsertBefore(d, c); err != ErrNotChild {
+		t.Errorf("InsertBefore: expected %v, actual %v\n", ErrNotChild, err)
+	}
+
+	expected := `<svg><g id="d"></g><g id="b"></g><g id="a"><g id="c"></g></g></svg>`
+	if actual, _ := render(root); actual != expected {
+		t.Errorf("Mutation: expected %v, actual %v\n", expected, actual)
+	}
+}
+
+func TestTreeMutationKeepsNodes(t *testing.T) {
+	root, _ := parse(`<svg><!-- a --><g id="a"/><!-- b --><g id="b"/></svg>`, false)
+	a := root.Children[0]
+
+	root.InsertAfter(element("g", map[string]string{"id": "new"}), a)
+	root.Children[2].Remove()
+
+	expected := `<svg><!-- a --><g id="a"></g><g id="new"></g><!-- b --></svg>`
+	if actual, _ := render(root); actual != expected {
+		t.Errorf("Mutation: expected %v, actual %v\n", expected, actual)
+	}
+}
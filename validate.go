@@ -0,0 +1,664 @@
+package svg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/galihrivanto/svg/utils"
+)
+
+// Violation describes a single rule of the SVG specification broken
+// by an element.
+type Violation struct {
+	// Path locates the element in the document, e.g. /svg/g[1]/rect[2].
+	Path    string
+	Element *Element
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// element categories as defined by the SVG specification
+const (
+	catAnimation = 1 << iota
+	catDescriptive
+	catShape
+	catStructural
+	catGradient
+	catFilterPrimitive
+	catLightSource
+	catTextContentChild
+	catFont
+	catOther
+)
+
+// content models shared by several elements
+const (
+	containerContent = catAnimation | catDescriptive | catShape | catStructural | catGradient | catFont | catOther
+	graphicContent   = catAnimation | catDescriptive
+)
+
+// svg version in which an element is defined
+const (
+	svg11 = 1 << iota
+	svg2
+	svgAll = svg11 | svg2
+)
+
+type elementRule struct {
+	category int
+	version  int
+
+	// allowed child categories and additional names
+	content  int
+	children []string
+
+	// any child is allowed, including unknown elements
+	anyContent bool
+
+	// required attributes, alternatives are separated by '|'
+	required []string
+}
+
+var elementRules = map[string]elementRule{
+	// animation elements
+	"animate":          {category: catAnimation, version: svgAll, content: catDescriptive},
+	"animateColor":     {category: catAnimation, version: svg11, content: catDescriptive},
+	"animateMotion":    {category: catAnimation, version: svgAll, content: catDescriptive, children: []string{"mpath"}},
+	"animateTransform": {category: catAnimation, version: svgAll, content: catDescriptive},
+	"set":              {category: catAnimation, version: svgAll, content: catDescriptive},
+	"discard":          {category: catAnimation, version: svg2, content: catDescriptive},
+	"mpath":            {category: catOther, version: svgAll, content: catDescriptive},
+
+	// descriptive elements
+	"desc":     {category: catDescriptive, version: svgAll},
+	"title":    {category: catDescriptive, version: svgAll},
+	"metadata": {category: catDescriptive, version: svgAll, anyContent: true},
+
+	// shape elements
+	"circle":   {category: catShape, version: svgAll, content: graphicContent, required: []string{"r"}},
+	"ellipse":  {category: catShape, version: svgAll, content: graphicContent, required: []string{"rx", "ry"}},
+	"line":     {category: catShape, version: svgAll, content: graphicContent},
+	"path":     {category: catShape, version: svgAll, content: graphicContent, required: []string{"d"}},
+	"polygon":  {category: catShape, version: svgAll, content: graphicContent, required: []string{"points"}},
+	"polyline": {category: catShape, version: svgAll, content: graphicContent, required: []string{"points"}},
+	"rect":     {category: catShape, version: svgAll, content: graphicContent, required: []string{"width", "height"}},
+
+	// structural elements
+	"defs":   {category: catStructural, version: svgAll, content: containerContent},
+	"g":      {category: catStructural, version: svgAll, content: containerContent},
+	"svg":    {category: catStructural, version: svgAll, content: containerContent},
+	"symbol": {category: catStructural, version: svgAll, content: containerContent},
+	"use":    {category: catStructural, version: svgAll, content: graphicContent, required: []string{"href|xlink:href"}},
+
+	// gradient elements
+	"linearGradient": {category: catGradient, version: svgAll, content: catDescriptive, children: []string{"animate", "animateTransform", "set", "stop"}},
+	"radialGradient": {category: catGradient, version: svgAll, content: catDescriptive, children: []string{"animate", "animateTransform", "set", "stop"}},
+	"stop":           {category: catOther, version: svgAll, children: []string{"animate", "animateColor", "set"}},
+
+	// text content elements
+	"text":     {category: catOther, version: svgAll, content: catAnimation | catDescriptive | catTextContentChild, children: []string{"a"}},
+	"tspan":    {category: catTextContentChild, version: svgAll, content: catDescriptive, children: []string{"a", "altGlyph", "animate", "animateColor", "set", "tref", "tspan"}},
+	"textPath": {category: catTextContentChild, version: svgAll, content: catDescriptive, children: []string{"a", "altGlyph", "animate", "animateColor", "set", "tref", "tspan"}},
+	"tref":     {category: catTextContentChild, version: svg11, content: catDescriptive, children: []string{"animate", "animateColor", "set"}, required: []string{"xlink:href"}},
+	"altGlyph": {category: catTextContentChild, version: svg11},
+
+	// filter elements
+	"filter":              {category: catOther, version: svgAll, content: catDescriptive | catFilterPrimitive, children: []string{"animate", "set"}},
+	"feBlend":             {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feColorMatrix":       {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feComponentTransfer": {category: catFilterPrimitive, version: svgAll, children: []string{"feFuncA", "feFuncB", "feFuncG", "feFuncR"}},
+	"feComposite":         {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feConvolveMatrix":    {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}, required: []string{"kernelMatrix"}},
+	"feDiffuseLighting":   {category: catFilterPrimitive, version: svgAll, content: catDescriptive | catLightSource},
+	"feDisplacementMap":   {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feDropShadow":        {category: catFilterPrimitive, version: svg2, children: []string{"animate", "set"}},
+	"feFlood":             {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "animateColor", "set"}},
+	"feGaussianBlur":      {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feImage":             {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "animateTransform", "set"}},
+	"feMerge":             {category: catFilterPrimitive, version: svgAll, children: []string{"feMergeNode"}},
+	"feMorphology":        {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feOffset":            {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feSpecularLighting":  {category: catFilterPrimitive, version: svgAll, content: catDescriptive | catLightSource},
+	"feTile":              {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feTurbulence":        {category: catFilterPrimitive, version: svgAll, children: []string{"animate", "set"}},
+	"feFuncA":             {category: catOther, version: svgAll, children: []string{"animate", "set"}},
+	"feFuncB":             {category: catOther, version: svgAll, children: []string{"animate", "set"}},
+	"feFuncG":             {category: catOther, version: svgAll, children: []string{"animate", "set"}},
+	"feFuncR":             {category: catOther, version: svgAll, children: []string{"animate", "set"}},
+	"feMergeNode":         {category: catOther, version: svgAll, children: []string{"animate", "set"}},
+	"feDistantLight":      {category: catLightSource, version: svgAll, children: []string{"animate", "set"}},
+	"fePointLight":        {category: catLightSource, version: svgAll, children: []string{"animate", "set"}},
+	"feSpotLight":         {category: catLightSource, version: svgAll, children: []string{"animate", "set"}},
+
+	// font elements
+	"font":             {category: catFont, version: svg11, content: catDescriptive, children: []string{"font-face", "glyph", "hkern", "missing-glyph", "vkern"}},
+	"font-face":        {category: catFont, version: svg11, content: catDescriptive, children: []string{"font-face-src", "definition-src"}},
+	"font-face-src":    {category: catOther, version: svg11, children: []string{"font-face-name", "font-face-uri"}},
+	"font-face-uri":    {category: catOther, version: svg11, children: []string{"font-face-format"}},
+	"font-face-format": {category: catOther, version: svg11},
+	"font-face-name":   {category: catOther, version: svg11},
+	"definition-src":   {category: catOther, version: svg11},
+	"glyph":            {category: catOther, version: svg11, content: containerContent},
+	"missing-glyph":    {category: catOther, version: svg11, content: containerContent},
+	"hkern":            {category: catOther, version: svg11},
+	"vkern":            {category: catOther, version: svg11},
+	"altGlyphDef":      {category: catOther, version: svg11, children: []string{"altGlyphItem", "glyphRef"}},
+	"altGlyphItem":     {category: catOther, version: svg11, children: []string{"glyphRef"}},
+	"glyphRef":         {category: catOther, version: svg11},
+	"color-profile":    {category: catOther, version: svg11, content: catDescriptive},
+	"cursor":           {category: catOther, version: svg11, content: catDescriptive},
+	"a":                {category: catOther, version: svgAll, content: containerContent | catTextContentChild},
+	"clipPath":         {category: catOther, version: svgAll, content: catAnimation | catDescriptive | catShape, children: []string{"text", "use"}},
+	"foreignObject":    {category: catOther, version: svgAll, anyContent: true},
+	"image":            {category: catOther, version: svgAll, content: graphicContent},
+	"marker":           {category: catOther, version: svgAll, content: containerContent},
+	"mask":             {category: catOther, version: svgAll, content: containerContent},
+	"pattern":          {category: catOther, version: svgAll, content: containerContent},
+	"script":           {category: catOther, version: svgAll},
+	"style":            {category: catOther, version: svgAll},
+	"switch":           {category: catOther, version: svgAll, content: catAnimation | catDescriptive | catShape, children: []string{"a", "foreignObject", "g", "image", "svg", "switch", "text", "use"}},
+	"view":             {category: catOther, version: svgAll, content: catDescriptive},
+}
+
+// attribute value grammars
+type grammar func(value string) error
+
+var (
+	lengthPattern    = `[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?(em|ex|px|in|cm|mm|pt|pc|Q|rem|ch|vw|vh|vmin|vmax|%)?`
+	numberPattern    = `[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?`
+	lengthRegex      = regexp.MustCompile(`^` + lengthPattern + `$`)
+	numberRegex      = regexp.MustCompile(`^` + numberPattern + `$`)
+	listSeparator    = regexp.MustCompile(`\s*,\s*|\s+`)
+	pathCharsRegex   = regexp.MustCompile(`^[MmZzLlHhVvCcSsQqTtAa0-9eE+\-.,\s]*$`)
+	transformRegex   = regexp.MustCompile(`^\s*(matrix|translate|scale|rotate|skewX|skewY)\s*\(([^()]*)\)\s*,?`)
+	hexColorRegex    = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	colorFuncRegex   = regexp.MustCompile(`^(rgb|rgba|hsl|hsla)\(\s*[^()]*\)$`)
+	funcIRIRegex     = regexp.MustCompile(`^url\(\s*['"]?[^'"()]+['"]?\s*\)(\s+(.*))?$`)
+	transformArities = map[string][]int{
+		"matrix":    {6},
+		"translate": {1, 2},
+		"scale":     {1, 2},
+		"rotate":    {1, 3},
+		"skewX":     {1},
+		"skewY":     {1},
+	}
+)
+
+func splitList(value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return listSeparator.Split(value, -1)
+}
+
+func parseNumber(value string) (float64, error) {
+	if !numberRegex.MatchString(value) {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func validLength(value string) error {
+	if !lengthRegex.MatchString(strings.TrimSpace(value)) {
+		return fmt.Errorf("%q is not a length", value)
+	}
+	return nil
+}
+
+func validNonNegativeLength(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "auto" {
+		return nil
+	}
+	if err := validLength(value); err != nil {
+		return err
+	}
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("negative value %q is not allowed", value)
+	}
+	return nil
+}
+
+func validLengthList(value string) error {
+	for _, item := range splitList(value) {
+		if err := validLength(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validNumber(value string) error {
+	_, err := parseNumber(strings.TrimSpace(value))
+	return err
+}
+
+// validAlpha accepts a number or, since SVG 2, a percentage.
+func validAlpha(value string) error {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		value = strings.TrimSuffix(value, "%")
+	}
+	return validNumber(value)
+}
+
+func validMiterLimit(value string) error {
+	n, err := parseNumber(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("miter limit %q must be greater than or equal to 1", value)
+	}
+	return nil
+}
+
+func validViewBox(value string) error {
+	items := splitList(value)
+	if len(items) != 4 {
+		return fmt.Errorf("viewBox %q must have four numbers", value)
+	}
+	for i, item := range items {
+		n, err := parseNumber(item)
+		if err != nil {
+			return err
+		}
+		if i >= 2 && n < 0 {
+			return fmt.Errorf("viewBox %q has a negative size", value)
+		}
+	}
+	return nil
+}
+
+func validPoints(value string) error {
+	items := splitList(value)
+	for _, item := range items {
+		if _, err := parseNumber(item); err != nil {
+			return err
+		}
+	}
+	if len(items)%2 != 0 {
+		return fmt.Errorf("points %q must have an even number of coordinates", value)
+	}
+	return nil
+}
+
+func validPathData(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "none" {
+		return nil
+	}
+	if !pathCharsRegex.MatchString(value) {
+		return fmt.Errorf("path data %q contains invalid characters", value)
+	}
+	if value[0] != 'M' && value[0] != 'm' {
+		return fmt.Errorf("path data %q must begin with a moveto command", value)
+	}
+	if _, err := utils.PathParser(value); err != nil {
+		return fmt.Errorf("path data %q: %s", value, err)
+	}
+	return nil
+}
+
+func validTransform(value string) error {
+	rest := strings.TrimSpace(value)
+	for rest != "" {
+		match := transformRegex.FindStringSubmatch(rest)
+		if match == nil {
+			return fmt.Errorf("transform %q is malformed", value)
+		}
+
+		args := splitList(match[2])
+		for _, arg := range args {
+			if _, err := parseNumber(arg); err != nil {
+				return fmt.Errorf("transform %q: %s", value, err)
+			}
+		}
+
+		valid := false
+		for _, n := range transformArities[match[1]] {
+			if len(args) == n {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("transform %q: wrong number of arguments for %s", value, match[1])
+		}
+
+		rest = strings.TrimSpace(rest[len(match[0]):])
+	}
+	return nil
+}
+
+func validPreserveAspectRatio(value string) error {
+	items := strings.Fields(value)
+	if len(items) > 0 && items[0] == "defer" {
+		items = items[1:]
+	}
+	if len(items) == 0 || len(items) > 2 {
+		return fmt.Errorf("preserveAspectRatio %q is malformed", value)
+	}
+
+	align := items[0]
+	if align != "none" {
+		if len(align) != 8 ||
+			!strings.HasPrefix(align, "x") ||
+			!strings.Contains("Min Mid Max", align[1:4]) ||
+			align[4] != 'Y' ||
+			!strings.Contains("Min Mid Max", align[5:8]) {
+			return fmt.Errorf("preserveAspectRatio %q has invalid alignment", value)
+		}
+	}
+
+	if len(items) == 2 && items[1] != "meet" && items[1] != "slice" {
+		return fmt.Errorf("preserveAspectRatio %q has invalid meetOrSlice", value)
+	}
+	return nil
+}
+
+func validColor(value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "currentColor" || value == "inherit" || value == "transparent":
+		return nil
+	case hexColorRegex.MatchString(value), colorFuncRegex.MatchString(value):
+		return nil
+	case namedColors[strings.ToLower(value)]:
+		return nil
+	}
+	return fmt.Errorf("%q is not a color", value)
+}
+
+func validPaint(value string) error {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "none", "context-fill", "context-stroke":
+		return nil
+	}
+
+	if match := funcIRIRegex.FindStringSubmatch(value); match != nil {
+		if fallback := match[2]; fallback != "" && fallback != "none" {
+			return validColor(fallback)
+		}
+		return nil
+	}
+	return validColor(value)
+}
+
+func validID(value string) error {
+	if value == "" || strings.IndexFunc(value, isSpace) >= 0 {
+		return fmt.Errorf("id %q is not a valid name", value)
+	}
+	return nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// grammars of attributes and style properties which are checked
+// regardless of the element they appear on
+var attributeGrammars = map[string]grammar{
+	"id":                  validID,
+	"x1":                  validLength,
+	"y1":                  validLength,
+	"x2":                  validLength,
+	"y2":                  validLength,
+	"cx":                  validLength,
+	"cy":                  validLength,
+	"r":                   validNonNegativeLength,
+	"rx":                  validNonNegativeLength,
+	"ry":                  validNonNegativeLength,
+	"width":               validNonNegativeLength,
+	"height":              validNonNegativeLength,
+	"stroke-width":        validNonNegativeLength,
+	"font-size":           validFontSize,
+	"opacity":             validAlpha,
+	"fill-opacity":        validAlpha,
+	"stroke-opacity":      validAlpha,
+	"stop-opacity":        validAlpha,
+	"flood-opacity":       validAlpha,
+	"stroke-miterlimit":   validMiterLimit,
+	"pathLength":          validNumber,
+	"viewBox":             validViewBox,
+	"preserveAspectRatio": validPreserveAspectRatio,
+	"points":              validPoints,
+	"d":                   validPathData,
+	"transform":           validTransform,
+	"gradientTransform":   validTransform,
+	"patternTransform":    validTransform,
+	"fill":                validPaint,
+	"stroke":              validPaint,
+	"color":               validColor,
+	"stop-color":          validColor,
+	"flood-color":         validColor,
+	"lighting-color":      validColor,
+}
+
+// elements on which x, y, dx and dy are lists of lengths
+var lengthListElements = map[string]bool{
+	"text":     true,
+	"tspan":    true,
+	"tref":     true,
+	"altGlyph": true,
+}
+
+// attributes whose grammar depends on the element
+var elementGrammars = map[string]map[string]grammar{
+	// fill on animation elements means freeze|remove
+	"animate":          {"fill": nil},
+	"animateColor":     {"fill": nil},
+	"animateMotion":    {"fill": nil},
+	"animateTransform": {"fill": nil},
+	"set":              {"fill": nil},
+	"discard":          {"fill": nil},
+
+	// animation values and gradient stops
+	"stop": {"offset": validAlpha},
+}
+
+func validFontSize(value string) error {
+	switch strings.TrimSpace(value) {
+	case "xx-small", "x-small", "small", "medium", "large", "x-large", "xx-large", "larger", "smaller", "inherit":
+		return nil
+	}
+	return validNonNegativeLength(value)
+}
+
+func attributeGrammar(element, name string) grammar {
+	if overrides, ok := elementGrammars[element]; ok {
+		if g, ok := overrides[name]; ok {
+			return g
+		}
+	}
+
+	switch name {
+	case "x", "y", "dx", "dy":
+		if lengthListElements[element] {
+			return validLengthList
+		}
+		if name == "x" || name == "y" {
+			return validLength
+		}
+	}
+
+	return attributeGrammars[name]
+}
+
+// isForeign reports whether an element belongs to a namespace other
+// than SVG, such as inkscape:, sodipodi: or rdf: elements.
+func isForeign(e *Element) bool {
+	return strings.Contains(e.Name, ":") || (e.Space != "" && e.Space != SVGNamespace)
+}
+
+type validator struct {
+	version    int
+	violations []Violation
+}
+
+func (v *validator) report(path string, e *Element, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Path:    path,
+		Element: e,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(e *Element, path string) {
+	rule, known := elementRules[e.Name]
+	if !known {
+		v.report(path, e, "unknown element <%s>", e.Name)
+		return
+	}
+
+	if rule.version&v.version == 0 {
+		v.report(path, e, "element <%s> is not defined in this SVG version", e.Name)
+	}
+
+	v.validateAttributes(e, path, rule)
+
+	counts := make(map[string]int)
+	for _, child := range e.Children {
+		counts[child.Name]++
+		childPath := fmt.Sprintf("%s/%s[%d]", path, child.Name, counts[child.Name])
+
+		if isForeign(child) {
+			continue
+		}
+
+		if !rule.anyContent {
+			childRule, ok := elementRules[child.Name]
+			if ok && !allowedChild(rule, child.Name, childRule) {
+				v.report(childPath, child, "element <%s> is not allowed in <%s>", child.Name, e.Name)
+			}
+		}
+
+		if rule.anyContent {
+			// content of metadata and foreignObject is not svg
+			continue
+		}
+
+		v.validate(child, childPath)
+	}
+}
+
+func allowedChild(parent elementRule, name string, child elementRule) bool {
+	if parent.content&child.category != 0 {
+		return true
+	}
+	for _, allowed := range parent.children {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) validateAttributes(e *Element, path string, rule elementRule) {
+	for _, required := range rule.required {
+		found := false
+		for _, name := range strings.Split(required, "|") {
+			if _, ok := e.Attributes[name]; ok {
+				found = true
+			}
+		}
+		if !found {
+			v.report(path, e, "missing required attribute %s", strings.Replace(required, "|", " or ", -1))
+		}
+	}
+
+	// in a stable order, the map order is random
+	for _, name := range sortedKeys(e.Attributes) {
+		if g := attributeGrammar(e.Name, name); g != nil {
+			if err := g(e.Attributes[name]); err != nil {
+				v.report(path, e, "attribute %s: %s", name, err)
+			}
+		}
+	}
+
+	if style, ok := e.Attributes["style"]; ok {
+		for _, s := range utils.StyleParser(style) {
+			property := strings.TrimSpace(s.Property)
+			value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s.Value), "!important"))
+			if g := attributeGrammars[property]; g != nil {
+				if err := g(value); err != nil {
+					v.report(path, e, "style property %s: %s", property, err)
+				}
+			}
+		}
+	}
+}
+
+// Validate checks the element tree against the SVG 1.1 and SVG 2 element
+// and attribute model. It reports unknown elements, children which are not
+// allowed by the content model of their parent, missing required
+// attributes and attribute values which do not match their grammar.
+// Elements from foreign namespaces are ignored. All violations are returned
+// together in a ValidationError.
+func Validate(root *Element) error {
+	v := &validator{version: svgAll}
+
+	switch root.Attributes["version"] {
+	case "1.0", "1.1":
+		v.version = svg11
+	}
+
+	path := "/" + root.Name
+	if root.Name != "svg" {
+		v.report(path, root, "root element must be <svg>, found <%s>", root.Name)
+	} else {
+		v.validate(root, path)
+	}
+
+	if len(v.violations) > 0 {
+		return ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+// CSS color keywords
+var namedColors = map[string]bool{
+	"aliceblue": true, "antiquewhite": true, "aqua": true, "aquamarine": true,
+	"azure": true, "beige": true, "bisque": true, "black": true,
+	"blanchedalmond": true, "blue": true, "blueviolet": true, "brown": true,
+	"burlywood": true, "cadetblue": true, "chartreuse": true, "chocolate": true,
+	"coral": true, "cornflowerblue": true, "cornsilk": true, "crimson": true,
+	"cyan": true, "darkblue": true, "darkcyan": true, "darkgoldenrod": true,
+	"darkgray": true, "darkgreen": true, "darkgrey": true, "darkkhaki": true,
+	"darkmagenta": true, "darkolivegreen": true, "darkorange": true, "darkorchid": true,
+	"darkred": true, "darksalmon": true, "darkseagreen": true, "darkslateblue": true,
+	"darkslategray": true, "darkslategrey": true, "darkturquoise": true, "darkviolet": true,
+	"deeppink": true, "deepskyblue": true, "dimgray": true, "dimgrey": true,
+	"dodgerblue": true, "firebrick": true, "floralwhite": true, "forestgreen": true,
+	"fuchsia": true, "gainsboro": true, "ghostwhite": true, "gold": true,
+	"goldenrod": true, "gray": true, "grey": true, "green": true,
+	"greenyellow": true, "honeydew": true, "hotpink": true, "indianred": true,
+	"indigo": true, "ivory": true, "khaki": true, "lavender": true,
+	"lavenderblush": true, "lawngreen": true, "lemonchiffon": true, "lightblue": true,
+	"lightcoral": true, "lightcyan": true, "lightgoldenrodyellow": true, "lightgray": true,
+	"lightgreen": true, "lightgrey": true, "lightpink": true, "lightsalmon": true,
+	"lightseagreen": true, "lightskyblue": true, "lightslategray": true, "lightslategrey": true,
+	"lightsteelblue": true, "lightyellow": true, "lime": true, "limegreen": true,
+	"linen": true, "magenta": true, "maroon": true, "mediumaquamarine": true,
+	"mediumblue": true, "mediumorchid": true, "mediumpurple": true, "mediumseagreen": true,
+	"mediumslateblue": true, "mediumspringgreen": true, "mediumturquoise": true, "mediumvioletred": true,
+	"midnightblue": true, "mintcream": true, "mistyrose": true, "moccasin": true,
+	"navajowhite": true, "navy": true, "oldlace": true, "olive": true,
+	"olivedrab": true, "orange": true, "orangered": true, "orchid": true,
+	"palegoldenrod": true, "palegreen": true, "paleturquoise": true, "palevioletred": true,
+	"papayawhip": true, "peachpuff": true, "peru": true, "pink": true,
+	"plum": true, "powderblue": true, "purple": true, "rebeccapurple": true,
+	"red": true, "rosybrown": true, "royalblue": true, "saddlebrown": true,
+	"salmon": true, "sandybrown": true, "seagreen": true, "seashell": true,
+	"sienna": true, "silver": true, "skyblue": true, "slateblue": true,
+	"slategray": true, "slategrey": true, "snow": true, "springgreen": true,
+	"steelblue": true, "tan": true, "teal": true, "thistle": true,
+	"tomato": true, "turquoise": true, "violet": true, "wheat": true,
+	"white": true, "whitesmoke": true, "yellow": true, "yellowgreen": true,
+}
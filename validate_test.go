@@ -0,0 +1,125 @@
+package svg
+
+import (
+	"os"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var testCases = []struct {
+		svg        string
+		violations []string
+	}{
+		{
+			`<svg width="100" height="100" viewBox="0 0 100 100">
+				<g transform="translate(10, 10) rotate(45)">
+					<rect width="10" height="10" fill="url(#grad) red" style="stroke:#000;stroke-width:1px"/>
+					<path d="M 10 10 L 20 20 Z"/>
+					<text x="1 2 3" y="5">A<tspan dx="1em">B</tspan></text>
+				</g>
+			</svg>`,
+			nil,
+		},
+		{
+			`<circle r="10"/>`,
+			[]string{"/circle: root element must be <svg>, found <circle>"},
+		},
+		{
+			`<svg><blink/></svg>`,
+			[]string{"/svg/blink[1]: unknown element <blink>"},
+		},
+		{
+			`<svg><rect width="1" height="1"><g/></rect></svg>`,
+			[]string{"/svg/rect[1]/g[1]: element <g> is not allowed in <rect>"},
+		},
+		{
+			`<svg><g/><g><circle/></g></svg>`,
+			[]string{"/svg/g[2]/circle[1]: missing required attribute r"},
+		},
+		{
+			`<svg><use/></svg>`,
+			[]string{"/svg/use[1]: missing required attribute href or xlink:href"},
+		},
+		{
+			`<svg width="-10" viewBox="0 0 10"></svg>`,
+			[]string{
+				`/svg: attribute viewBox: viewBox "0 0 10" must have four numbers`,
+				`/svg: attribute width: negative value "-10" is not allowed`,
+			},
+		},
+		{
+			`<svg><path d="L 10 10"/><polygon points="1 2 3"/></svg>`,
+			[]string{
+				`/svg/path[1]: attribute d: path data "L 10 10" must begin with a moveto command`,
+				`/svg/polygon[1]: attribute points: points "1 2 3" must have an even number of coordinates`,
+			},
+		},
+		{
+			`<svg><rect width="1" height="1" fill="bluish" transform="rotate(1, 2)" style="opacity:high"/></svg>`,
+			[]string{
+				`/svg/rect[1]: attribute fill: "bluish" is not a color`,
+				`/svg/rect[1]: attribute transform: transform "rotate(1, 2)": wrong number of arguments for rotate`,
+				`/svg/rect[1]: style property opacity: "high" is not a number`,
+			},
+		},
+		{
+			`<svg version="1.1"><filter><feDropShadow/></filter></svg>`,
+			[]string{"/svg/filter[1]/feDropShadow[1]: element <feDropShadow> is not defined in this SVG version"},
+		},
+		{
+			`<svg xmlns:x="urn:x"><x:anything><blink/></x:anything><metadata><blink/></metadata></svg>`,
+			nil,
+		},
+	}
+
+	for _, test := range testCases {
+		element, err := parse(test.svg, false)
+		if err != nil {
+			t.Errorf("Validate: unexpected parse error %v\n", err)
+			continue
+		}
+
+		err = Validate(element)
+		if len(test.violations) == 0 {
+			if err != nil {
+				t.Errorf("Validate: expected %v, actual %v\n", nil, err)
+			}
+			continue
+		}
+
+		verr, ok := err.(ValidationError)
+		if !ok {
+			t.Errorf("Validate: expected ValidationError, actual %v\n", err)
+			continue
+		}
+
+		// violations are reported in a stable order
+		if len(verr.Violations) != len(test.violations) {
+			t.Errorf("Validate: expected %v, actual %v\n", test.violations, verr.Violations)
+			continue
+		}
+		for i, v := range test.violations {
+			if actual := verr.Violations[i].String(); actual != v {
+				t.Errorf("Validate: expected %v, actual %v\n", v, actual)
+			}
+		}
+	}
+}
+
+func TestParseValidate(t *testing.T) {
+	element, err := parse(`<svg><rect width="1"/></svg>`, true)
+	if element != nil || err == nil {
+		t.Errorf("Validation: expected error, actual %v\n", err)
+	}
+
+	f, err := os.Open("./inkscape.svg")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer f.Close()
+
+	if _, err := Parse(f, true); err != nil {
+		t.Errorf("Validation: expected %v, actual %v\n", nil, err)
+	}
+}
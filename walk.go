@@ -0,0 +1,157 @@
+package svg
+
+// WalkAction tells a traversal how to go on after visiting an element.
+type WalkAction int
+
+// traversal actions
+const (
+	// WalkContinue visits the children of the element, then its
+	// following siblings.
+	WalkContinue WalkAction = iota
+	// WalkSkip does not visit the children of the element.
+	WalkSkip
+	// WalkStop ends the traversal.
+	WalkStop
+)
+
+// WalkContext describes the element being visited by WalkWithContext.
+type WalkContext struct {
+	Element *Element
+	Depth   int
+
+	// Ancestors of the element from the element the walk started with.
+	// The slice is reused during the walk and must be copied to be kept.
+	Ancestors []*Element
+
+	// Post is set when the element is visited after its children.
+	Post bool
+}
+
+// Parent returns the parent of the element within the walk, nil for the
+// element the walk started with.
+func (c *WalkContext) Parent() *Element {
+	if len(c.Ancestors) == 0 {
+		return nil
+	}
+	return c.Ancestors[len(c.Ancestors)-1]
+}
+
+// Walk calls fn for the element and its descendants in document order,
+// depth being 0 for the element itself.
+func (e *Element) Walk(fn func(el *Element, depth int) WalkAction) {
+	e.WalkWithContext(func(ctx *WalkContext) WalkAction {
+		if ctx.Post {
+			return WalkContinue
+		}
+		return fn(ctx.Element, ctx.Depth)
+	})
+}
+
+// WalkWithContext calls fn for the element and its descendants, before
+// visiting the children of an element and again after them. The action
+// returned by the second call may only stop the walk.
+func (e *Element) WalkWithContext(fn func(ctx *WalkContext) WalkAction) {
+	ctx := &WalkContext{}
+	walk(e, ctx, fn)
+}
+
+func walk(e *Element, ctx *WalkContext, fn func(ctx *WalkContext) WalkAction) WalkAction {
+	ctx.Element, ctx.Depth, ctx.Post = e, len(ctx.Ancestors), false
+	switch fn(ctx) {
+	case WalkStop:
+		return WalkStop
+	case WalkSkip:
+		return WalkContinue
+	}
+
+	ctx.Ancestors = append(ctx.Ancestors, e)
+	for i := 0; i < len(e.Children); i++ {
+		if walk(e.Children[i], ctx, fn) == WalkStop {
+			return WalkStop
+		}
+	}
+	ctx.Ancestors = ctx.Ancestors[:len(ctx.Ancestors)-1]
+
+	ctx.Element, ctx.Depth, ctx.Post = e, len(ctx.Ancestors), true
+	return fn(ctx)
+}
+
+// Iterator traverses an element and its descendants in document order.
+//
+//	it := root.Iterate()
+//	for it.Next() {
+//		el := it.Element()
+//		...
+//	}
+type Iterator struct {
+	root    *Element
+	current *Element
+	skip    bool
+
+	// open ancestors and the index of the child being visited in each
+	ancestors []*Element
+	indexes   []int
+}
+
+// Iterate returns an iterator over the element and its descendants.
+func (e *Element) Iterate() *Iterator {
+	return &Iterator{root: e}
+}
+
+// Next moves to the next element and reports whether there is one.
+func (it *Iterator) Next() bool {
+	if it.root != nil {
+		it.current, it.root = it.root, nil
+		return true
+	}
+	if it.current == nil {
+		return false
+	}
+
+	if !it.skip && len(it.current.Children) > 0 {
+		it.ancestors = append(it.ancestors, it.current)
+		it.indexes = append(it.indexes, 0)
+		it.current = it.current.Children[0]
+		return true
+	}
+	it.skip = false
+
+	for len(it.ancestors) > 0 {
+		last := len(it.ancestors) - 1
+		parent, next := it.ancestors[last], it.indexes[last]+1
+		if next < len(parent.Children) {
+			it.indexes[last] = next
+			it.current = parent.Children[next]
+			return true
+		}
+
+		it.ancestors = it.ancestors[:last]
+		it.indexes = it.indexes[:last]
+	}
+
+	it.current = nil
+	return false
+}
+
+// Element returns the current element.
+func (it *Iterator) Element() *Element {
+	return it.current
+}
+
+// Depth returns the depth of the current element, 0 for the element the
+// iteration started with.
+func (it *Iterator) Depth() int {
+	return len(it.ancestors)
+}
+
+// Ancestors returns the ancestors of the current element from the
+// element the iteration started with. The slice must not be modified.
+func (it *Iterator) Ancestors() []*Element {
+	return it.ancestors
+}
+
+// SkipChildren makes the next call to Next skip the descendants of the
+// current element.
+func (it *Iterator) SkipChildren() {
+	it.skip = true
+}
@@ -0,0 +1,101 @@
+package svg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	root := testElement()
+
+	var visited []string
+	root.Walk(func(el *Element, depth int) WalkAction {
+		visited = append(visited, fmt.Sprintf("%s:%d", el.Name, depth))
+		if el.Attributes["id"] == "first" {
+			return WalkSkip
+		}
+		return WalkContinue
+	})
+
+	expected := "svg:0 g:1 g:1 path:2 rect:2"
+	if actual := strings.Join(visited, " "); actual != expected {
+		t.Errorf("Walk: expected %v, actual %v\n", expected, actual)
+	}
+
+	visited = nil
+	root.Walk(func(el *Element, depth int) WalkAction {
+		visited = append(visited, el.Name)
+		if el.Name == "path" {
+			return WalkStop
+		}
+		return WalkContinue
+	})
+
+	expected = "svg g rect rect g path"
+	if actual := strings.Join(visited, " "); actual != expected {
+		t.Errorf("Walk: expected %v, actual %v\n", expected, actual)
+	}
+}
+
+func TestWalkWithContext(t *testing.T) {
+	root := testElement()
+
+	var visited []string
+	root.WalkWithContext(func(ctx *WalkContext) WalkAction {
+		name := ctx.Element.Name
+		if ctx.Post {
+			name = "/" + name
+		}
+		if ctx.Element.Name == "path" && !ctx.Post {
+			if ctx.Parent() != root.FindID("second") || len(ctx.Ancestors) != 2 || ctx.Ancestors[0] != root {
+				t.Errorf("WalkWithContext: unexpected ancestors %v\n", ctx.Ancestors)
+			}
+		}
+		visited = append(visited, name)
+
+		if ctx.Element.Attributes["id"] == "first" {
+			return WalkSkip
+		}
+		if ctx.Post && ctx.Element.Attributes["id"] == "second" {
+			return WalkStop
+		}
+		return WalkContinue
+	})
+
+	expected := "svg g g path /path rect /rect /g"
+	if actual := strings.Join(visited, " "); actual != expected {
+		t.Errorf("WalkWithContext: expected %v, actual %v\n", expected, actual)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	root := testElement()
+
+	var visited []string
+	it := root.Iterate()
+	for it.Next() {
+		el := it.Element()
+		visited = append(visited, fmt.Sprintf("%s:%d", el.Name, it.Depth()))
+		if el.Attributes["id"] == "first" {
+			it.SkipChildren()
+		}
+		if el.Name == "path" && it.Ancestors()[1] != root.FindID("second") {
+			t.Errorf("Iterator: unexpected ancestors %v\n", it.Ancestors())
+		}
+	}
+
+	expected := "svg:0 g:1 g:1 path:2 rect:2"
+	if actual := strings.Join(visited, " "); actual != expected {
+		t.Errorf("Iterator: expected %v, actual %v\n", expected, actual)
+	}
+	if it.Next() {
+		t.Errorf("Iterator: expected end\n")
+	}
+
+	leaf := element("rect", map[string]string{})
+	it = leaf.Iterate()
+	if !it.Next() || it.Element() != leaf || it.Next() {
+		t.Errorf("Iterator: expected single element\n")
+	}
+}
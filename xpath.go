@@ -0,0 +1,940 @@
+package svg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XPathNodeType identifies the kind of an XPath node.
+type XPathNodeType int
+
+// XPath node types
+const (
+	XPathRootNode XPathNodeType = iota
+	XPathElementNode
+	XPathAttributeNode
+	XPathTextNode
+	XPathCommentNode
+	XPathProcInstNode
+)
+
+// XPathNode is a node of the XPath data model. Element is the element
+// node itself, the owner of an attribute or text node, or the document
+// element for the root node.
+type XPathNode struct {
+	Type      XPathNodeType
+	Element   *Element
+	Attribute string
+	Node      *Node
+}
+
+// Name returns the qualified name of element and attribute nodes and the
+// target of processing instructions.
+func (n XPathNode) Name() string {
+	switch n.Type {
+	case XPathElementNode:
+		return n.Element.Name
+	case XPathAttributeNode:
+		return n.Attribute
+	case XPathProcInstNode:
+		return n.Node.Target
+	}
+	return ""
+}
+
+// Value returns the string-value of the node.
+func (n XPathNode) Value() string {
+	switch n.Type {
+	case XPathRootNode, XPathElementNode:
+		var value strings.Builder
+		writeTextContent(&value, n.Element)
+		return value.String()
+	case XPathAttributeNode:
+		return n.Element.Attributes[n.Attribute]
+	}
+	return n.Node.Data
+}
+
+// writeTextContent writes the text of the element and its descendants.
+func writeTextContent(w *strings.Builder, e *Element) {
+	for _, child := range childNodes(e) {
+		if child.Type == XPathElementNode {
+			writeTextContent(w, child.Element)
+		} else if child.Type == XPathTextNode {
+			w.WriteString(child.Node.Data)
+		}
+	}
+}
+
+// childNodes returns the element, text, comment and processing
+// instruction children of the element in document order. Text replaced
+// through Content is taken into account the way Encode writes it.
+func childNodes(e *Element) []XPathNode {
+	var nodes []XPathNode
+	text := func(data string) {
+		nodes = append(nodes, XPathNode{Type: XPathTextNode, Element: e, Node: &Node{Type: TextNode, Data: data}})
+	}
+
+	replaced := e.Content != e.text()
+	content := -1
+	if replaced || len(e.Nodes) == 0 {
+		for i, node := range e.Nodes {
+			if node.isText() && !node.isBlank() {
+				content = i
+				break
+			}
+		}
+		if content < 0 && e.Content != "" {
+			text(e.Content)
+		}
+	}
+
+	next := 0
+	for i, node := range e.Nodes {
+		switch {
+		case node.Type == ElementNode:
+			if next < len(e.Children) {
+				nodes = append(nodes, XPathNode{Type: XPathElementNode, Element: e.Children[next]})
+				next++
+			}
+		case i == content:
+			text(e.Content)
+		case node.isText() && replaced && !node.isBlank():
+			// superseded by Content
+		case node.isText():
+			nodes = append(nodes, XPathNode{Type: XPathTextNode, Element: e, Node: node})
+		case node.Type == CommentNode:
+			nodes = append(nodes, XPathNode{Type: XPathCommentNode, Element: e, Node: node})
+		case node.Type == ProcInstNode:
+			nodes = append(nodes, XPathNode{Type: XPathProcInstNode, Element: e, Node: node})
+		}
+	}
+
+	for _, child := range e.Children[next:] {
+		nodes = append(nodes, XPathNode{Type: XPathElementNode, Element: child})
+	}
+	return nodes
+}
+
+// XPath is a compiled XPath 1.0 expression.
+//
+// Prefixes in the expression are resolved against the namespaces in
+// scope of the context element, the svg, xlink and xml prefixes being
+// bound to their usual namespaces when not declared. As required by
+// XPath, names without prefix only match elements in no namespace, so
+// documents with a default namespace are queried with a prefix, as in
+// //svg:text.
+type XPath struct {
+	source string
+	expr   xpathExpr
+}
+
+// CompileXPath parses an XPath expression.
+func CompileXPath(expr string) (*XPath, error) {
+	parsed, err := parseXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &XPath{source: expr, expr: parsed}, nil
+}
+
+// MustCompileXPath is like CompileXPath but panics if the expression can
+// not be parsed.
+func MustCompileXPath(expr string) *XPath {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// String returns the source of the expression.
+func (x *XPath) String() string {
+	return x.source
+}
+
+// Evaluate evaluates the expression with the element as context node.
+// The result is a []XPathNode in document order, a string, a float64 or
+// a bool.
+func (x *XPath) Evaluate(context *Element) (interface{}, error) {
+	ev := newXPathEvaluator(context)
+	return x.expr.eval(&xpathContext{ev: ev, node: XPathNode{Type: XPathElementNode, Element: context}, position: 1, size: 1})
+}
+
+// Elements evaluates an expression resulting in a node set and returns
+// the elements it contains.
+func (x *XPath) Elements(context *Element) ([]*Element, error) {
+	result, err := x.Evaluate(context)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, ok := result.([]XPathNode)
+	if !ok {
+		return nil, fmt.Errorf("xpath %q: result is not a node set", x.source)
+	}
+
+	var elements []*Element
+	for _, node := range nodes {
+		if node.Type == XPathElementNode {
+			elements = append(elements, node.Element)
+		}
+	}
+	return elements, nil
+}
+
+// EvaluateString evaluates the expression and converts the result to a
+// string.
+func (x *XPath) EvaluateString(context *Element) (string, error) {
+	result, err := x.Evaluate(context)
+	if err != nil {
+		return "", err
+	}
+	return xpathString(result), nil
+}
+
+// EvaluateNumber evaluates the expression and converts the result to a
+// number.
+func (x *XPath) EvaluateNumber(context *Element) (float64, error) {
+	result, err := x.Evaluate(context)
+	if err != nil {
+		return 0, err
+	}
+	return xpathNumber(result), nil
+}
+
+// EvaluateBool evaluates the expression and converts the result to a
+// boolean.
+func (x *XPath) EvaluateBool(context *Element) (bool, error) {
+	result, err := x.Evaluate(context)
+	if err != nil {
+		return false, err
+	}
+	return xpathBoolean(result), nil
+}
+
+// XPath evaluates an XPath expression with the element as context node.
+// See XPath.Evaluate for the types of result.
+func (e *Element) XPath(expr string) (interface{}, error) {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return x.Evaluate(e)
+}
+
+// XPathElements returns the elements selected by an XPath expression
+// with the element as context node.
+func (e *Element) XPathElements(expr string) ([]*Element, error) {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return x.Elements(e)
+}
+
+// well known prefixes, used when not declared in the document
+var xpathPrefixes = map[string]string{
+	"svg":   SVGNamespace,
+	"xlink": XLinkNamespace,
+	"xml":   XMLNamespace,
+}
+
+// xpathEvaluator holds the state of an evaluation: the tree the context
+// element belongs to, indexed for axes and document order.
+type xpathEvaluator struct {
+	context  *Element
+	top      *Element
+	parents  map[*Element]*Element
+	children map[*Element][]XPathNode
+	order    map[XPathNode]int
+}
+
+func newXPathEvaluator(context *Element) *xpathEvaluator {
+	ev := &xpathEvaluator{
+		context:  context,
+		top:      context,
+		parents:  make(map[*Element]*Element),
+		children: make(map[*Element][]XPathNode),
+		order:    make(map[XPathNode]int),
+	}
+	for ev.top.Parent() != nil {
+		ev.top = ev.top.Parent()
+	}
+
+	ev.order[ev.root()] = 0
+	ev.index(ev.top)
+	return ev
+}
+
+// index records document order, parents and children of the element
+// and its descendants.
+func (ev *xpathEvaluator) index(e *Element) {
+	ev.order[XPathNode{Type: XPathElementNode, Element: e}] = len(ev.order)
+	for _, key := range attributeKeys(e) {
+		ev.order[XPathNode{Type: XPathAttributeNode, Element: e, Attribute: key}] = len(ev.order)
+	}
+
+	children := childNodes(e)
+	ev.children[e] = children
+	for _, child := range children {
+		if child.Type == XPathElementNode {
+			ev.parents[child.Element] = e
+			ev.index(child.Element)
+		} else {
+			ev.order[child] = len(ev.order)
+		}
+	}
+}
+
+// attributeKeys returns the sorted attributes of the element, without
+// namespace declarations.
+func attributeKeys(e *Element) []string {
+	var keys []string
+	for key := range e.Attributes {
+		if key != "xmlns" && !strings.HasPrefix(key, "xmlns:") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (ev *xpathEvaluator) root() XPathNode {
+	return XPathNode{Type: XPathRootNode, Element: ev.top}
+}
+
+// namespace resolves a prefix of the expression.
+func (ev *xpathEvaluator) namespace(prefix string) (string, error) {
+	if uri, ok := ev.context.LookupNamespace(prefix); ok {
+		return uri, nil
+	}
+	if uri, ok := xpathPrefixes[prefix]; ok {
+		return uri, nil
+	}
+	return "", fmt.Errorf("xpath: undeclared prefix %s", prefix)
+}
+
+// parent returns the parent of the node, false for the root node.
+func (ev *xpathEvaluator) parent(n XPathNode) (XPathNode, bool) {
+	switch n.Type {
+	case XPathRootNode:
+		return XPathNode{}, false
+	case XPathElementNode:
+		if parent, ok := ev.parents[n.Element]; ok {
+			return XPathNode{Type: XPathElementNode, Element: parent}, true
+		}
+		return ev.root(), true
+	}
+	return XPathNode{Type: XPathElementNode, Element: n.Element}, true
+}
+
+// childrenOf returns the children of the node.
+func (ev *xpathEvaluator) childrenOf(n XPathNode) []XPathNode {
+	switch n.Type {
+	case XPathRootNode:
+		return []XPathNode{{Type: XPathElementNode, Element: ev.top}}
+	case XPathElementNode:
+		return ev.children[n.Element]
+	}
+	return nil
+}
+
+// descendants appends the descendants of the node in document order.
+func (ev *xpathEvaluator) descendants(nodes []XPathNode, n XPathNode) []XPathNode {
+	for _, child := range ev.childrenOf(n) {
+		nodes = append(nodes, child)
+		nodes = ev.descendants(nodes, child)
+	}
+	return nodes
+}
+
+// siblings returns the nodes sharing the parent of the node and the
+// index of the node among them.
+func (ev *xpathEvaluator) siblings(n XPathNode) ([]XPathNode, int) {
+	if n.Type == XPathAttributeNode {
+		return nil, -1
+	}
+
+	parent, ok := ev.parent(n)
+	if !ok {
+		return nil, -1
+	}
+
+	siblings := ev.childrenOf(parent)
+	for i, sibling := range siblings {
+		if sibling == n {
+			return siblings, i
+		}
+	}
+	return nil, -1
+}
+
+// axis returns the nodes of the axis from the node, in axis order.
+func (ev *xpathEvaluator) axis(name string, n XPathNode) []XPathNode {
+	var nodes []XPathNode
+	switch name {
+	case "self":
+		nodes = append(nodes, n)
+
+	case "child":
+		nodes = append(nodes, ev.childrenOf(n)...)
+
+	case "attribute":
+		if n.Type == XPathElementNode {
+			for _, key := range attributeKeys(n.Element) {
+				nodes = append(nodes, XPathNode{Type: XPathAttributeNode, Element: n.Element, Attribute: key})
+			}
+		}
+
+	case "descendant":
+		nodes = ev.descendants(nodes, n)
+
+	case "descendant-or-self":
+		nodes = ev.descendants(append(nodes, n), n)
+
+	case "parent":
+		if parent, ok := ev.parent(n); ok {
+			nodes = append(nodes, parent)
+		}
+
+	case "ancestor", "ancestor-or-self":
+		if name == "ancestor-or-self" {
+			nodes = append(nodes, n)
+		}
+		for parent, ok := ev.parent(n); ok; parent, ok = ev.parent(parent) {
+			nodes = append(nodes, parent)
+		}
+
+	case "following-sibling":
+		if siblings, i := ev.siblings(n); i >= 0 {
+			nodes = append(nodes, siblings[i+1:]...)
+		}
+
+	case "preceding-sibling":
+		if siblings, i := ev.siblings(n); i >= 0 {
+			for j := i - 1; j >= 0; j-- {
+				nodes = append(nodes, siblings[j])
+			}
+		}
+
+	case "following":
+		// following siblings of the node and its ancestors, with their
+		// descendants
+		for current, ok := n, true; ok; current, ok = ev.parent(current) {
+			if siblings, i := ev.siblings(current); i >= 0 {
+				for _, sibling := range siblings[i+1:] {
+					nodes = append(nodes, sibling)
+					nodes = ev.descendants(nodes, sibling)
+				}
+			}
+		}
+		ev.sort(nodes)
+
+	case "preceding":
+		ancestors := make(map[XPathNode]bool)
+		for parent, ok := ev.parent(n); ok; parent, ok = ev.parent(parent) {
+			ancestors[parent] = true
+		}
+		position := ev.position(n)
+		for node, i := range ev.order {
+			if i < position && !ancestors[node] && node.Type != XPathAttributeNode {
+				nodes = append(nodes, node)
+			}
+		}
+		ev.sort(nodes)
+		reverse(nodes)
+	}
+	return nodes
+}
+
+// position returns the document order of the node. Attributes of the
+// same element follow it in any order.
+func (ev *xpathEvaluator) position(n XPathNode) int {
+	if i, ok := ev.order[n]; ok {
+		return i
+	}
+	// attribute added during evaluation
+	return ev.order[XPathNode{Type: XPathElementNode, Element: n.Element}]
+}
+
+// sort puts the nodes in document order.
+func (ev *xpathEvaluator) sort(nodes []XPathNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return ev.position(nodes[i]) < ev.position(nodes[j])
+	})
+}
+
+// union returns the nodes of both sets in document order, without
+// duplicates.
+func (ev *xpathEvaluator) union(a, b []XPathNode) []XPathNode {
+	seen := make(map[XPathNode]bool, len(a)+len(b))
+	var nodes []XPathNode
+	for _, set := range [][]XPathNode{a, b} {
+		for _, node := range set {
+			if !seen[node] {
+				seen[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	ev.sort(nodes)
+	return nodes
+}
+
+func reverse(nodes []XPathNode) {
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+}
+
+// xpathContext is the evaluation context of an expression.
+type xpathContext struct {
+	ev       *xpathEvaluator
+	node     XPathNode
+	position int
+	size     int
+}
+
+type xpathExpr interface {
+	eval(ctx *xpathContext) (interface{}, error)
+}
+
+type literalExpr string
+
+func (e literalExpr) eval(ctx *xpathContext) (interface{}, error) {
+	return string(e), nil
+}
+
+type numberExpr float64
+
+func (e numberExpr) eval(ctx *xpathContext) (interface{}, error) {
+	return float64(e), nil
+}
+
+type negateExpr struct {
+	expr xpathExpr
+}
+
+func (e *negateExpr) eval(ctx *xpathContext) (interface{}, error) {
+	value, err := e.expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathNumber(value), nil
+}
+
+var errNotNodeSet = errors.New("xpath: expression does not result in a node set")
+
+type unionExpr struct {
+	left, right xpathExpr
+}
+
+func (e *unionExpr) eval(ctx *xpathContext) (interface{}, error) {
+	left, err := evalNodes(ctx, e.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalNodes(ctx, e.right)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.ev.union(left, right), nil
+}
+
+func evalNodes(ctx *xpathContext, expr xpathExpr) ([]XPathNode, error) {
+	value, err := expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, ok := value.([]XPathNode)
+	if !ok {
+		return nil, errNotNodeSet
+	}
+	return nodes, nil
+}
+
+type binaryExpr struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (e *binaryExpr) eval(ctx *xpathContext) (interface{}, error) {
+	left, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// boolean operators do not evaluate the right operand if not needed
+	switch e.op {
+	case "or":
+		if xpathBoolean(left) {
+			return true, nil
+		}
+	case "and":
+		if !xpathBoolean(left) {
+			return false, nil
+		}
+	}
+
+	right, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "or", "and":
+		return xpathBoolean(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(e.op, left, right), nil
+	}
+
+	a, b := xpathNumber(left), xpathNumber(right)
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "div":
+		return a / b, nil
+	}
+	return math.Mod(a, b), nil
+}
+
+// compare compares two values, node sets comparing true when any of
+// their nodes does.
+func compare(op string, left, right interface{}) bool {
+	leftNodes, leftSet := left.([]XPathNode)
+	rightNodes, rightSet := right.([]XPathNode)
+
+	switch {
+	case leftSet && rightSet:
+		for _, l := range leftNodes {
+			for _, r := range rightNodes {
+				if compareValues(op, l.Value(), r.Value()) {
+					return true
+				}
+			}
+		}
+		return false
+
+	case leftSet:
+		if b, ok := right.(bool); ok {
+			return compareValues(op, len(leftNodes) > 0, b)
+		}
+		for _, l := range leftNodes {
+			if compareValues(op, l.Value(), right) {
+				return true
+			}
+		}
+		return false
+
+	case rightSet:
+		if b, ok := left.(bool); ok {
+			return compareValues(op, b, len(rightNodes) > 0)
+		}
+		for _, r := range rightNodes {
+			if compareValues(op, left, r.Value()) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareValues(op, left, right)
+}
+
+// compareValues compares two strings, numbers or booleans.
+func compareValues(op string, left, right interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, leftBool := left.(bool)
+		_, rightBool := right.(bool)
+		_, leftNumber := left.(float64)
+		_, rightNumber := right.(float64)
+
+		switch {
+		case leftBool || rightBool:
+			equal = xpathBoolean(left) == xpathBoolean(right)
+		case leftNumber || rightNumber:
+			equal = xpathNumber(left) == xpathNumber(right)
+		default:
+			equal = xpathString(left) == xpathString(right)
+		}
+		return equal == (op == "=")
+	}
+
+	a, b := xpathNumber(left), xpathNumber(right)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+type filterExpr struct {
+	expr       xpathExpr
+	predicates []xpathExpr
+}
+
+func (e *filterExpr) eval(ctx *xpathContext) (interface{}, error) {
+	nodes, err := evalNodes(ctx, e.expr)
+	if err != nil {
+		return nil, err
+	}
+	return filter(ctx.ev, nodes, e.predicates)
+}
+
+// filter keeps the nodes matching all predicates, positions being
+// taken in the order of nodes.
+func filter(ev *xpathEvaluator, nodes []XPathNode, predicates []xpathExpr) ([]XPathNode, error) {
+	for _, predicate := range predicates {
+		var kept []XPathNode
+		for i, node := range nodes {
+			ctx := &xpathContext{ev: ev, node: node, position: i + 1, size: len(nodes)}
+			value, err := predicate.eval(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			match := false
+			if n, ok := value.(float64); ok {
+				match = n == float64(i+1)
+			} else {
+				match = xpathBoolean(value)
+			}
+			if match {
+				kept = append(kept, node)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+type nodeTest struct {
+	// name, node, text, comment or processing-instruction
+	kind   string
+	prefix string
+	local  string
+	// target of processing-instruction('target')
+	target string
+}
+
+// match tests a node found on an axis whose principal node type is
+// principal.
+func (t *nodeTest) match(ev *xpathEvaluator, n XPathNode, principal XPathNodeType) (bool, error) {
+	switch t.kind {
+	case "node":
+		return true, nil
+	case "text":
+		return n.Type == XPathTextNode, nil
+	case "comment":
+		return n.Type == XPathCommentNode, nil
+	case "processing-instruction":
+		return n.Type == XPathProcInstNode && (t.target == "" || t.target == n.Node.Target), nil
+	}
+
+	if n.Type != principal {
+		return false, nil
+	}
+
+	var prefix, local, space string
+	if n.Type == XPathElementNode {
+		prefix, local = splitName(n.Element.Name)
+		space = n.Element.Space
+	} else {
+		prefix, local = splitName(n.Attribute)
+		if prefix != "" {
+			space, _ = n.Element.LookupNamespace(prefix)
+		}
+	}
+
+	if t.local != "*" && t.local != local {
+		return false, nil
+	}
+	if t.prefix == "" && t.local == "*" {
+		return true, nil
+	}
+	if t.prefix == "" {
+		return space == "" && prefix == "", nil
+	}
+
+	uri, err := ev.namespace(t.prefix)
+	if err != nil {
+		return false, err
+	}
+	if space == "" {
+		// prefix not declared in the document
+		return prefix == t.prefix, nil
+	}
+	return space == uri, nil
+}
+
+type xpathStep struct {
+	axis       string
+	test       nodeTest
+	predicates []xpathExpr
+}
+
+// eval returns the nodes selected by the step from the node, in
+// document order.
+func (s *xpathStep) eval(ev *xpathEvaluator, n XPathNode) ([]XPathNode, error) {
+	principal := XPathElementNode
+	if s.axis == "attribute" {
+		principal = XPathAttributeNode
+	}
+
+	var nodes []XPathNode
+	if s.axis != "namespace" {
+		for _, node := range ev.axis(s.axis, n) {
+			match, err := s.test.match(ev, node, principal)
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+
+	nodes, err := filter(ev, nodes, s.predicates)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.axis {
+	case "ancestor", "ancestor-or-self", "preceding", "preceding-sibling":
+		reverse(nodes)
+	}
+	return nodes, nil
+}
+
+type pathExpr struct {
+	// expression the path starts from, nil for location paths
+	filter   xpathExpr
+	absolute bool
+	steps    []*xpathStep
+}
+
+func (e *pathExpr) eval(ctx *xpathContext) (interface{}, error) {
+	nodes := []XPathNode{ctx.node}
+	switch {
+	case e.filter != nil:
+		var err error
+		if nodes, err = evalNodes(ctx, e.filter); err != nil {
+			return nil, err
+		}
+	case e.absolute:
+		nodes = []XPathNode{ctx.ev.root()}
+	}
+
+	for _, step := range e.steps {
+		var selected []XPathNode
+		for _, node := range nodes {
+			found, err := step.eval(ctx.ev, node)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, found...)
+		}
+
+		if len(nodes) > 1 {
+			selected = ctx.ev.union(selected, nil)
+		}
+		nodes = selected
+	}
+
+	if nodes == nil {
+		nodes = []XPathNode{}
+	}
+	return nodes, nil
+}
+
+// xpathString converts a value to a string.
+func xpathString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return formatXPathNumber(v)
+	case []XPathNode:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].Value()
+	}
+	return ""
+}
+
+func formatXPathNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	case n == 0:
+		return "0"
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// xpathNumber converts a value to a number.
+func xpathNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return parseXPathNumber(xpathString(value))
+}
+
+func parseXPathNumber(s string) float64 {
+	s = strings.Trim(s, " \t\r\n")
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits == "." || strings.Trim(digits, "0123456789.") != "" || strings.Count(digits, ".") > 1 {
+		return math.NaN()
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return n
+}
+
+// xpathBoolean converts a value to a boolean.
+func xpathBoolean(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []XPathNode:
+		return len(v) > 0
+	}
+	return false
+}
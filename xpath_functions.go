@@ -0,0 +1,328 @@
+package svg
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+type xpathFunction func(ctx *xpathContext, args []interface{}) (interface{}, error)
+
+// xpathFunctions is the core function library, with the minimal and
+// maximal number of arguments of each function, -1 for any.
+var xpathFunctions map[string]struct {
+	min, max int
+	fn       xpathFunction
+}
+
+func init() {
+	xpathFunctions = map[string]struct {
+		min, max int
+		fn       xpathFunction
+	}{
+		"last":             {0, 0, xpathLast},
+		"position":         {0, 0, xpathPosition},
+		"count":            {1, 1, xpathCount},
+		"id":               {1, 1, xpathID},
+		"local-name":       {0, 1, xpathLocalName},
+		"namespace-uri":    {0, 1, xpathNamespaceURI},
+		"name":             {0, 1, xpathName},
+		"string":           {0, 1, xpathStringFunction},
+		"concat":           {2, -1, xpathConcat},
+		"starts-with":      {2, 2, xpathStartsWith},
+		"contains":         {2, 2, xpathContains},
+		"substring-before": {2, 2, xpathSubstringBefore},
+		"substring-after":  {2, 2, xpathSubstringAfter},
+		"substring":        {2, 3, xpathSubstring},
+		"string-length":    {0, 1, xpathStringLength},
+		"normalize-space":  {0, 1, xpathNormalizeSpace},
+		"translate":        {3, 3, xpathTranslate},
+		"boolean":          {1, 1, xpathBooleanFunction},
+		"not":              {1, 1, xpathNot},
+		"true":             {0, 0, xpathTrue},
+		"false":            {0, 0, xpathFalse},
+		"lang":             {1, 1, xpathLang},
+		"number":           {0, 1, xpathNumberFunction},
+		"sum":              {1, 1, xpathSum},
+		"floor":            {1, 1, xpathFloor},
+		"ceiling":          {1, 1, xpathCeiling},
+		"round":            {1, 1, xpathRound},
+	}
+}
+
+type functionCall struct {
+	name string
+	fn   xpathFunction
+	args []xpathExpr
+}
+
+func (e *functionCall) eval(ctx *xpathContext) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return e.fn(ctx, args)
+}
+
+// argNodes returns the node set argument, the context node when it is
+// omitted.
+func argNodes(ctx *xpathContext, args []interface{}) ([]XPathNode, error) {
+	if len(args) == 0 {
+		return []XPathNode{ctx.node}, nil
+	}
+
+	nodes, ok := args[0].([]XPathNode)
+	if !ok {
+		return nil, errNotNodeSet
+	}
+	return nodes, nil
+}
+
+// argString returns the string argument, the string-value of the
+// context node when it is omitted.
+func argString(ctx *xpathContext, args []interface{}) string {
+	if len(args) == 0 {
+		return ctx.node.Value()
+	}
+	return xpathString(args[0])
+}
+
+func xpathLast(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return float64(ctx.size), nil
+}
+
+func xpathPosition(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return float64(ctx.position), nil
+}
+
+func xpathCount(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	nodes, err := argNodes(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return float64(len(nodes)), nil
+}
+
+func xpathID(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	var ids []string
+	if nodes, ok := args[0].([]XPathNode); ok {
+		for _, node := range nodes {
+			ids = append(ids, strings.Fields(node.Value())...)
+		}
+	} else {
+		ids = strings.Fields(xpathString(args[0]))
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var found []XPathNode
+	ctx.ev.top.Walk(func(e *Element, depth int) WalkAction {
+		if id, ok := e.Attributes["id"]; ok && wanted[id] {
+			found = append(found, XPathNode{Type: XPathElementNode, Element: e})
+			// the first element with an id wins
+			delete(wanted, id)
+		}
+		return WalkContinue
+	})
+	return found, nil
+}
+
+func xpathLocalName(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	nodes, err := argNodes(ctx, args)
+	if err != nil || len(nodes) == 0 {
+		return "", err
+	}
+	_, local := splitName(nodes[0].Name())
+	return local, nil
+}
+
+func xpathNamespaceURI(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	nodes, err := argNodes(ctx, args)
+	if err != nil || len(nodes) == 0 {
+		return "", err
+	}
+
+	switch n := nodes[0]; n.Type {
+	case XPathElementNode:
+		return n.Element.Space, nil
+	case XPathAttributeNode:
+		if prefix, _ := splitName(n.Attribute); prefix != "" {
+			uri, _ := n.Element.LookupNamespace(prefix)
+			return uri, nil
+		}
+	}
+	return "", nil
+}
+
+func xpathName(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	nodes, err := argNodes(ctx, args)
+	if err != nil || len(nodes) == 0 {
+		return "", err
+	}
+	return nodes[0].Name(), nil
+}
+
+func xpathStringFunction(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return argString(ctx, args), nil
+}
+
+func xpathConcat(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	var s strings.Builder
+	for _, arg := range args {
+		s.WriteString(xpathString(arg))
+	}
+	return s.String(), nil
+}
+
+func xpathStartsWith(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return strings.HasPrefix(xpathString(args[0]), xpathString(args[1])), nil
+}
+
+func xpathContains(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return strings.Contains(xpathString(args[0]), xpathString(args[1])), nil
+}
+
+func xpathSubstringBefore(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	s, sep := xpathString(args[0]), xpathString(args[1])
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], nil
+	}
+	return "", nil
+}
+
+func xpathSubstringAfter(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	s, sep := xpathString(args[0]), xpathString(args[1])
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[i+len(sep):], nil
+	}
+	return "", nil
+}
+
+func xpathSubstring(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	runes := []rune(xpathString(args[0]))
+	start := round(xpathNumber(args[1]))
+	end := math.Inf(1)
+	if len(args) == 3 {
+		end = start + round(xpathNumber(args[2]))
+	}
+
+	// characters at one based positions p with start <= p < end
+	var s strings.Builder
+	for i, r := range runes {
+		p := float64(i + 1)
+		if p >= start && p < end {
+			s.WriteRune(r)
+		}
+	}
+	return s.String(), nil
+}
+
+func xpathStringLength(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return float64(utf8.RuneCountInString(argString(ctx, args))), nil
+}
+
+func xpathNormalizeSpace(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return strings.Join(strings.Fields(argString(ctx, args)), " "), nil
+}
+
+func xpathTranslate(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	from, to := []rune(xpathString(args[1])), []rune(xpathString(args[2]))
+	mapping := make(map[rune]rune, len(from))
+	for i, r := range from {
+		if _, ok := mapping[r]; ok {
+			continue
+		}
+		if i < len(to) {
+			mapping[r] = to[i]
+		} else {
+			mapping[r] = -1
+		}
+	}
+
+	return strings.Map(func(r rune) rune {
+		if m, ok := mapping[r]; ok {
+			return m
+		}
+		return r
+	}, xpathString(args[0])), nil
+}
+
+func xpathBooleanFunction(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return xpathBoolean(args[0]), nil
+}
+
+func xpathNot(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return !xpathBoolean(args[0]), nil
+}
+
+func xpathTrue(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return true, nil
+}
+
+func xpathFalse(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return false, nil
+}
+
+func xpathLang(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	lang := strings.ToLower(xpathString(args[0]))
+	for _, node := range ctx.ev.axis("ancestor-or-self", ctx.node) {
+		if node.Type != XPathElementNode {
+			continue
+		}
+		if value, ok := node.Element.Attributes["xml:lang"]; ok {
+			value = strings.ToLower(value)
+			return value == lang || strings.HasPrefix(value, lang+"-"), nil
+		}
+	}
+	return false, nil
+}
+
+func xpathNumberFunction(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return parseXPathNumber(ctx.node.Value()), nil
+	}
+	return xpathNumber(args[0]), nil
+}
+
+func xpathSum(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	nodes, err := argNodes(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := 0.0
+	for _, node := range nodes {
+		sum += parseXPathNumber(node.Value())
+	}
+	return sum, nil
+}
+
+func xpathFloor(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return math.Floor(xpathNumber(args[0])), nil
+}
+
+func xpathCeiling(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return math.Ceil(xpathNumber(args[0])), nil
+}
+
+func xpathRound(ctx *xpathContext, args []interface{}) (interface{}, error) {
+	return round(xpathNumber(args[0])), nil
+}
+
+// round rounds half up as required by XPath, keeping negative zero.
+func round(n float64) float64 {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return n
+	}
+	if n < 0 && n >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(n + 0.5)
+}
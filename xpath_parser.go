@@ -0,0 +1,619 @@
+package svg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// XPathError describes a malformed XPath expression.
+type XPathError struct {
+	Expr   string
+	Offset int
+	Msg    string
+}
+
+func (err *XPathError) Error() string {
+	return fmt.Sprintf("invalid xpath %q at offset %d: %s", err.Expr, err.Offset, err.Msg)
+}
+
+type xpathTokenKind int
+
+const (
+	tokenEOF xpathTokenKind = iota
+	// / // | + - = != < <= > >= * and or mod div
+	tokenOperator
+	// ( ) [ ] . .. @ , ::
+	tokenPunct
+	// name test: QName, prefix:* or *
+	tokenName
+	// comment, text, processing-instruction or node followed by (
+	tokenNodeType
+	// function name followed by (
+	tokenFunction
+	// axis name followed by ::
+	tokenAxis
+	tokenLiteral
+	tokenNumber
+	tokenVariable
+)
+
+type xpathToken struct {
+	kind  xpathTokenKind
+	value string
+	pos   int
+}
+
+func (t xpathToken) is(kind xpathTokenKind, value string) bool {
+	return t.kind == kind && t.value == value
+}
+
+var xpathNodeTypes = map[string]bool{
+	"comment":                true,
+	"text":                   true,
+	"processing-instruction": true,
+	"node":                   true,
+}
+
+var xpathAxes = map[string]bool{
+	"ancestor":           true,
+	"ancestor-or-self":   true,
+	"attribute":          true,
+	"child":              true,
+	"descendant":         true,
+	"descendant-or-self": true,
+	"following":          true,
+	"following-sibling":  true,
+	"namespace":          true,
+	"parent":             true,
+	"preceding":          true,
+	"preceding-sibling":  true,
+	"self":               true,
+}
+
+// xpathLexer splits an expression into tokens, applying the
+// disambiguation rules of the XPath recommendation.
+type xpathLexer struct {
+	source string
+	pos    int
+	tokens []xpathToken
+}
+
+func (l *xpathLexer) error(format string, args ...interface{}) error {
+	return &XPathError{Expr: l.source, Offset: l.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *xpathLexer) skipSpace() {
+	for l.pos < len(l.source) && strings.IndexByte(" \t\r\n", l.source[l.pos]) >= 0 {
+		l.pos++
+	}
+}
+
+func (l *xpathLexer) emit(kind xpathTokenKind, value string, pos int) {
+	l.tokens = append(l.tokens, xpathToken{kind: kind, value: value, pos: pos})
+}
+
+// operatorExpected reports whether the previous token requires * and
+// names to be read as operators.
+func (l *xpathLexer) operatorExpected() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case tokenOperator:
+		return false
+	case tokenPunct:
+		switch prev.value {
+		case "@", "::", "(", "[", ",":
+			return false
+		}
+	}
+	return true
+}
+
+// next returns the input after white space from the current position,
+// without consuming it.
+func (l *xpathLexer) next() string {
+	return strings.TrimLeft(l.source[l.pos:], " \t\r\n")
+}
+
+func tokenizeXPath(source string) ([]xpathToken, error) {
+	l := &xpathLexer{source: source}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.source) {
+			l.emit(tokenEOF, "", l.pos)
+			return l.tokens, nil
+		}
+
+		start := l.pos
+		rest := l.source[l.pos:]
+		c := rest[0]
+
+		switch {
+		case strings.HasPrefix(rest, "//"), strings.HasPrefix(rest, "!="),
+			strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, ">="):
+			l.pos += 2
+			l.emit(tokenOperator, rest[:2], start)
+
+		case strings.HasPrefix(rest, "::"), strings.HasPrefix(rest, ".."):
+			l.pos += 2
+			l.emit(tokenPunct, rest[:2], start)
+
+		case c == '.' && (len(rest) < 2 || rest[1] < '0' || rest[1] > '9'):
+			l.pos++
+			l.emit(tokenPunct, ".", start)
+
+		case strings.IndexByte("()[]@,", c) >= 0:
+			l.pos++
+			l.emit(tokenPunct, string(c), start)
+
+		case strings.IndexByte("/|+-=<>", c) >= 0:
+			l.pos++
+			l.emit(tokenOperator, string(c), start)
+
+		case c == '*':
+			l.pos++
+			if l.operatorExpected() {
+				l.emit(tokenOperator, "*", start)
+			} else {
+				l.emit(tokenName, "*", start)
+			}
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(rest[1:], c)
+			if end < 0 {
+				return nil, l.error("unterminated literal")
+			}
+			l.pos += end + 2
+			l.emit(tokenLiteral, rest[1:end+1], start)
+
+		case c == '.' || ('0' <= c && c <= '9'):
+			for l.pos < len(l.source) && ('0' <= l.source[l.pos] && l.source[l.pos] <= '9' || l.source[l.pos] == '.') {
+				l.pos++
+			}
+			number := l.source[start:l.pos]
+			if strings.Count(number, ".") > 1 {
+				return nil, l.error("invalid number %q", number)
+			}
+			l.emit(tokenNumber, number, start)
+
+		case c == '$':
+			l.pos++
+			name := l.qname()
+			if name == "" {
+				return nil, l.error("expected variable name")
+			}
+			l.emit(tokenVariable, name, start)
+
+		default:
+			if err := l.name(start); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// name reads a name test, operator name, node type, function or axis
+// name.
+func (l *xpathLexer) name(start int) error {
+	if l.operatorExpected() {
+		name := l.ncname()
+		switch name {
+		case "and", "or", "mod", "div":
+			l.emit(tokenOperator, name, start)
+			return nil
+		}
+		l.pos = start
+		return l.error("expected operator")
+	}
+
+	name := l.qname()
+	if name == "" {
+		return l.error("unexpected character %q", l.source[start:start+1])
+	}
+
+	next := l.next()
+	switch {
+	case strings.HasPrefix(next, "::") && xpathAxes[name]:
+		l.emit(tokenAxis, name, start)
+	case strings.HasPrefix(next, "::"):
+		return l.error("unknown axis %s", name)
+	case strings.HasPrefix(next, "(") && xpathNodeTypes[name]:
+		l.emit(tokenNodeType, name, start)
+	case strings.HasPrefix(next, "("):
+		l.emit(tokenFunction, name, start)
+	default:
+		l.emit(tokenName, name, start)
+	}
+	return nil
+}
+
+// qname reads a qualified name, or a prefix followed by :*.
+func (l *xpathLexer) qname() string {
+	start := l.pos
+	if l.ncname() == "" {
+		return ""
+	}
+
+	rest := l.source[l.pos:]
+	if strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, "::") {
+		if strings.HasPrefix(rest, ":*") {
+			l.pos += 2
+			return l.source[start:l.pos]
+		}
+
+		l.pos++
+		if l.ncname() == "" {
+			l.pos--
+		}
+	}
+	return l.source[start:l.pos]
+}
+
+// ncname reads a name without colon.
+func (l *xpathLexer) ncname() string {
+	start := l.pos
+	for l.pos < len(l.source) {
+		r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+		nameStart := r == '_' || unicode.IsLetter(r)
+		nameChar := nameStart || r == '-' || r == '.' || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if (l.pos == start && !nameStart) || !nameChar {
+			break
+		}
+		l.pos += size
+	}
+	return l.source[start:l.pos]
+}
+
+// xpathParser builds the syntax tree of an expression.
+type xpathParser struct {
+	source string
+	tokens []xpathToken
+	pos    int
+}
+
+func (p *xpathParser) peek() xpathToken {
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) advance() xpathToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *xpathParser) error(format string, args ...interface{}) error {
+	return &XPathError{Expr: p.source, Offset: p.peek().pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *xpathParser) expect(kind xpathTokenKind, value string) error {
+	if !p.peek().is(kind, value) {
+		return p.error("expected %s", value)
+	}
+	p.advance()
+	return nil
+}
+
+func parseXPath(source string) (xpathExpr, error) {
+	tokens, err := tokenizeXPath(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &xpathParser{source: source, tokens: tokens}
+	expr, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.error("unexpected %q", p.peek().value)
+	}
+	return expr, nil
+}
+
+func (p *xpathParser) expr() (xpathExpr, error) {
+	return p.binary(0)
+}
+
+// precedence levels of binary operators, from the loosest.
+var xpathPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (p *xpathParser) binary(level int) (xpathExpr, error) {
+	if level == len(xpathPrecedence) {
+		return p.unary()
+	}
+
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		found := false
+		for _, op := range xpathPrecedence[level] {
+			found = found || t.is(tokenOperator, op)
+		}
+		if !found {
+			return left, nil
+		}
+
+		p.advance()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: t.value, left: left, right: right}
+	}
+}
+
+func (p *xpathParser) unary() (xpathExpr, error) {
+	if p.peek().is(tokenOperator, "-") {
+		p.advance()
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{expr: expr}, nil
+	}
+	return p.union()
+}
+
+func (p *xpathParser) union() (xpathExpr, error) {
+	left, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is(tokenOperator, "|") {
+		p.advance()
+		right, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		left = &unionExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+// startsStep reports whether the token may begin a location step.
+func startsStep(t xpathToken) bool {
+	switch t.kind {
+	case tokenName, tokenNodeType, tokenAxis:
+		return true
+	case tokenPunct:
+		return t.value == "." || t.value == ".." || t.value == "@"
+	}
+	return false
+}
+
+func (p *xpathParser) path() (xpathExpr, error) {
+	t := p.peek()
+	path := &pathExpr{}
+
+	switch {
+	case t.is(tokenOperator, "/"):
+		p.advance()
+		path.absolute = true
+		if !startsStep(p.peek()) {
+			return path, nil
+		}
+
+	case t.is(tokenOperator, "//"):
+		p.advance()
+		path.absolute = true
+		path.steps = append(path.steps, descendantOrSelf())
+
+	case startsStep(t):
+
+	default:
+		filter, err := p.filter()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek().is(tokenOperator, "/") && !p.peek().is(tokenOperator, "//") {
+			return filter, nil
+		}
+		path.filter = filter
+		if p.peek().value == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		}
+		p.advance()
+	}
+
+	for {
+		step, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+
+		switch {
+		case p.peek().is(tokenOperator, "/"):
+			p.advance()
+		case p.peek().is(tokenOperator, "//"):
+			p.advance()
+			path.steps = append(path.steps, descendantOrSelf())
+		default:
+			return path, nil
+		}
+	}
+}
+
+func descendantOrSelf() *xpathStep {
+	return &xpathStep{axis: "descendant-or-self", test: nodeTest{kind: "node"}}
+}
+
+func (p *xpathParser) step() (*xpathStep, error) {
+	t := p.peek()
+	switch {
+	case t.is(tokenPunct, "."):
+		p.advance()
+		return &xpathStep{axis: "self", test: nodeTest{kind: "node"}}, nil
+	case t.is(tokenPunct, ".."):
+		p.advance()
+		return &xpathStep{axis: "parent", test: nodeTest{kind: "node"}}, nil
+	}
+
+	step := &xpathStep{axis: "child"}
+	switch {
+	case t.kind == tokenAxis:
+		p.advance()
+		step.axis = t.value
+		if err := p.expect(tokenPunct, "::"); err != nil {
+			return nil, err
+		}
+	case t.is(tokenPunct, "@"):
+		p.advance()
+		step.axis = "attribute"
+	}
+
+	t = p.peek()
+	if t.kind != tokenName && t.kind != tokenNodeType {
+		return nil, p.error("expected node test")
+	}
+	p.advance()
+
+	switch t.kind {
+	case tokenName:
+		step.test.kind = "name"
+		step.test.prefix, step.test.local = splitName(t.value)
+
+	case tokenNodeType:
+		step.test.kind = t.value
+		if err := p.expect(tokenPunct, "("); err != nil {
+			return nil, err
+		}
+		if t.value == "processing-instruction" && p.peek().kind == tokenLiteral {
+			step.test.target = p.advance().value
+		}
+		if err := p.expect(tokenPunct, ")"); err != nil {
+			return nil, err
+		}
+	}
+
+	predicates, err := p.predicates()
+	if err != nil {
+		return nil, err
+	}
+	step.predicates = predicates
+	return step, nil
+}
+
+func (p *xpathParser) predicates() ([]xpathExpr, error) {
+	var predicates []xpathExpr
+	for p.peek().is(tokenPunct, "[") {
+		p.advance()
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, expr)
+	}
+	return predicates, nil
+}
+
+func (p *xpathParser) filter() (xpathExpr, error) {
+	primary, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	predicates, err := p.predicates()
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) == 0 {
+		return primary, nil
+	}
+	return &filterExpr{expr: primary, predicates: predicates}, nil
+}
+
+func (p *xpathParser) primary() (xpathExpr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenLiteral:
+		p.advance()
+		return literalExpr(t.value), nil
+
+	case tokenNumber:
+		p.advance()
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.error("invalid number %q", t.value)
+		}
+		return numberExpr(n), nil
+
+	case tokenVariable:
+		return nil, p.error("variables are not supported")
+
+	case tokenFunction:
+		return p.function()
+
+	case tokenPunct:
+		if t.value == "(" {
+			p.advance()
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(tokenPunct, ")")
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, p.error("unexpected end of expression")
+	}
+	return nil, p.error("unexpected %q", t.value)
+}
+
+func (p *xpathParser) function() (xpathExpr, error) {
+	t := p.advance()
+	fn, ok := xpathFunctions[t.value]
+	if !ok {
+		p.pos--
+		return nil, p.error("unknown function %s()", t.value)
+	}
+
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+
+	call := &functionCall{name: t.value, fn: fn.fn}
+	if !p.peek().is(tokenPunct, ")") {
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+
+			if !p.peek().is(tokenPunct, ",") {
+				break
+			}
+			p.advance()
+		}
+	}
+
+	if len(call.args) < fn.min || (fn.max >= 0 && len(call.args) > fn.max) {
+		return nil, p.error("wrong number of arguments for %s()", t.value)
+	}
+	return call, p.expect(tokenPunct, ")")
+}
@@ -0,0 +1,214 @@
+package svg
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func xpathTestElement() *Element {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" xmlns:xlink="http://www.w3.org/1999/xlink">
+	<g id="layer1" inkscape:label="Header">
+		<text id="total" x="10"><tspan id="s1">12</tspan><tspan id="s2">30</tspan></text>
+		<!-- note -->
+		<rect id="r1" width="5" height="3"/>
+		<rect id="r2" width="5" height="2" xml:lang="en-GB"/>
+	</g>
+	<g id="layer2">
+		<rect id="r3" width="5" height="1"/>
+		<use id="u1" xlink:href="#r1"/>
+		<text id="label">Hello <tspan id="s3">big</tspan> world</text>
+	</g>
+</svg>`
+	element, _ := parse(svg, false)
+	return element
+}
+
+func ids(nodes []XPathNode) string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		switch node.Type {
+		case XPathElementNode:
+			names[i] = node.Element.Attributes["id"]
+		case XPathAttributeNode:
+			names[i] = "@" + node.Attribute
+		case XPathTextNode:
+			names[i] = "'" + node.Node.Data + "'"
+		case XPathCommentNode:
+			names[i] = "<!--" + node.Node.Data + "-->"
+		case XPathRootNode:
+			names[i] = "/"
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func TestXPathNodes(t *testing.T) {
+	root := xpathTestElement()
+
+	var testCases = []struct {
+		expr     string
+		expected string
+	}{
+		{"//svg:text[@id='total']/svg:tspan[1]", "s1"},
+		{"//svg:tspan", "s1 s2 s3"},
+		{"//svg:tspan[last()]", "s2 s3"},
+		{"(//svg:tspan)[last()]", "s3"},
+		{"//svg:rect", "r1 r2 r3"},
+		{"//rect", ""},
+		{"/svg:svg/svg:g[2]/*", "r3 u1 label"},
+		{"//svg:g[@inkscape:label='Header']", "layer1"},
+		{"//*[@xlink:href='#r1']", "u1"},
+		{"//svg:rect[@height > 1]", "r1 r2"},
+		{"(//svg:rect[not(@xml:lang)])[position() = 2]", "r3"},
+		{"//svg:rect[not(@xml:lang)][position() = 2]", ""},
+		{"//svg:rect[lang('en')]", "r2"},
+		{"//svg:tspan[. = 30]", "s2"},
+		{"//svg:tspan[@id='s2']/ancestor::*[@id]", "layer1 total"},
+		{"//svg:tspan[@id='s2']/ancestor::*[1]", "total"},
+		{"//svg:rect[@id='r2']/preceding-sibling::*[1]", "r1"},
+		{"//svg:rect[@id='r1']/following::svg:rect", "r2 r3"},
+		{"//svg:rect[@id='r3']/preceding::svg:tspan", "s1 s2"},
+		{"//svg:use/..", "layer2"},
+		{"//svg:rect[1]/@*", "@height @id @width @height @id @width"},
+		{"//svg:rect[@id='r1']/@*", "@height @id @width"},
+		{"//svg:text[@id='label']/text()", "'Hello ' ' world'"},
+		{"//svg:g[1]/comment()", "<!-- note -->"},
+		{"id('r3 s1')", "s1 r3"},
+		{"//svg:tspan[@id='s3'] | //svg:rect[@id='r1'] | //svg:tspan[@id='s3']", "r1 s3"},
+		{"/", "/"},
+		{"svg:g/svg:rect", "r1 r2 r3"},
+		{".//inkscape:*", ""},
+	}
+
+	for _, test := range testCases {
+		result, err := root.XPath(test.expr)
+		if err != nil {
+			t.Errorf("XPath(%s): unexpected error %v\n", test.expr, err)
+			continue
+		}
+
+		nodes, ok := result.([]XPathNode)
+		if !ok {
+			t.Errorf("XPath(%s): expected node set, actual %v\n", test.expr, result)
+			continue
+		}
+		if actual := ids(nodes); actual != test.expected {
+			t.Errorf("XPath(%s): expected %v, actual %v\n", test.expr, test.expected, actual)
+		}
+	}
+}
+
+func TestXPathValues(t *testing.T) {
+	root := xpathTestElement()
+
+	var testCases = []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"count(//svg:rect)", 3.0},
+		{"sum(//svg:rect/@height)", 6.0},
+		{"string(//svg:text[@id='total'])", "1230"},
+		{"normalize-space(//svg:text[@id='label'])", "Hello big world"},
+		{"//svg:text[1]/@x = 10", true},
+		{"//svg:rect/@height = 2", true},
+		{"//svg:rect/@height != 2", true},
+		{"//svg:rect/@height > 3", false},
+		{"1 + 2 * 3 - 4 div 2", 5.0},
+		{"7 mod 3", 1.0},
+		{"-(2)", -2.0},
+		{"concat('a', 1, true())", "a1true"},
+		{"substring('12345', 1.5, 2.6)", "234"},
+		{"substring('12345', 0, 3)", "12"},
+		{"substring-before('1999/04/01', '/')", "1999"},
+		{"substring-after('1999/04/01', '/')", "04/01"},
+		{"translate('--aaa--', 'abc-', 'ABC')", "AAA"},
+		{"string-length('héllo')", 5.0},
+		{"starts-with(name(//*[@inkscape:label]), 'g')", true},
+		{"local-name(//@xlink:href)", "href"},
+		{"namespace-uri(//@xlink:href)", XLinkNamespace},
+		{"namespace-uri(/*)", SVGNamespace},
+		{"name(//@xlink:href)", "xlink:href"},
+		{"round(2.5) + floor(-1.5) + ceiling(1.2)", 3.0},
+		{"string(1 div 0)", "Infinity"},
+		{"string(0.5)", "0.5"},
+		{"string(3.0)", "3"},
+		{"boolean(//svg:circle) or 1 = 1 and true()", true},
+		{"'abc' < 'abd'", false},
+		{"true() = 'x'", true},
+	}
+
+	for _, test := range testCases {
+		result, err := root.XPath(test.expr)
+		if err != nil {
+			t.Errorf("XPath(%s): unexpected error %v\n", test.expr, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("XPath(%s): expected %v, actual %v\n", test.expr, test.expected, result)
+		}
+	}
+
+	if n, _ := MustCompileXPath("number('x')").EvaluateNumber(root); !math.IsNaN(n) {
+		t.Errorf("XPath: expected %v, actual %v\n", math.NaN(), n)
+	}
+}
+
+func TestXPathContext(t *testing.T) {
+	root := xpathTestElement()
+	layer2 := root.FindID("layer2")
+
+	elements, err := layer2.XPathElements("svg:rect | ../svg:g[1]/svg:rect[1]")
+	if err != nil {
+		t.Errorf("XPathElements: unexpected error %v\n", err)
+	}
+	equalSlices(t, "XPathElements", []*Element{root.FindID("r1"), root.FindID("r3")}, elements)
+
+	if s, _ := MustCompileXPath("string(@id)").EvaluateString(layer2); s != "layer2" {
+		t.Errorf("XPath: expected %v, actual %v\n", "layer2", s)
+	}
+
+	// without namespace declarations names match without prefix
+	plain := testElement()
+	if n, _ := MustCompileXPath("count(//rect)").EvaluateNumber(plain); n != 3 {
+		t.Errorf("XPath: expected %v, actual %v\n", 3, n)
+	}
+
+	// edited content is seen as the text of the element
+	text := root.FindID("label")
+	text.Content = "Bye"
+	if s, _ := MustCompileXPath("string(.)").EvaluateString(text); s != "Byebig" {
+		t.Errorf("XPath: expected %v, actual %v\n", "Byebig", s)
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	var testCases = []string{
+		"",
+		"//",
+		"foo(1)",
+		"count()",
+		"//svg:rect[",
+		"'abc",
+		"$x",
+		"bogus::node()",
+		"1 +",
+		"//svg:rect 2",
+	}
+
+	for _, expr := range testCases {
+		if _, err := CompileXPath(expr); err == nil {
+			t.Errorf("CompileXPath(%s): expected error, actual %v\n", expr, err)
+		} else if _, ok := err.(*XPathError); !ok {
+			t.Errorf("CompileXPath(%s): expected XPathError, actual %v\n", expr, err)
+		}
+	}
+
+	root := xpathTestElement()
+	if _, err := root.XPath("//foo:rect"); err == nil {
+		t.Errorf("XPath: expected undeclared prefix error, actual %v\n", err)
+	}
+	if _, err := root.XPath("count(1)"); err == nil {
+		t.Errorf("XPath: expected node set error, actual %v\n", err)
+	}
+}